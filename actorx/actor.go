@@ -0,0 +1,235 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actorx
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"go.infratographer.com/x/gidx"
+)
+
+// Kind describes the type of actor.
+type Kind string
+
+const (
+	// KindUser is an actor acting as an end user.
+	KindUser Kind = "user"
+
+	// KindService is an actor acting as itself, such as a service authenticated with client credentials.
+	KindService Kind = "service"
+
+	// KindAnonymous is an actor which hasn't been authenticated.
+	KindAnonymous Kind = "anonymous"
+)
+
+const (
+	// AnonymousPrefix is the reserved gidx prefix of the anonymous actor subject.
+	AnonymousPrefix = "anonact"
+
+	// AnonymousSubject is the subject used for anonymous actors. It is a valid PrefixedID so
+	// it passes strict ID validation when it is published or stored.
+	AnonymousSubject gidx.PrefixedID = AnonymousPrefix + "-000000000000000000000"
+)
+
+func init() {
+	gidx.MustRegisterPrefix(gidx.PrefixInfo{
+		Prefix:      AnonymousPrefix,
+		TypeName:    "AnonymousActor",
+		Service:     "actorx",
+		Description: "Reserved subject of actors which haven't been authenticated.",
+	})
+}
+
+type actorContext struct{}
+
+// Actor is the identity performing a request.
+type Actor struct {
+	// Subject is the unique identifier of the actor. It is only set when the subject provided by
+	// the authenticator is a valid PrefixedID, RawSubject is always set.
+	Subject gidx.PrefixedID `json:"subject"`
+
+	// RawSubject is the subject as provided by the authenticator, such as the sub claim or a
+	// certificate's SPIFFE ID, which may not be a PrefixedID.
+	RawSubject string `json:"raw_subject,omitempty"`
+
+	// Kind is the type of actor.
+	Kind Kind `json:"kind"`
+
+	// Issuer is the issuer which authenticated the actor.
+	Issuer string `json:"issuer,omitempty"`
+
+	// ClientID is the oauth client the actor authenticated with.
+	ClientID string `json:"client_id,omitempty"`
+
+	// Impersonator is the actor acting on behalf of the subject, as provided by the act claim.
+	Impersonator *Actor `json:"impersonator,omitempty"`
+
+	// Scopes are the scopes granted to the actor.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// New returns a new actor of the kind with the subject. Subject is only set if the subject
+// is a valid PrefixedID, the subject is always kept in RawSubject.
+func New(subject string, kind Kind) *Actor {
+	actor := &Actor{
+		RawSubject: subject,
+		Kind:       kind,
+	}
+
+	if id, err := gidx.Parse(subject); err == nil {
+		actor.Subject = id
+	}
+
+	return actor
+}
+
+// Anonymous returns a new anonymous actor.
+func Anonymous() *Actor {
+	return New(AnonymousSubject.String(), KindAnonymous)
+}
+
+// String returns the actor's subject as provided by the authenticator.
+func (a *Actor) String() string {
+	if a == nil {
+		return ""
+	}
+
+	if a.RawSubject != "" {
+		return a.RawSubject
+	}
+
+	return a.Subject.String()
+}
+
+// ID returns the actor's subject when it is a valid PrefixedID, otherwise AnonymousSubject is returned.
+// It is safe to store or publish, such as the ActorID of a change message.
+func (a *Actor) ID() gidx.PrefixedID {
+	if a == nil || a.Subject == gidx.NullPrefixedID {
+		return AnonymousSubject
+	}
+
+	return a.Subject
+}
+
+// IsAnonymous returns true if the actor is nil or anonymous.
+func (a *Actor) IsAnonymous() bool {
+	return a == nil || a.Kind == KindAnonymous
+}
+
+// IsImpersonated returns true if another actor is acting on behalf of the actor.
+func (a *Actor) IsImpersonated() bool {
+	return a != nil && a.Impersonator != nil
+}
+
+// HasScope returns true if the actor was granted the scope.
+func (a *Actor) HasScope(scope string) bool {
+	return a != nil && slices.Contains(a.Scopes, scope)
+}
+
+// NewContext returns a new context with the actor stored in it.
+func NewContext(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, actorContext{}, actor)
+}
+
+// FromContext returns the actor stored in the context.
+func FromContext(ctx context.Context) (*Actor, bool) {
+	actor, ok := ctx.Value(actorContext{}).(*Actor)
+
+	return actor, ok && actor != nil
+}
+
+// FromContextOrAnonymous returns the actor stored in the context, or an anonymous actor if there is none.
+func FromContextOrAnonymous(ctx context.Context) *Actor {
+	if actor, ok := FromContext(ctx); ok {
+		return actor
+	}
+
+	return Anonymous()
+}
+
+// FromClaims builds an actor from token claims. The subject is read from subjectClaim, defaulting to sub.
+// The actor is a service when the subject is the client the token was issued to, otherwise it's a user.
+// An act claim, as defined by RFC 8693, is used as the impersonator. If the subject claim is missing
+// an anonymous actor is returned. Subjects which aren't a PrefixedID are only kept in RawSubject.
+func FromClaims(claims map[string]any, subjectClaim string) *Actor {
+	if subjectClaim == "" {
+		subjectClaim = "sub"
+	}
+
+	subject, _ := claims[subjectClaim].(string)
+	if subject == "" {
+		return Anonymous()
+	}
+
+	actor := New(subject, KindUser)
+	actor.Issuer = stringClaim(claims, "iss")
+	actor.ClientID = clientID(claims)
+	actor.Scopes = ClaimScopes(claims)
+
+	if actor.ClientID != "" && actor.ClientID == subject {
+		actor.Kind = KindService
+	}
+
+	if act, ok := claims["act"].(map[string]any); ok {
+		actor.Impersonator = FromClaims(act, "sub")
+
+		if actor.Impersonator.IsAnonymous() {
+			actor.Impersonator = nil
+		}
+	}
+
+	return actor
+}
+
+// ClaimScopes returns the scopes granted by the claims. Scopes are read from the space
+// delimited scope claim, as defined by RFC 8693, and the scp claim which may be a list or string.
+func ClaimScopes(claims map[string]any) []string {
+	var scopes []string
+
+	for _, name := range []string{"scope", "scp"} {
+		switch v := claims[name].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(v)...)
+		case []any:
+			for _, s := range v {
+				if str, ok := s.(string); ok {
+					scopes = append(scopes, str)
+				}
+			}
+		case []string:
+			scopes = append(scopes, v...)
+		}
+	}
+
+	return scopes
+}
+
+// clientID returns the client the token was issued to, using the client_id claim defined by
+// RFC 9068 or the azp claim used by OpenID Connect providers.
+func clientID(claims map[string]any) string {
+	if id := stringClaim(claims, "client_id"); id != "" {
+		return id
+	}
+
+	return stringClaim(claims, "azp")
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	v, _ := claims[name].(string)
+
+	return v
+}
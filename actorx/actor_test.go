@@ -0,0 +1,170 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actorx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/actorx"
+	"go.infratographer.com/x/gidx"
+)
+
+func TestFromClaims(t *testing.T) {
+	testCases := []struct {
+		name         string
+		claims       map[string]any
+		subjectClaim string
+		expect       *actorx.Actor
+	}{
+		{
+			name:   "user",
+			claims: map[string]any{"sub": "idntusr-abc", "iss": "https://issuer", "azp": "cli", "scp": []any{"read"}},
+			expect: &actorx.Actor{
+				Subject:    "idntusr-abc",
+				RawSubject: "idntusr-abc",
+				Kind:       actorx.KindUser,
+				Issuer:     "https://issuer",
+				ClientID:   "cli",
+				Scopes:     []string{"read"},
+			},
+		},
+		{
+			name:   "service",
+			claims: map[string]any{"sub": "svc", "client_id": "svc", "scope": "read write"},
+			expect: &actorx.Actor{
+				RawSubject: "svc",
+				Kind:       actorx.KindService,
+				ClientID:   "svc",
+				Scopes:     []string{"read", "write"},
+			},
+		},
+		{
+			name:         "subject claim",
+			claims:       map[string]any{"sub": "abc", "client_id": "svc"},
+			subjectClaim: "client_id",
+			expect: &actorx.Actor{
+				RawSubject: "svc",
+				Kind:       actorx.KindService,
+				ClientID:   "svc",
+			},
+		},
+		{
+			name: "impersonated",
+			claims: map[string]any{
+				"sub": "idntusr-abc",
+				"act": map[string]any{
+					"sub": "idntusr-admin",
+					"act": map[string]any{"sub": "svc", "client_id": "svc"},
+				},
+			},
+			expect: &actorx.Actor{
+				Subject:    "idntusr-abc",
+				RawSubject: "idntusr-abc",
+				Kind:       actorx.KindUser,
+				Impersonator: &actorx.Actor{
+					Subject:    "idntusr-admin",
+					RawSubject: "idntusr-admin",
+					Kind:       actorx.KindUser,
+					Impersonator: &actorx.Actor{
+						RawSubject: "svc",
+						Kind:       actorx.KindService,
+						ClientID:   "svc",
+					},
+				},
+			},
+		},
+		{
+			name:   "invalid act claim",
+			claims: map[string]any{"sub": "idntusr-abc", "act": map[string]any{"iss": "https://issuer"}},
+			expect: &actorx.Actor{
+				Subject:    "idntusr-abc",
+				RawSubject: "idntusr-abc",
+				Kind:       actorx.KindUser,
+			},
+		},
+		{
+			name:   "missing subject",
+			claims: map[string]any{"client_id": "svc"},
+			expect: actorx.Anonymous(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, actorx.FromClaims(tc.claims, tc.subjectClaim))
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := actorx.FromContext(ctx)
+	assert.False(t, ok, "expected no actor in context")
+
+	anon := actorx.FromContextOrAnonymous(ctx)
+	assert.True(t, anon.IsAnonymous())
+	assert.Equal(t, actorx.AnonymousSubject, anon.Subject)
+
+	actor := &actorx.Actor{Subject: "idntusr-abc", Kind: actorx.KindUser, Scopes: []string{"read"}}
+
+	ctx = actorx.NewContext(ctx, actor)
+
+	got, ok := actorx.FromContext(ctx)
+	assert.True(t, ok, "expected actor in context")
+	assert.Same(t, actor, got)
+	assert.Same(t, actor, actorx.FromContextOrAnonymous(ctx))
+	assert.False(t, got.IsAnonymous())
+	assert.True(t, got.HasScope("read"))
+	assert.False(t, got.HasScope("write"))
+	assert.Equal(t, "idntusr-abc", got.String())
+
+	_, ok = actorx.FromContext(actorx.NewContext(context.Background(), nil))
+	assert.False(t, ok, "expected nil actor to be ignored")
+}
+
+func TestActorID(t *testing.T) {
+	actor := actorx.New("idntusr-abc", actorx.KindUser)
+	assert.Equal(t, gidx.PrefixedID("idntusr-abc"), actor.ID())
+	assert.Equal(t, "idntusr-abc", actor.String())
+
+	actor = actorx.New("auth0|123", actorx.KindUser)
+	assert.Equal(t, gidx.NullPrefixedID, actor.Subject)
+	assert.Equal(t, actorx.AnonymousSubject, actor.ID())
+	assert.Equal(t, "auth0|123", actor.String())
+
+	var nilActor *actorx.Actor
+
+	assert.Equal(t, actorx.AnonymousSubject, nilActor.ID())
+	assert.Equal(t, actorx.AnonymousSubject, actorx.Anonymous().ID())
+}
+
+func TestAnonymousSubject(t *testing.T) {
+	id, err := gidx.ParseWithOptions(
+		actorx.AnonymousSubject.String(),
+		gidx.WithIDPartValidation(gidx.IDPartValidationStrict),
+		gidx.WithPrefixValidation(gidx.PrefixValidationRegistered),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, actorx.AnonymousPrefix, id.Prefix())
+
+	info, ok := id.PrefixInfo()
+	require.True(t, ok)
+	assert.Equal(t, "AnonymousActor", info.TypeName)
+}
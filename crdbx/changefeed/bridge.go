@@ -0,0 +1,313 @@
+package changefeed
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+)
+
+const (
+	base10    = 10
+	bitSize64 = 64
+)
+
+// Bridge publishes ChangeMessages for rows changed in a table.
+type Bridge struct {
+	logger    *zap.SugaredLogger
+	db        *sql.DB
+	publisher events.Publisher
+	cursors   CursorStore
+	cfg       Config
+}
+
+// NewBridge creates a new Bridge watching the configured table on the database
+// and publishing changes with the publisher.
+func NewBridge(db *sql.DB, publisher events.Publisher, cursors CursorStore, config Config, options ...Option) (*Bridge, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	b := &Bridge{
+		logger:    zap.NewNop().Sugar(),
+		db:        db,
+		publisher: publisher,
+		cursors:   cursors,
+		cfg:       config.WithDefaults(),
+	}
+
+	for _, opt := range options {
+		opt(b)
+	}
+
+	return b, nil
+}
+
+// Run starts the changefeed from the last saved cursor and publishes changes until
+// the context is canceled or an error occurs.
+// Any error publishing a change stops the bridge so the change is not lost,
+// once restarted the changefeed resumes from the last saved cursor.
+func (b *Bridge) Run(ctx context.Context) error {
+	cursor, err := b.cursors.LoadCursor(ctx, b.cfg.Name)
+	if err != nil {
+		return fmt.Errorf("failed loading changefeed cursor: %w", err)
+	}
+
+	logger := b.logger.With("changefeed.name", b.cfg.Name, "changefeed.table", b.cfg.Table, "changefeed.cursor", cursor)
+
+	logger.Debug("starting changefeed")
+
+	rows, err := b.db.QueryContext(ctx, b.statement(cursor))
+	if err != nil {
+		return fmt.Errorf("failed starting changefeed: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			table      sql.NullString
+			key, value []byte
+		)
+
+		if err := rows.Scan(&table, &key, &value); err != nil {
+			return fmt.Errorf("failed reading changefeed row: %w", err)
+		}
+
+		if err := b.handle(ctx, value); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("changefeed failed: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bridge) statement(cursor string) string {
+	parts := strings.Split(b.cfg.Table, ".")
+
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+
+	options := []string{
+		"updated",
+		"diff",
+		fmt.Sprintf("resolved = '%s'", b.cfg.ResolvedInterval),
+	}
+
+	if cursor != "" {
+		options = append(options, fmt.Sprintf("cursor = %s", pq.QuoteLiteral(cursor)))
+	} else if !b.cfg.InitialScan {
+		options = append(options, "initial_scan = 'no'")
+	}
+
+	return fmt.Sprintf("CREATE CHANGEFEED FOR TABLE %s WITH %s", strings.Join(parts, "."), strings.Join(options, ", "))
+}
+
+func (b *Bridge) handle(ctx context.Context, value []byte) error {
+	var row changefeedRow
+
+	if err := json.Unmarshal(value, &row); err != nil {
+		return fmt.Errorf("failed decoding changefeed row: %w", err)
+	}
+
+	if row.Resolved != "" {
+		if err := b.cursors.SaveCursor(ctx, b.cfg.Name, row.Resolved); err != nil {
+			return fmt.Errorf("failed saving changefeed cursor: %w", err)
+		}
+
+		return nil
+	}
+
+	msg, ok, err := b.changeMessage(row)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	if _, err := b.publisher.PublishChange(ctx, b.cfg.Topic, msg); err != nil {
+		return fmt.Errorf("failed to publish change: %w", err)
+	}
+
+	return nil
+}
+
+// changeMessage builds the ChangeMessage for the row.
+// False is returned if the row contains no changes which should be published.
+func (b *Bridge) changeMessage(row changefeedRow) (events.ChangeMessage, bool, error) {
+	before, err := decodeColumns(row.Before)
+	if err != nil {
+		return events.ChangeMessage{}, false, err
+	}
+
+	after, err := decodeColumns(row.After)
+	if err != nil {
+		return events.ChangeMessage{}, false, err
+	}
+
+	timestamp, err := parseHLC(row.Updated)
+	if err != nil {
+		return events.ChangeMessage{}, false, err
+	}
+
+	msg := events.ChangeMessage{
+		Timestamp: timestamp,
+	}
+
+	current := after
+
+	switch {
+	case after == nil:
+		msg.EventType = string(events.DeleteChangeType)
+		current = before
+	case before == nil:
+		msg.EventType = string(events.CreateChangeType)
+	default:
+		msg.EventType = string(events.UpdateChangeType)
+	}
+
+	if current == nil {
+		return events.ChangeMessage{}, false, nil
+	}
+
+	msg.SubjectID = gidx.PrefixedID(current[b.cfg.SubjectIDColumn])
+
+	if msg.SubjectID == gidx.NullPrefixedID {
+		return events.ChangeMessage{}, false, fmt.Errorf("%w: column %s", ErrMissingSubjectID, b.cfg.SubjectIDColumn)
+	}
+
+	for _, column := range b.cfg.AdditionalSubjectColumns {
+		if id := current[column]; id != "" {
+			msg.AdditionalSubjectIDs = append(msg.AdditionalSubjectIDs, gidx.PrefixedID(id))
+		}
+	}
+
+	// delete messages only identify the subject.
+	if after == nil {
+		return msg, true, nil
+	}
+
+	msg.SubjectFields = after
+	msg.FieldChanges = fieldChanges(before, after)
+
+	if msg.EventType == string(events.UpdateChangeType) && b.ignored(msg.FieldChanges) {
+		return events.ChangeMessage{}, false, nil
+	}
+
+	return msg, true, nil
+}
+
+// ignored returns true if all changes are to ignored fields.
+func (b *Bridge) ignored(changes []events.FieldChange) bool {
+	for _, change := range changes {
+		if !slices.Contains(b.cfg.IgnoredFields, change.Field) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// changefeedRow is the json value emitted by a changefeed with the updated, diff and resolved options.
+type changefeedRow struct {
+	After    json.RawMessage `json:"after"`
+	Before   json.RawMessage `json:"before"`
+	Updated  string          `json:"updated"`
+	Resolved string          `json:"resolved"`
+}
+
+// decodeColumns decodes a row into a map of column names to string values.
+// A nil map is returned if the row is null.
+func decodeColumns(raw json.RawMessage) (map[string]string, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	var columns map[string]json.RawMessage
+
+	if err := json.Unmarshal(raw, &columns); err != nil {
+		return nil, fmt.Errorf("failed decoding changefeed columns: %w", err)
+	}
+
+	values := make(map[string]string, len(columns))
+
+	for column, value := range columns {
+		values[column] = columnString(value)
+	}
+
+	return values, nil
+}
+
+// columnString converts a json column value into a string.
+// Strings are unquoted, nulls become empty and all other values are kept as their json encoding.
+func columnString(value json.RawMessage) string {
+	if bytes.Equal(value, []byte("null")) {
+		return ""
+	}
+
+	var s string
+
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+
+	return string(value)
+}
+
+// fieldChanges returns the changes between the before and after rows sorted by field.
+func fieldChanges(before, after map[string]string) []events.FieldChange {
+	changes := []events.FieldChange{}
+
+	for field, current := range after {
+		previous := before[field]
+		if previous == current {
+			continue
+		}
+
+		changes = append(changes, events.FieldChange{
+			Field:         field,
+			PreviousValue: previous,
+			CurrentValue:  current,
+		})
+	}
+
+	slices.SortFunc(changes, func(a, b events.FieldChange) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+
+	return changes
+}
+
+// parseHLC converts a CockroachDB HLC timestamp into a time.
+func parseHLC(hlc string) (time.Time, error) {
+	if hlc == "" {
+		return time.Now().UTC(), nil
+	}
+
+	wall, _, _ := strings.Cut(hlc, ".")
+
+	nanos, err := strconv.ParseInt(wall, base10, bitSize64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTimestamp, hlc)
+	}
+
+	return time.Unix(0, nanos).UTC(), nil
+}
@@ -0,0 +1,184 @@
+package changefeed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+func TestBridgeStatement(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    Config
+		cursor    string
+		expectSQL string
+	}{
+		{
+			"no cursor",
+			Config{Table: "load_balancers", Topic: "load-balancer"},
+			"",
+			`CREATE CHANGEFEED FOR TABLE "load_balancers" WITH updated, diff, resolved = '10s', initial_scan = 'no'`,
+		},
+		{
+			"no cursor with initial scan",
+			Config{Table: "lb.public.load_balancers", Topic: "load-balancer", InitialScan: true, ResolvedInterval: time.Minute},
+			"",
+			`CREATE CHANGEFEED FOR TABLE "lb"."public"."load_balancers" WITH updated, diff, resolved = '1m0s'`,
+		},
+		{
+			"with cursor",
+			Config{Table: "load_balancers", Topic: "load-balancer"},
+			"1700000000000000000.0000000000",
+			`CREATE CHANGEFEED FOR TABLE "load_balancers" WITH updated, diff, resolved = '10s', cursor = '1700000000000000000.0000000000'`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bridge, err := NewBridge(nil, nil, NewMemoryCursorStore(), tc.config)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectSQL, bridge.statement(tc.cursor))
+		})
+	}
+}
+
+func TestBridgeHandle(t *testing.T) {
+	ctx := context.Background()
+
+	updated := time.Unix(1700000000, 123).UTC()
+
+	testCases := []struct {
+		name          string
+		value         string
+		expectPublish bool
+		expectMessage events.ChangeMessage
+		expectCursor  string
+		expectErr     error
+	}{
+		{
+			name:         "resolved saves cursor",
+			value:        `{"resolved": "1700000000000000000.0000000000"}`,
+			expectCursor: "1700000000000000000.0000000000",
+		},
+		{
+			name:          "create",
+			value:         `{"after": {"id": "loadbal-abc", "name": "lb", "port": 80, "owner_id": "tnntten-abc", "meta": {"a": 1}, "deleted": null}, "before": null, "updated": "1700000000000000123.0000000000"}`,
+			expectPublish: true,
+			expectMessage: events.ChangeMessage{
+				SubjectID:            "loadbal-abc",
+				EventType:            "create",
+				AdditionalSubjectIDs: []gidx.PrefixedID{"tnntten-abc"},
+				Timestamp:            updated,
+				SubjectFields: map[string]string{
+					"id":       "loadbal-abc",
+					"name":     "lb",
+					"port":     "80",
+					"owner_id": "tnntten-abc",
+					"meta":     `{"a": 1}`,
+					"deleted":  "",
+				},
+				FieldChanges: []events.FieldChange{
+					{Field: "id", CurrentValue: "loadbal-abc"},
+					{Field: "meta", CurrentValue: `{"a": 1}`},
+					{Field: "name", CurrentValue: "lb"},
+					{Field: "owner_id", CurrentValue: "tnntten-abc"},
+					{Field: "port", CurrentValue: "80"},
+				},
+			},
+		},
+		{
+			name:          "update",
+			value:         `{"after": {"id": "loadbal-abc", "name": "lb2", "owner_id": "tnntten-abc", "updated_at": "2"}, "before": {"id": "loadbal-abc", "name": "lb", "owner_id": "tnntten-abc", "updated_at": "1"}, "updated": "1700000000000000123.0000000000"}`,
+			expectPublish: true,
+			expectMessage: events.ChangeMessage{
+				SubjectID:            "loadbal-abc",
+				EventType:            "update",
+				AdditionalSubjectIDs: []gidx.PrefixedID{"tnntten-abc"},
+				Timestamp:            updated,
+				SubjectFields: map[string]string{
+					"id":         "loadbal-abc",
+					"name":       "lb2",
+					"owner_id":   "tnntten-abc",
+					"updated_at": "2",
+				},
+				FieldChanges: []events.FieldChange{
+					{Field: "name", PreviousValue: "lb", CurrentValue: "lb2"},
+					{Field: "updated_at", PreviousValue: "1", CurrentValue: "2"},
+				},
+			},
+		},
+		{
+			name:  "update only ignored fields",
+			value: `{"after": {"id": "loadbal-abc", "updated_at": "2"}, "before": {"id": "loadbal-abc", "updated_at": "1"}, "updated": "1700000000000000123.0000000000"}`,
+		},
+		{
+			name:          "delete",
+			value:         `{"after": null, "before": {"id": "loadbal-abc", "name": "lb", "owner_id": "tnntten-abc"}, "updated": "1700000000000000123.0000000000"}`,
+			expectPublish: true,
+			expectMessage: events.ChangeMessage{
+				SubjectID:            "loadbal-abc",
+				EventType:            "delete",
+				AdditionalSubjectIDs: []gidx.PrefixedID{"tnntten-abc"},
+				Timestamp:            updated,
+			},
+		},
+		{
+			name:      "missing subject id",
+			value:     `{"after": {"name": "lb"}, "before": null, "updated": "1700000000000000123.0000000000"}`,
+			expectErr: ErrMissingSubjectID,
+		},
+		{
+			name:      "invalid timestamp",
+			value:     `{"after": {"id": "loadbal-abc"}, "before": null, "updated": "bad"}`,
+			expectErr: ErrInvalidTimestamp,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			publisher := new(eventtools.MockConnection)
+			cursors := NewMemoryCursorStore()
+
+			if tc.expectPublish {
+				publisher.On("PublishChange", "load-balancer", tc.expectMessage).Return(new(eventtools.MockMessage[events.ChangeMessage]), nil)
+			}
+
+			bridge, err := NewBridge(nil, publisher, cursors, Config{
+				Table:                    "load_balancers",
+				Topic:                    "load-balancer",
+				AdditionalSubjectColumns: []string{"owner_id"},
+			})
+			require.NoError(t, err)
+
+			err = bridge.handle(ctx, []byte(tc.value))
+
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			publisher.AssertExpectations(t)
+
+			if !tc.expectPublish {
+				publisher.AssertNotCalled(t, "PublishChange", mock.Anything, mock.Anything)
+			}
+
+			cursor, err := cursors.LoadCursor(ctx, "load_balancers")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectCursor, cursor)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package changefeed
+
+import (
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+var (
+	// DefaultSubjectIDColumn is the default column containing the SubjectID of a row.
+	DefaultSubjectIDColumn = "id"
+	// DefaultResolvedInterval is the default interval resolved timestamps are emitted and cursors are saved.
+	DefaultResolvedInterval = 10 * time.Second
+	// DefaultIgnoredFields are the default fields which alone do not produce an update message.
+	DefaultIgnoredFields = []string{"updated_at"}
+)
+
+// Config defines the changefeed bridge configuration.
+type Config struct {
+	// Name identifies the bridge cursor, defaults to the Table.
+	Name string
+	// Table is the table to watch for changes.
+	Table string
+	// Topic is the subject type changes are published to.
+	Topic string
+	// SubjectIDColumn is the column containing the SubjectID of the row.
+	SubjectIDColumn string
+	// AdditionalSubjectColumns are columns containing ids added to the message AdditionalSubjectIDs.
+	AdditionalSubjectColumns []string
+	// IgnoredFields are fields which alone do not produce an update message.
+	IgnoredFields []string
+	// ResolvedInterval is the interval resolved timestamps are emitted and cursors are saved.
+	ResolvedInterval time.Duration
+	// InitialScan publishes a create message for every existing row when no cursor has been saved.
+	InitialScan bool
+}
+
+// Validate ensures the configuration is valid.
+func (c Config) Validate() error {
+	var err error
+
+	if c.Table == "" {
+		err = multierr.Append(err, ErrMissingTable)
+	}
+
+	if c.Topic == "" {
+		err = multierr.Append(err, ErrMissingTopic)
+	}
+
+	return err
+}
+
+// WithDefaults sets default values for the fields unset.
+func (c Config) WithDefaults() Config {
+	if c.Name == "" {
+		c.Name = c.Table
+	}
+
+	if c.SubjectIDColumn == "" {
+		c.SubjectIDColumn = DefaultSubjectIDColumn
+	}
+
+	if c.IgnoredFields == nil {
+		c.IgnoredFields = DefaultIgnoredFields
+	}
+
+	if c.ResolvedInterval <= 0 {
+		c.ResolvedInterval = DefaultResolvedInterval
+	}
+
+	return c
+}
+
+// Option configures a Bridge.
+type Option func(b *Bridge)
+
+// WithLogger sets the logger for the bridge.
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(b *Bridge) {
+		b.logger = logger
+	}
+}
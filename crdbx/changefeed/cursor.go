@@ -0,0 +1,115 @@
+package changefeed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// DefaultCursorTable is the default table SQLCursorStore saves cursors to.
+var DefaultCursorTable = "changefeed_cursors"
+
+// CursorStore persists changefeed cursors so a bridge may resume after a restart.
+type CursorStore interface {
+	// LoadCursor returns the last saved cursor for the name, or an empty string if none has been saved.
+	LoadCursor(ctx context.Context, name string) (string, error)
+	// SaveCursor saves the cursor for the name.
+	SaveCursor(ctx context.Context, name, cursor string) error
+}
+
+var _ CursorStore = (*SQLCursorStore)(nil)
+
+// SQLCursorStore saves cursors to a database table.
+type SQLCursorStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLCursorStore creates a new SQLCursorStore saving cursors to the provided table.
+// If table is empty, DefaultCursorTable is used.
+func NewSQLCursorStore(db *sql.DB, table string) *SQLCursorStore {
+	if table == "" {
+		table = DefaultCursorTable
+	}
+
+	return &SQLCursorStore{
+		db:    db,
+		table: table,
+	}
+}
+
+// CreateTable creates the cursor table if it does not already exist.
+func (s *SQLCursorStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (name STRING PRIMARY KEY, cursor STRING NOT NULL, updated_at TIMESTAMPTZ NOT NULL DEFAULT now())",
+		pq.QuoteIdentifier(s.table),
+	))
+
+	return err
+}
+
+// LoadCursor returns the last saved cursor for the name, or an empty string if none has been saved.
+func (s *SQLCursorStore) LoadCursor(ctx context.Context, name string) (string, error) {
+	var cursor string
+
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT cursor FROM %s WHERE name = $1", pq.QuoteIdentifier(s.table)),
+		name,
+	).Scan(&cursor)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return cursor, nil
+}
+
+// SaveCursor saves the cursor for the name.
+func (s *SQLCursorStore) SaveCursor(ctx context.Context, name, cursor string) error {
+	_, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("UPSERT INTO %s (name, cursor, updated_at) VALUES ($1, $2, now())", pq.QuoteIdentifier(s.table)),
+		name, cursor,
+	)
+
+	return err
+}
+
+var _ CursorStore = (*MemoryCursorStore)(nil)
+
+// MemoryCursorStore keeps cursors in memory, cursors are lost when the process exits.
+type MemoryCursorStore struct {
+	mu      sync.RWMutex
+	cursors map[string]string
+}
+
+// NewMemoryCursorStore creates a new empty MemoryCursorStore.
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return &MemoryCursorStore{
+		cursors: make(map[string]string),
+	}
+}
+
+// LoadCursor returns the last saved cursor for the name, or an empty string if none has been saved.
+func (s *MemoryCursorStore) LoadCursor(_ context.Context, name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cursors[name], nil
+}
+
+// SaveCursor saves the cursor for the name.
+func (s *MemoryCursorStore) SaveCursor(_ context.Context, name, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cursors[name] = cursor
+
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package changefeed publishes events ChangeMessages for rows changed in a CockroachDB table.
+//
+// A Bridge runs a sinkless changefeed over a database connection, such as one
+// created with crdbx.NewDB, and publishes a ChangeMessage for every row inserted,
+// updated or deleted. This allows tables mutated outside of ent to produce the
+// same changes the entx event hooks would.
+//
+// Progress is checkpointed to a CursorStore each time the changefeed emits a
+// resolved timestamp, restarting the bridge resumes from the last checkpoint.
+// Changes after the checkpoint may be published again, consumers should expect
+// at-least-once delivery.
+//
+//	bridge, err := changefeed.NewBridge(db, conn, changefeed.NewSQLCursorStore(db, ""), changefeed.Config{
+//		Table: "load_balancers",
+//		Topic: "load-balancer",
+//	})
+//	if err != nil {
+//		// handle error
+//	}
+//
+//	if err := bridge.Run(ctx); err != nil {
+//		// handle error
+//	}
+package changefeed
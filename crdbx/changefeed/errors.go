@@ -0,0 +1,17 @@
+package changefeed
+
+import "errors"
+
+var (
+	// ErrMissingTable is returned when the config does not specify a table.
+	ErrMissingTable = errors.New("changefeed table required")
+
+	// ErrMissingTopic is returned when the config does not specify a topic.
+	ErrMissingTopic = errors.New("changefeed topic required")
+
+	// ErrMissingSubjectID is returned when a changed row does not have a value for the subject id column.
+	ErrMissingSubjectID = errors.New("changefeed row missing subject id")
+
+	// ErrInvalidTimestamp is returned when a changefeed timestamp cannot be parsed.
+	ErrInvalidTimestamp = errors.New("invalid changefeed timestamp")
+)
@@ -23,7 +23,6 @@ import (
 	"time"
 
 	"github.com/MicahParks/jwkset"
-	"github.com/MicahParks/keyfunc/v3"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -40,10 +39,19 @@ var (
 
 type actorContext struct{}
 
+type claimsContext struct{}
+
 const (
 	// ActorKey defines the context key an actor is stored in for an echo context
 	ActorKey = "actor"
 
+	// ActorDetailsKey defines the context key the structured actor is stored in for an echo context.
+	// The actor is stored in the request context using actorx.NewContext.
+	ActorDetailsKey = "actor_details"
+
+	// ClaimsKey defines the context key the validated jwt claims are stored in for an echo context
+	ClaimsKey = "claims"
+
 	// DefaultHTTPClientStorageOptionRefreshInterval defines the frequency at which the jwks file is refreshed.
 	DefaultHTTPClientStorageOptionRefreshInterval = time.Hour
 
@@ -58,6 +66,9 @@ var (
 	// ActorCtxKey defines the context key an actor is stored in for a plain context
 	ActorCtxKey = actorContext{}
 
+	// ClaimsCtxKey defines the context key the validated jwt claims are stored in for a plain context
+	ClaimsCtxKey = claimsContext{}
+
 	// ErrJWKSURIMissing is returned when the jwks_uri field is not found in the issuer's oidc well-known configuration.
 	ErrJWKSURIMissing = errors.New("jwks_uri missing from oidc provider")
 )
@@ -73,6 +84,23 @@ type AuthConfig struct {
 	// Audience is the Auth Audience
 	Audience string `mapstructure:"audience"`
 
+	// Issuers are additional trusted issuers, each with their own audiences and JWKS.
+	// The issuer for a token is selected by its iss claim before the signature is verified.
+	Issuers []IssuerConfig `mapstructure:"issuers"`
+
+	// JWKS configures local key sources for the Issuer, used instead of, or as a fallback for, OIDC discovery.
+	// Static sources without an Issuer require AllowAnyIssuer.
+	JWKS JWKSConfig `mapstructure:"jwks"`
+
+	// AllowAnyIssuer trusts tokens from any issuer signed by the JWKS keys when Issuer is empty.
+	AllowAnyIssuer bool `mapstructure:"allow_any_issuer"`
+
+	// Claims configures additional validation of token claims.
+	Claims ClaimsConfig `mapstructure:"claims"`
+
+	// Introspection configures validating opaque tokens with the issuer's introspection endpoint.
+	Introspection IntrospectionConfig `mapstructure:"introspection"`
+
 	// RefreshTimeout is the timeout for fetching the JWKS from the issuer.
 	RefreshTimeout time.Duration `mapstructure:"refresh_timeout"`
 
@@ -92,8 +120,13 @@ type Auth struct {
 	// HTTPClientStorageOptions configuration for fetching JWKS.
 	HTTPClientStorageOptions jwkset.HTTPClientStorageOptions
 
-	issuer   string
-	audience string
+	issuers map[string]*trustedIssuer
+
+	introspector *introspector
+
+	denylist Denylist
+
+	claimsConfig ClaimsConfig
 }
 
 // WithLogger sets the logger for the auth middleware.
@@ -135,15 +168,15 @@ func (a *Auth) setup(ctx context.Context, config AuthConfig, options ...Opts) er
 		config.RateLimitWaitMax = DefaultRateLimitWaitMax
 	}
 
-	a.issuer = config.Issuer
-	a.audience = config.Audience
+	if err := config.Claims.validate(); err != nil {
+		return err
+	}
 
-	if a.JWTConfig.KeyFunc == nil {
-		jwksURI, err := jwksURI(ctx, a.issuer)
-		if err != nil {
-			return err
-		}
+	a.claimsConfig = config.Claims
 
+	loadKeys := a.JWTConfig.KeyFunc == nil
+
+	if loadKeys {
 		if a.HTTPClientStorageOptions.Ctx == nil {
 			a.HTTPClientStorageOptions.Ctx = ctx
 		}
@@ -161,33 +194,24 @@ func (a *Auth) setup(ctx context.Context, config AuthConfig, options ...Opts) er
 		if a.HTTPClientStorageOptions.HTTPTimeout == 0 {
 			a.HTTPClientStorageOptions.HTTPTimeout = DefaultHTTPClientStorageOptionHTTPTimeout
 		}
+	}
 
-		storage, err := jwkset.NewStorageFromHTTP(jwksURI.String(), a.HTTPClientStorageOptions)
-		if err != nil {
-			return err
-		}
-
-		clientOptions := jwkset.HTTPClientOptions{
-			Given:            storage,
-			RateLimitWaitMax: config.RateLimitWaitMax,
-		}
+	if err := a.setupIssuers(ctx, config, loadKeys); err != nil {
+		return err
+	}
 
-		clientStorage, err := jwkset.NewHTTPClient(clientOptions)
-		if err != nil {
-			return err
-		}
+	if loadKeys {
+		a.JWTConfig.KeyFunc = a.keyfunc
+	}
 
-		keyfuncOptions := keyfunc.Options{
-			Ctx:     ctx,
-			Storage: clientStorage,
-		}
+	if a.JWTConfig.ParseTokenFunc == nil {
+		a.JWTConfig.ParseTokenFunc = a.parseJWT
+	}
 
-		jwks, err := keyfunc.New(keyfuncOptions)
-		if err != nil {
+	if config.Introspection.Enabled {
+		if err := a.setupIntrospection(ctx, config); err != nil {
 			return err
 		}
-
-		a.JWTConfig.KeyFunc = jwks.Keyfunc
 	}
 
 	mdw, err := a.JWTConfig.ToMiddleware()
@@ -243,6 +267,12 @@ func NewAuth(ctx context.Context, config AuthConfig, options ...Opts) (*Auth, er
 }
 
 func jwksURI(ctx context.Context, issuer string) (*url.URL, error) {
+	return discoverEndpoint(ctx, issuer, "jwks_uri", ErrJWKSURIMissing)
+}
+
+// discoverEndpoint returns the url for the key in the issuer's oidc well-known configuration.
+// If the key is missing, errMissing is returned.
+func discoverEndpoint(ctx context.Context, issuer, key string, errMissing error) (*url.URL, error) {
 	uri, err := url.JoinPath(issuer, ".well-known", "openid-configuration")
 	if err != nil {
 		return nil, err
@@ -264,10 +294,10 @@ func jwksURI(ctx context.Context, issuer string) (*url.URL, error) {
 		return nil, err
 	}
 
-	jwksURL, ok := m["jwks_uri"]
+	endpoint, ok := m[key].(string)
 	if !ok {
-		return nil, ErrJWKSURIMissing
+		return nil, errMissing
 	}
 
-	return url.Parse(jwksURL.(string))
+	return url.Parse(endpoint)
 }
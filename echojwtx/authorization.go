@@ -0,0 +1,192 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/exp/slices"
+
+	"go.infratographer.com/x/actorx"
+)
+
+var (
+	// ErrMissingClaims is returned when authorization middleware is used on a request without validated jwt claims.
+	ErrMissingClaims = errors.New("missing jwt claims")
+
+	// ErrInsufficientScope is returned when the jwt is missing a required scope.
+	ErrInsufficientScope = errors.New("insufficient scope")
+
+	// ErrClaimMismatch is returned when a jwt claim is missing or doesn't match an allowed value.
+	ErrClaimMismatch = errors.New("claim mismatch")
+
+	// ErrExpressionNotSatisfied is returned when the jwt claims don't satisfy an authorization expression.
+	ErrExpressionNotSatisfied = errors.New("expression not satisfied")
+)
+
+// ClaimsMatcher reports whether the claims are authorized, returning an error explaining why not when they aren't.
+type ClaimsMatcher func(claims jwt.MapClaims) error
+
+// RequireClaims returns middleware which only allows requests with validated jwt claims
+// that satisfy the matcher. Requests without claims are rejected with a 401, requests
+// which don't satisfy the matcher are rejected with a 403.
+func RequireClaims(matcher ClaimsMatcher) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := Claims(c)
+			if claims == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing or malformed jwt").SetInternal(ErrMissingClaims)
+			}
+
+			if err := matcher(claims); err != nil {
+				return forbiddenError(err)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RequireScopes returns middleware which only allows requests whose jwt includes all of the scopes.
+// Scopes are read from the space delimited scope claim or the scp claim, see ClaimScopes.
+func RequireScopes(scopes ...string) echo.MiddlewareFunc {
+	return RequireClaims(func(claims jwt.MapClaims) error {
+		granted := ClaimScopes(claims)
+
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return fmt.Errorf("%w: %s required", ErrInsufficientScope, scope)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RequireAnyScope returns middleware which only allows requests whose jwt includes at least one of the scopes.
+func RequireAnyScope(scopes ...string) echo.MiddlewareFunc {
+	return RequireClaims(func(claims jwt.MapClaims) error {
+		granted := ClaimScopes(claims)
+
+		for _, scope := range scopes {
+			if slices.Contains(granted, scope) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: one of %s required", ErrInsufficientScope, strings.Join(scopes, ", "))
+	})
+}
+
+// RequireClaim returns middleware which only allows requests whose jwt has the named claim.
+// If values are provided, the claim must equal one of them, or for list claims contain one of them.
+// Nested claims may be referenced with a dot separated name, such as realm_access.roles.
+func RequireClaim(name string, values ...string) echo.MiddlewareFunc {
+	return RequireClaims(func(claims jwt.MapClaims) error {
+		value, ok := lookupClaim(claims, name)
+		if !ok {
+			return fmt.Errorf("%w: %s missing", ErrClaimMismatch, name)
+		}
+
+		if len(values) == 0 {
+			return nil
+		}
+
+		for _, v := range claimStrings(value) {
+			if slices.Contains(values, v) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: %s not one of %s", ErrClaimMismatch, name, strings.Join(values, ", "))
+	})
+}
+
+// RequireExpression returns middleware which only allows requests whose jwt claims satisfy the
+// CEL expression. See CompileClaimsExpression for the variables available to the expression.
+func RequireExpression(expression string) (echo.MiddlewareFunc, error) {
+	expr, err := CompileClaimsExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return RequireClaims(expr.Matcher()), nil
+}
+
+// MustRequireExpression wraps RequireExpression and panics in the event of an error.
+func MustRequireExpression(expression string) echo.MiddlewareFunc {
+	mdw, err := RequireExpression(expression)
+	if err != nil {
+		panic(err)
+	}
+
+	return mdw
+}
+
+// ClaimScopes returns the scopes granted by the claims. Scopes are read from the space
+// delimited scope claim, as defined by RFC 8693, and the scp claim which may be a list or string.
+func ClaimScopes(claims jwt.MapClaims) []string {
+	return actorx.ClaimScopes(claims)
+}
+
+func forbiddenError(err error) error {
+	return echo.NewHTTPError(http.StatusForbidden, "insufficient permissions").SetInternal(err)
+}
+
+// lookupClaim returns the claim value for a dot separated name. A claim matching the full name
+// is preferred so claims containing dots, such as urls, can still be referenced.
+func lookupClaim(claims jwt.MapClaims, name string) (any, bool) {
+	if v, ok := claims[name]; ok {
+		return v, true
+	}
+
+	var current any = map[string]any(claims)
+
+	for _, part := range strings.Split(name, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// claimStrings returns the string representations of a claim value, or each of its items for lists.
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case []any:
+		strs := make([]string, 0, len(v))
+
+		for _, item := range v {
+			strs = append(strs, fmt.Sprint(item))
+		}
+
+		return strs
+	case []string:
+		return v
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
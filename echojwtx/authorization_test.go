@@ -0,0 +1,247 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/echojwtx"
+)
+
+var testAuthorizationClaims = jwt.MapClaims{
+	"sub":    "urn:test:user",
+	"scope":  "read write",
+	"scp":    []any{"admin"},
+	"groups": []any{"devs", "ops"},
+	"tier":   float64(3),
+	"realm_access": map[string]any{
+		"roles": []any{"viewer"},
+	},
+	"https://example.com/tenant": "tnntten-abc",
+}
+
+// testAuthorization runs the middleware with the claims and returns the resulting status code.
+func testAuthorization(t *testing.T, mdw echo.MiddlewareFunc, claims jwt.MapClaims) int {
+	t.Helper()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	if claims != nil {
+		c.Set(echojwtx.ClaimsKey, claims)
+	}
+
+	err := mdw(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})(c)
+	if err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+
+	return rec.Code
+}
+
+func TestRequireScopes(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mdw    echo.MiddlewareFunc
+		claims jwt.MapClaims
+		expect int
+	}{
+		{"all scopes", echojwtx.RequireScopes("read", "write", "admin"), testAuthorizationClaims, http.StatusOK},
+		{"missing scope", echojwtx.RequireScopes("read", "delete"), testAuthorizationClaims, http.StatusForbidden},
+		{"any scope", echojwtx.RequireAnyScope("delete", "admin"), testAuthorizationClaims, http.StatusOK},
+		{"no matching scope", echojwtx.RequireAnyScope("delete"), testAuthorizationClaims, http.StatusForbidden},
+		{"no claims", echojwtx.RequireScopes("read"), nil, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, testAuthorization(t, tc.mdw, tc.claims))
+		})
+	}
+}
+
+func TestRequireClaim(t *testing.T) {
+	testCases := []struct {
+		name   string
+		claim  string
+		values []string
+		expect int
+	}{
+		{"present", "sub", nil, http.StatusOK},
+		{"missing", "email", nil, http.StatusForbidden},
+		{"value match", "sub", []string{"urn:test:other", "urn:test:user"}, http.StatusOK},
+		{"value mismatch", "sub", []string{"urn:test:other"}, http.StatusForbidden},
+		{"list contains", "groups", []string{"ops"}, http.StatusOK},
+		{"list missing", "groups", []string{"admins"}, http.StatusForbidden},
+		{"number", "tier", []string{"3"}, http.StatusOK},
+		{"nested", "realm_access.roles", []string{"viewer"}, http.StatusOK},
+		{"dotted name", "https://example.com/tenant", []string{"tnntten-abc"}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, testAuthorization(t, echojwtx.RequireClaim(tc.claim, tc.values...), testAuthorizationClaims))
+		})
+	}
+}
+
+func TestClaimsExpression(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		expect     bool
+		compileErr bool
+		evalErr    bool
+	}{
+		{name: "equals", expression: `claims.sub == "urn:test:user"`, expect: true},
+		{name: "not equals", expression: `claims.sub != 'urn:test:user'`, expect: false},
+		{name: "in list claim", expression: `"ops" in claims.groups`, expect: true},
+		{name: "in scopes", expression: `"write" in scopes && "admin" in scopes`, expect: true},
+		{name: "in literal list", expression: `claims.sub in ["a", "urn:test:user"]`, expect: true},
+		{name: "optional claim", expression: `has(claims.roles) && "ops" in claims.roles`, expect: false},
+		{name: "missing claim", expression: `"ops" in claims.roles`, evalErr: true},
+		{name: "has", expression: `has(claims.realm_access) && !has(claims.email)`, expect: true},
+		{name: "nested index", expression: `claims["realm_access"].roles[0] == "viewer"`, expect: true},
+		{name: "url claim", expression: `claims["https://example.com/tenant"].startsWith("tnntten-")`, expect: true},
+		{name: "numbers", expression: `claims.tier >= 3 && claims.tier < 4.5`, expect: true},
+		{name: "size", expression: `size(claims.groups) == 2`, expect: true},
+		{name: "or short circuits", expression: `true || claims.sub > 1`, expect: true},
+		{name: "grouping", expression: `!(claims.sub.endsWith("user") && false)`, expect: true},
+		{name: "contains", expression: `claims.sub.contains("test")`, expect: true},
+		{name: "comprehension", expression: `claims.groups.exists(g, g in ["ops", "admins"])`, expect: true},
+		{name: "type mismatch", expression: `claims.sub > 1`, evalErr: true},
+		{name: "non bool result", expression: `claims.sub`, evalErr: true},
+		{name: "unterminated string", expression: `claims.sub == "abc`, compileErr: true},
+		{name: "unknown identifier", expression: `user.sub == "abc"`, compileErr: true},
+		{name: "unknown method", expression: `claims.sub.unknown("abc")`, compileErr: true},
+		{name: "static type mismatch", expression: `scopes.size() > "1"`, compileErr: true},
+		{name: "static non bool result", expression: `size(scopes)`, compileErr: true},
+		{name: "trailing tokens", expression: `true true`, compileErr: true},
+		{name: "unbalanced", expression: `(true`, compileErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := echojwtx.CompileClaimsExpression(tc.expression)
+			if tc.compileErr {
+				assert.ErrorIs(t, err, echojwtx.ErrInvalidExpression)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expression, expr.String())
+
+			ok, err := expr.Match(testAuthorizationClaims)
+			if tc.evalErr {
+				assert.ErrorIs(t, err, echojwtx.ErrExpressionEvaluation)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, ok)
+		})
+	}
+
+	mdw, err := echojwtx.RequireExpression(`"devs" in claims.groups`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, testAuthorization(t, mdw, testAuthorizationClaims))
+
+	mdw = echojwtx.MustRequireExpression(`"admins" in claims.groups`)
+	assert.Equal(t, http.StatusForbidden, testAuthorization(t, mdw, testAuthorizationClaims))
+
+	assert.Panics(t, func() { echojwtx.MustRequireExpression(`claims.`) })
+}
+
+func TestClaimsExpressionCostLimit(t *testing.T) {
+	groups := make([]any, 1000)
+
+	for i := range groups {
+		groups[i] = fmt.Sprintf("group-%d", i)
+	}
+
+	expr := echojwtx.MustCompileClaimsExpression(`claims.groups.all(a, claims.groups.all(b, a + b != ""))`)
+
+	_, err := expr.Match(jwt.MapClaims{"groups": groups})
+	require.ErrorIs(t, err, echojwtx.ErrExpressionEvaluation)
+	assert.ErrorContains(t, err, "cost limit")
+}
+
+func TestAuthorizationWithAuth(t *testing.T) {
+	oauthClient, issuer, closer := OAuthTestClient("urn:test:user", "")
+	defer closer()
+
+	auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer:         issuer,
+		RefreshTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	gotClaimsCh := make(chan jwt.MapClaims, 2)
+
+	e := echo.New()
+
+	e.Use(auth.Middleware())
+
+	handler := func(c echo.Context) error {
+		gotClaimsCh <- echojwtx.Claims(c)
+		gotClaimsCh <- echojwtx.ClaimsFromContext(c.Request().Context())
+
+		return nil
+	}
+
+	e.GET("/allowed", handler, echojwtx.RequireScopes("test"))
+	e.GET("/denied", handler, echojwtx.RequireScopes("admin"))
+
+	srv := httptest.NewServer(e)
+
+	defer srv.Close()
+
+	for path, expectStatus := range map[string]int{"/allowed": http.StatusOK, "/denied": http.StatusForbidden} {
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err, "expected new request without error")
+
+		resp, err := oauthClient.Do(req)
+		require.NoError(t, err, "expected response without error")
+
+		_ = resp.Body.Close()
+
+		assert.Equal(t, expectStatus, resp.StatusCode, path)
+	}
+
+	for range 2 {
+		select {
+		case claims := <-gotClaimsCh:
+			require.NotNil(t, claims)
+			assert.Equal(t, "urn:test:user", claims["sub"])
+		case <-time.After(chanTimeout):
+			t.Error("failed to receive claims")
+		}
+	}
+}
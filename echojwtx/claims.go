@@ -0,0 +1,151 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrMissingRequiredClaim is returned when a token is missing a required claim.
+	ErrMissingRequiredClaim = errors.New("missing required claim")
+
+	// ErrTokenTooOld is returned when a token was issued longer ago than the max token age.
+	ErrTokenTooOld = errors.New("token exceeds max age")
+
+	// ErrUnsupportedAlgorithm is returned when an allowed signing algorithm isn't supported.
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+	// ErrUnsupportedClaims is returned when the JWTConfig NewClaimsFunc returns claims other than jwt.MapClaims.
+	ErrUnsupportedClaims = errors.New("unsupported claims type, jwt.MapClaims required")
+)
+
+// ClaimsConfig configures additional validation of token claims.
+type ClaimsConfig struct {
+	// Leeway is the allowed clock skew when validating the exp, nbf and iat claims.
+	// When set, tokens with an iat claim in the future are rejected.
+	Leeway time.Duration `mapstructure:"leeway"`
+
+	// Required are the claims every token must include.
+	Required []string `mapstructure:"required"`
+
+	// MaxAge is the maximum duration since a token was issued. When set, tokens must include the iat
+	// claim and tokens with an iat claim in the future are rejected.
+	MaxAge time.Duration `mapstructure:"max_age"`
+
+	// Algorithms are the allowed signing algorithms. If empty, any algorithm supported by the key is allowed.
+	Algorithms []string `mapstructure:"algorithms"`
+
+	// Strict rejects tokens with aud claims which can't be parsed, instead of logging and allowing them.
+	// Tokens with iss claims which can't be parsed are always rejected.
+	Strict bool `mapstructure:"strict"`
+}
+
+// validate ensures the allowed algorithms are supported.
+func (c ClaimsConfig) validate() error {
+	for _, alg := range c.Algorithms {
+		if jwt.GetSigningMethod(alg) == nil {
+			return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+		}
+	}
+
+	return nil
+}
+
+// parserOptions returns the jwt parser options applying the leeway and allowed algorithms.
+// If no algorithms are allowed, the signingMethod is the only algorithm allowed when set.
+func (c ClaimsConfig) parserOptions(signingMethod string) []jwt.ParserOption {
+	var options []jwt.ParserOption
+
+	if c.Leeway > 0 {
+		options = append(options, jwt.WithLeeway(c.Leeway))
+	}
+
+	if c.Leeway > 0 || c.MaxAge > 0 {
+		options = append(options, jwt.WithIssuedAt())
+	}
+
+	switch {
+	case len(c.Algorithms) != 0:
+		options = append(options, jwt.WithValidMethods(c.Algorithms))
+	case signingMethod != "":
+		options = append(options, jwt.WithValidMethods([]string{signingMethod}))
+	}
+
+	return options
+}
+
+// validateRequiredClaims ensures the claims include the required claims and don't exceed the max token age.
+func (a *Auth) validateRequiredClaims(claims jwt.MapClaims) error {
+	for _, name := range a.claimsConfig.Required {
+		if v, ok := claims[name]; !ok || v == nil {
+			a.logger.Error("jwt user missing required claim", zap.String("claim", name))
+
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(fmt.Errorf("%w: %s", ErrMissingRequiredClaim, name))
+		}
+	}
+
+	if a.claimsConfig.MaxAge > 0 {
+		iat, err := claims.GetIssuedAt()
+		if err != nil || iat == nil {
+			a.logger.Error("jwt user missing issued at", zap.Error(err), zap.Any("iat", claims["iat"]))
+
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(fmt.Errorf("%w: iat", ErrMissingRequiredClaim))
+		}
+
+		if time.Since(iat.Time) > a.claimsConfig.MaxAge+a.claimsConfig.Leeway {
+			a.logger.Error("jwt user token too old", zap.Time("iat", iat.Time))
+
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(ErrTokenTooOld)
+		}
+	}
+
+	return nil
+}
+
+// parseJWT parses and verifies the jwt in the same way as the echojwt default, applying the
+// configured leeway and allowed signing algorithms. When no algorithms are configured, the
+// JWTConfig SigningMethod is enforced if set.
+func (a *Auth) parseJWT(c echo.Context, auth string) (interface{}, error) {
+	claims := jwt.MapClaims{}
+
+	if a.JWTConfig.NewClaimsFunc != nil {
+		mapClaims, ok := a.JWTConfig.NewClaimsFunc(c).(jwt.MapClaims)
+		if !ok {
+			return nil, &echojwt.TokenError{Err: ErrUnsupportedClaims}
+		}
+
+		claims = mapClaims
+	}
+
+	token, err := jwt.ParseWithClaims(auth, claims, a.JWTConfig.KeyFunc, a.claimsConfig.parserOptions(a.JWTConfig.SigningMethod)...)
+	if err != nil {
+		return nil, &echojwt.TokenError{Token: token, Err: err}
+	}
+
+	if !token.Valid {
+		return nil, &echojwt.TokenError{Token: token, Err: errors.New("invalid token")} //nolint:err113 // matches echojwt
+	}
+
+	return token, nil
+}
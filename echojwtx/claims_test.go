@@ -0,0 +1,235 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	golangjwt "github.com/golang-jwt/jwt/v5"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/echojwtx"
+)
+
+func TestClaimsConfig(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	now := time.Now()
+
+	testCases := []struct {
+		name       string
+		config     echojwtx.ClaimsConfig
+		claims     jwt.Claims
+		extra      map[string]any
+		expectCode int
+	}{
+		{
+			name:       "expired",
+			claims:     jwt.Claims{Expiry: jwt.NewNumericDate(now.Add(-30 * time.Second))},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired within leeway",
+			config:     echojwtx.ClaimsConfig{Leeway: time.Minute},
+			claims:     jwt.Claims{Expiry: jwt.NewNumericDate(now.Add(-30 * time.Second))},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "not before within leeway",
+			config:     echojwtx.ClaimsConfig{Leeway: time.Minute},
+			claims:     jwt.Claims{NotBefore: jwt.NewNumericDate(now.Add(30 * time.Second))},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "issued in the future",
+			config:     echojwtx.ClaimsConfig{Leeway: time.Minute},
+			claims:     jwt.Claims{IssuedAt: jwt.NewNumericDate(now.Add(5 * time.Minute))},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "required claims",
+			config:     echojwtx.ClaimsConfig{Required: []string{"email", "jti"}},
+			claims:     jwt.Claims{ID: "token-id"},
+			extra:      map[string]any{"email": "user@example.com"},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "missing required claim",
+			config:     echojwtx.ClaimsConfig{Required: []string{"email"}},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "within max age",
+			config:     echojwtx.ClaimsConfig{MaxAge: time.Hour},
+			claims:     jwt.Claims{IssuedAt: jwt.NewNumericDate(now.Add(-time.Minute))},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "exceeds max age",
+			config:     echojwtx.ClaimsConfig{MaxAge: time.Hour},
+			claims:     jwt.Claims{IssuedAt: jwt.NewNumericDate(now.Add(-2 * time.Hour))},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "max age without issued at",
+			config:     echojwtx.ClaimsConfig{MaxAge: time.Hour},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "allowed algorithm",
+			config:     echojwtx.ClaimsConfig{Algorithms: []string{"RS256", "ES256"}},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "disallowed algorithm",
+			config:     echojwtx.ClaimsConfig{Algorithms: []string{"ES256"}},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "unparsable audience",
+			extra:      map[string]any{"aud": []any{"urn:test:aud", 123}},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "strict unparsable audience",
+			config:     echojwtx.ClaimsConfig{Strict: true},
+			extra:      map[string]any{"aud": []any{"urn:test:aud", 123}},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "unparsable issuer",
+			extra:      map[string]any{"iss": 123},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "strict unparsable issuer",
+			config:     echojwtx.ClaimsConfig{Strict: true},
+			extra:      map[string]any{"iss": 123},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "strict",
+			config:     echojwtx.ClaimsConfig{Strict: true},
+			expectCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+				Issuer: issuer,
+				Claims: tc.config,
+			})
+			require.NoError(t, err, "no error expected for NewAuth")
+
+			e := echo.New()
+
+			e.Use(auth.Middleware())
+
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(http.StatusOK, echojwtx.Actor(c))
+			})
+
+			claims := tc.claims
+			claims.Subject = "urn:test:user"
+
+			if _, ok := tc.extra["iss"]; !ok {
+				claims.Issuer = issuer
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+testHelperSignToken(TestPrivRSAKey1ID, claims, tc.extra))
+
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+		})
+	}
+}
+
+func TestClaimsConfigUnsupportedAlgorithm(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	_, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer: issuer,
+		Claims: echojwtx.ClaimsConfig{Algorithms: []string{"XX256"}},
+	})
+	assert.ErrorIs(t, err, echojwtx.ErrUnsupportedAlgorithm)
+}
+
+func TestClaimsJWTConfig(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	testCases := []struct {
+		name       string
+		config     echojwt.Config
+		expectCode int
+	}{
+		{
+			name:       "signing method",
+			config:     echojwt.Config{SigningMethod: "RS256"},
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "other signing method",
+			config:     echojwt.Config{SigningMethod: "ES256"},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "unsupported claims",
+			config:     echojwt.Config{NewClaimsFunc: func(echo.Context) golangjwt.Claims { return &golangjwt.RegisteredClaims{} }},
+			expectCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+				Issuer: issuer,
+			}, echojwtx.WithJWTConfig(tc.config))
+			require.NoError(t, err, "no error expected for NewAuth")
+
+			e := echo.New()
+
+			e.Use(auth.Middleware())
+
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(http.StatusOK, echojwtx.Actor(c))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+testHelperSignToken(TestPrivRSAKey1ID, jwt.Claims{Issuer: issuer, Subject: "urn:test:user"}, nil))
+
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+		})
+	}
+}
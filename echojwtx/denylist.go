@@ -0,0 +1,299 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrTokenRevoked is returned when a token matches an entry in the denylist.
+	ErrTokenRevoked = errors.New("token has been revoked")
+
+	// ErrInvalidDenylistEntry is returned when a denylist entry doesn't identify exactly one token id or subject.
+	ErrInvalidDenylistEntry = errors.New("invalid denylist entry")
+)
+
+// DenylistEntry revokes a single token by its jti claim or all tokens for a subject.
+type DenylistEntry struct {
+	// TokenID revokes the token with the matching jti claim.
+	TokenID string `json:"jti,omitempty"`
+
+	// Subject revokes tokens for the actor subject, as selected by the issuer's ActorClaim.
+	Subject string `json:"sub,omitempty"`
+
+	// IssuedBefore limits a Subject entry to tokens issued before the timestamp. Tokens without
+	// an iat claim are treated as issued before. If zero, all tokens for the subject are revoked.
+	IssuedBefore time.Time `json:"issued_before,omitzero"`
+
+	// ExpiresAt is when the entry no longer applies, typically the expiry of the revoked tokens.
+	// If zero the entry never expires.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+
+	// Reason is an optional description of why the tokens were revoked.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate ensures the entry identifies exactly one token id or subject.
+func (e DenylistEntry) Validate() error {
+	switch {
+	case e.TokenID == "" && e.Subject == "":
+		return fmt.Errorf("%w: jti or sub required", ErrInvalidDenylistEntry)
+	case e.TokenID != "" && e.Subject != "":
+		return fmt.Errorf("%w: only one of jti or sub may be set", ErrInvalidDenylistEntry)
+	case e.TokenID != "" && !e.IssuedBefore.IsZero():
+		return fmt.Errorf("%w: issued_before requires sub", ErrInvalidDenylistEntry)
+	}
+
+	return nil
+}
+
+// Expired returns true if the entry no longer applies at the provided time.
+func (e DenylistEntry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Revokes returns true if the entry revokes a token with the provided jti, subject and issued at time.
+func (e DenylistEntry) Revokes(tokenID, subject string, issuedAt time.Time, now time.Time) bool {
+	if e.Expired(now) {
+		return false
+	}
+
+	if e.TokenID != "" {
+		return e.TokenID == tokenID
+	}
+
+	if e.Subject == "" || e.Subject != subject {
+		return false
+	}
+
+	if e.IssuedBefore.IsZero() || issuedAt.IsZero() {
+		return true
+	}
+
+	return issuedAt.Before(e.IssuedBefore)
+}
+
+// merge returns the entry combined with the existing entry for the same key, keeping the most
+// restrictive IssuedBefore and ExpiresAt so adding an entry never shortens or narrows a revocation.
+// A zero IssuedBefore or ExpiresAt is the most restrictive value. The entry's Reason is kept if set.
+func (e DenylistEntry) merge(existing DenylistEntry, now time.Time) DenylistEntry {
+	if existing.Expired(now) {
+		return e
+	}
+
+	if existing.IssuedBefore.IsZero() || (!e.IssuedBefore.IsZero() && existing.IssuedBefore.After(e.IssuedBefore)) {
+		e.IssuedBefore = existing.IssuedBefore
+	}
+
+	if existing.ExpiresAt.IsZero() || (!e.ExpiresAt.IsZero() && existing.ExpiresAt.After(e.ExpiresAt)) {
+		e.ExpiresAt = existing.ExpiresAt
+	}
+
+	if e.Reason == "" {
+		e.Reason = existing.Reason
+	}
+
+	return e
+}
+
+// key returns the unique key for the entry. Values are encoded so keys are safe for stores with
+// restricted key characters.
+func (e DenylistEntry) key() string {
+	if e.TokenID != "" {
+		return denylistTokenKey(e.TokenID)
+	}
+
+	return denylistSubjectKey(e.Subject)
+}
+
+func denylistTokenKey(tokenID string) string {
+	return "jti." + base64.RawURLEncoding.EncodeToString([]byte(tokenID))
+}
+
+func denylistSubjectKey(subject string) string {
+	return "sub." + base64.RawURLEncoding.EncodeToString([]byte(subject))
+}
+
+// Denylist stores revoked tokens. It is checked after the token signature has been validated.
+type Denylist interface {
+	// Add adds the entry to the denylist. An existing entry for the same token id or subject is merged
+	// with the entry, keeping the most restrictive IssuedBefore and ExpiresAt.
+	Add(ctx context.Context, entry DenylistEntry) error
+
+	// Lookup returns the entries for the token id and subject. Empty values are not looked up.
+	Lookup(ctx context.Context, tokenID, subject string) ([]DenylistEntry, error)
+}
+
+// WithDenylist sets the Denylist revoked tokens are checked against.
+func WithDenylist(denylist Denylist) Opts {
+	return func(a *Auth) {
+		a.denylist = denylist
+	}
+}
+
+// checkDenylist returns an error if the token has been revoked. Tokens are rejected if the
+// denylist can't be checked.
+func (a *Auth) checkDenylist(ctx context.Context, claims jwt.MapClaims, subject string) error {
+	if a.denylist == nil {
+		return nil
+	}
+
+	tokenID, _ := claims["jti"].(string)
+
+	entries, err := a.denylist.Lookup(ctx, tokenID, subject)
+	if err != nil {
+		a.logger.Error("failed to check token denylist", zap.Error(err))
+
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(err)
+	}
+
+	var issuedAt time.Time
+
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		issuedAt = iat.Time
+	}
+
+	now := time.Now()
+
+	for _, entry := range entries {
+		if entry.Revokes(tokenID, subject, issuedAt, now) {
+			a.logger.Warn("jwt has been revoked", zap.String("jti", tokenID), zap.String("subject", subject), zap.String("reason", entry.Reason))
+
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(ErrTokenRevoked)
+		}
+	}
+
+	return nil
+}
+
+// MemoryDenylist is an in-memory Denylist. Expired entries are removed as new entries are added.
+type MemoryDenylist struct {
+	mu      sync.RWMutex
+	entries map[string]DenylistEntry
+	now     func() time.Time
+}
+
+// NewMemoryDenylist creates a new empty MemoryDenylist.
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{
+		entries: make(map[string]DenylistEntry),
+		now:     time.Now,
+	}
+}
+
+// Add implements Denylist.
+func (d *MemoryDenylist) Add(_ context.Context, entry DenylistEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	key := entry.key()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if existing, ok := d.entries[key]; ok {
+		entry = entry.merge(existing, d.now())
+	}
+
+	d.setLocked(key, entry)
+
+	return nil
+}
+
+// Lookup implements Denylist.
+func (d *MemoryDenylist) Lookup(_ context.Context, tokenID, subject string) ([]DenylistEntry, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var entries []DenylistEntry
+
+	if tokenID != "" {
+		if entry, ok := d.entries[denylistTokenKey(tokenID)]; ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	if subject != "" {
+		if entry, ok := d.entries[denylistSubjectKey(subject)]; ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// set replaces the entry for the key.
+func (d *MemoryDenylist) set(key string, entry DenylistEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.setLocked(key, entry)
+}
+
+func (d *MemoryDenylist) setLocked(key string, entry DenylistEntry) {
+	now := d.now()
+
+	for k, e := range d.entries {
+		if e.Expired(now) {
+			delete(d.entries, k)
+		}
+	}
+
+	if !entry.Expired(now) {
+		d.entries[key] = entry
+	}
+}
+
+func (d *MemoryDenylist) delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.entries, key)
+}
+
+// DenylistHandler returns an echo handler which adds the DenylistEntry in the json request body to
+// the denylist and responds with the added entry. The handler doesn't authorize requests itself and
+// should be protected with authorization middleware, such as RequireScopes.
+func DenylistHandler(denylist Denylist) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var entry DenylistEntry
+
+		if err := c.Bind(&entry); err != nil {
+			return err
+		}
+
+		if err := entry.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		}
+
+		if err := denylist.Add(c.Request().Context(), entry); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to add denylist entry").SetInternal(err)
+		}
+
+		return c.JSON(http.StatusCreated, entry)
+	}
+}
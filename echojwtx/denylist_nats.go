@@ -0,0 +1,216 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// natsDenylistMaxAttempts is the number of times Add tries to store an entry when the key is
+	// updated concurrently by other instances.
+	natsDenylistMaxAttempts = 10
+
+	// natsDenylistRetryBackoff is the delay before retrying a conflicting update, multiplied by the attempt.
+	natsDenylistRetryBackoff = 10 * time.Millisecond
+)
+
+// NATSDenylist is a Denylist stored in a NATS KV bucket. Entries are watched and cached in memory
+// so lookups don't require a request to NATS, entries added by other instances are applied as
+// they're received.
+type NATSDenylist struct {
+	kv      nats.KeyValue
+	watcher nats.KeyWatcher
+	cache   *MemoryDenylist
+
+	mu        sync.Mutex
+	revisions map[string]uint64
+}
+
+// NewNATSDenylist creates a new NATSDenylist backed by the KV bucket. The existing entries are
+// loaded before returning. The bucket is watched until the denylist is closed or the context is canceled.
+func NewNATSDenylist(ctx context.Context, kv nats.KeyValue) (*NATSDenylist, error) {
+	watcher, err := kv.WatchAll(nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed watching denylist bucket: %w", err)
+	}
+
+	d := &NATSDenylist{
+		kv:        kv,
+		watcher:   watcher,
+		cache:     NewMemoryDenylist(),
+		revisions: make(map[string]uint64),
+	}
+
+	// the watcher sends all existing entries followed by a nil entry once it's caught up.
+	for {
+		select {
+		case <-ctx.Done():
+			_ = watcher.Stop()
+
+			return nil, ctx.Err()
+		case entry, ok := <-watcher.Updates():
+			if !ok {
+				return nil, fmt.Errorf("failed loading denylist bucket: %w", nats.ErrBadSubscription)
+			}
+
+			if entry == nil {
+				go d.watch()
+
+				return d, nil
+			}
+
+			d.apply(entry)
+		}
+	}
+}
+
+// Add implements Denylist. The entry is merged with the stored entry for the same key, conflicting
+// updates from other instances are retried with a backoff, up to 10 attempts or until the context is done.
+func (d *NATSDenylist) Add(ctx context.Context, entry DenylistEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	key := entry.key()
+
+	for attempt := 1; ; attempt++ {
+		merged, revision, err := d.put(key, entry)
+		if err == nil {
+			// update the cache immediately so the entry applies without waiting for the watcher.
+			d.setRevision(key, revision, func() {
+				d.cache.set(key, merged)
+			})
+
+			return nil
+		}
+
+		if !errors.Is(err, nats.ErrKeyExists) || attempt == natsDenylistMaxAttempts {
+			return fmt.Errorf("failed storing denylist entry after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(time.Duration(attempt) * natsDenylistRetryBackoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("failed storing denylist entry: %w", errors.Join(err, ctx.Err()))
+		case <-timer.C:
+		}
+	}
+}
+
+// put stores the entry merged with the current value of the key, returning the stored entry and its
+// revision. nats.ErrKeyExists is returned if the key was changed since it was read.
+func (d *NATSDenylist) put(key string, entry DenylistEntry) (DenylistEntry, uint64, error) {
+	current, err := d.kv.Get(key)
+	if err != nil && !errors.Is(err, nats.ErrKeyNotFound) {
+		return entry, 0, err
+	}
+
+	var existing DenylistEntry
+
+	if current != nil && json.Unmarshal(current.Value(), &existing) == nil && existing.Validate() == nil {
+		entry = entry.merge(existing, d.cache.now())
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return entry, 0, err
+	}
+
+	var revision uint64
+
+	if current == nil {
+		revision, err = d.kv.Create(key, value)
+	} else {
+		revision, err = d.kv.Update(key, value, current.Revision())
+	}
+
+	return entry, revision, err
+}
+
+// setRevision calls update if the revision is newer than the last revision applied to the cache
+// for the key, so an older revision never replaces a newer one.
+func (d *NATSDenylist) setRevision(key string, revision uint64, update func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if revision < d.revisions[key] {
+		return
+	}
+
+	d.revisions[key] = revision
+
+	update()
+}
+
+// Lookup implements Denylist.
+func (d *NATSDenylist) Lookup(ctx context.Context, tokenID, subject string) ([]DenylistEntry, error) {
+	return d.cache.Lookup(ctx, tokenID, subject)
+}
+
+// Close stops watching the KV bucket.
+func (d *NATSDenylist) Close() error {
+	err := d.watcher.Stop()
+	if errors.Is(err, nats.ErrBadSubscription) {
+		return nil
+	}
+
+	return err
+}
+
+func (d *NATSDenylist) watch() {
+	for entry := range d.watcher.Updates() {
+		if entry != nil {
+			d.apply(entry)
+		}
+	}
+}
+
+func (d *NATSDenylist) apply(kve nats.KeyValueEntry) {
+	key := kve.Key()
+
+	if !strings.HasPrefix(key, "jti.") && !strings.HasPrefix(key, "sub.") {
+		return
+	}
+
+	if kve.Operation() != nats.KeyValuePut {
+		d.setRevision(key, kve.Revision(), func() {
+			d.cache.delete(key)
+		})
+
+		return
+	}
+
+	var entry DenylistEntry
+
+	if err := json.Unmarshal(kve.Value(), &entry); err != nil || entry.Validate() != nil || entry.key() != key {
+		return
+	}
+
+	d.setRevision(key, kve.Revision(), func() {
+		d.cache.set(key, entry)
+	})
+}
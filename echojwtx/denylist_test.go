@@ -0,0 +1,399 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/echojwtx"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+func TestDenylistEntry(t *testing.T) {
+	now := time.Now()
+	issuedAt := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name     string
+		entry    echojwtx.DenylistEntry
+		tokenID  string
+		issuedAt time.Time
+		expect   bool
+	}{
+		{"token id", echojwtx.DenylistEntry{TokenID: "abc"}, "abc", issuedAt, true},
+		{"other token id", echojwtx.DenylistEntry{TokenID: "xyz"}, "abc", issuedAt, false},
+		{"subject", echojwtx.DenylistEntry{Subject: "urn:test:user"}, "abc", issuedAt, true},
+		{"other subject", echojwtx.DenylistEntry{Subject: "urn:test:other"}, "abc", issuedAt, false},
+		{"issued before", echojwtx.DenylistEntry{Subject: "urn:test:user", IssuedBefore: now}, "abc", issuedAt, true},
+		{"issued after", echojwtx.DenylistEntry{Subject: "urn:test:user", IssuedBefore: issuedAt.Add(-time.Minute)}, "abc", issuedAt, false},
+		{"missing issued at", echojwtx.DenylistEntry{Subject: "urn:test:user", IssuedBefore: now}, "abc", time.Time{}, true},
+		{"expired", echojwtx.DenylistEntry{TokenID: "abc", ExpiresAt: now.Add(-time.Minute)}, "abc", issuedAt, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.entry.Validate())
+
+			assert.Equal(t, tc.expect, tc.entry.Revokes(tc.tokenID, "urn:test:user", tc.issuedAt, now))
+		})
+	}
+
+	for _, entry := range []echojwtx.DenylistEntry{
+		{},
+		{TokenID: "abc", Subject: "urn:test:user"},
+		{TokenID: "abc", IssuedBefore: now},
+	} {
+		assert.ErrorIs(t, entry.Validate(), echojwtx.ErrInvalidDenylistEntry)
+	}
+}
+
+func TestDenylistAuth(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	denylist := echojwtx.NewMemoryDenylist()
+
+	auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer:         issuer,
+		RefreshTimeout: 5 * time.Second,
+	}, echojwtx.WithDenylist(denylist))
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	e := echo.New()
+
+	e.Use(auth.Middleware())
+
+	e.GET("/test", func(c echo.Context) error {
+		return c.String(http.StatusOK, echojwtx.Actor(c))
+	})
+
+	now := time.Now()
+
+	do := func(subject, tokenID string, issuedAt time.Time) int {
+		token := testHelperSignToken(TestPrivRSAKey1ID, jwt.Claims{
+			Issuer:   issuer,
+			Subject:  subject,
+			ID:       tokenID,
+			IssuedAt: jwt.NewNumericDate(issuedAt),
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	ctx := context.Background()
+
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{TokenID: "revoked-token"}))
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{Subject: "urn:test:revoked"}))
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{Subject: "urn:test:reauth", IssuedBefore: now.Add(-time.Minute)}))
+
+	assert.Equal(t, http.StatusOK, do("urn:test:user", "valid-token", now))
+	assert.Equal(t, http.StatusUnauthorized, do("urn:test:user", "revoked-token", now))
+	assert.Equal(t, http.StatusUnauthorized, do("urn:test:revoked", "valid-token", now))
+	assert.Equal(t, http.StatusUnauthorized, do("urn:test:reauth", "valid-token", now.Add(-time.Hour)))
+	assert.Equal(t, http.StatusOK, do("urn:test:reauth", "valid-token", now))
+}
+
+func TestDenylistHandler(t *testing.T) {
+	denylist := echojwtx.NewMemoryDenylist()
+
+	e := echo.New()
+
+	e.POST("/denylist", echojwtx.DenylistHandler(denylist))
+
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/denylist", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, post(`{"sub": "urn:test:user", "issued_before": "2023-01-02T03:04:05Z", "reason": "leaked"}`))
+	assert.Equal(t, http.StatusBadRequest, post(`{"reason": "missing token"}`))
+	assert.Equal(t, http.StatusBadRequest, post(`{"jti": 1}`))
+
+	entries, err := denylist.Lookup(context.Background(), "", "urn:test:user")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, "leaked", entries[0].Reason)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), entries[0].IssuedBefore)
+}
+
+func TestNATSDenylist(t *testing.T) {
+	ctx := context.Background()
+
+	srv, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer srv.Close()
+
+	kv, err := srv.JetStream.CreateKeyValue(&nats.KeyValueConfig{Bucket: "denylist"})
+	require.NoError(t, err)
+
+	_, err = kv.Put("invalid", []byte("ignored"))
+	require.NoError(t, err)
+
+	first, err := echojwtx.NewNATSDenylist(ctx, kv)
+	require.NoError(t, err)
+
+	defer first.Close() //nolint:errcheck // within test
+
+	require.NoError(t, first.Add(ctx, echojwtx.DenylistEntry{TokenID: "revoked-token"}))
+
+	second, err := echojwtx.NewNATSDenylist(ctx, kv)
+	require.NoError(t, err)
+
+	defer second.Close() //nolint:errcheck // within test
+
+	entries, err := second.Lookup(ctx, "revoked-token", "urn:test:user")
+	require.NoError(t, err)
+	assert.Equal(t, []echojwtx.DenylistEntry{{TokenID: "revoked-token"}}, entries)
+
+	require.NoError(t, second.Add(ctx, echojwtx.DenylistEntry{Subject: "urn:test:user", Reason: "leaked"}))
+
+	assert.Eventually(t, func() bool {
+		entries, err := first.Lookup(ctx, "", "urn:test:user")
+
+		return err == nil && len(entries) == 1 && entries[0].Reason == "leaked"
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, kv.Purge("jti."+"cmV2b2tlZC10b2tlbg"))
+
+	assert.Eventually(t, func() bool {
+		entries, err := second.Lookup(ctx, "revoked-token", "")
+
+		return err == nil && len(entries) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	assert.ErrorIs(t, first.Add(ctx, echojwtx.DenylistEntry{}), echojwtx.ErrInvalidDenylistEntry)
+
+	// Re-adding the purged token after it was deleted creates it again.
+	require.NoError(t, second.Add(ctx, echojwtx.DenylistEntry{TokenID: "revoked-token"}))
+
+	t.Run("merge", func(t *testing.T) {
+		testDenylistMerge(t, first)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+
+		for i := range 10 {
+			wg.Add(1)
+
+			go func(denylist *echojwtx.NATSDenylist) {
+				defer wg.Done()
+
+				assert.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{Subject: "urn:test:concurrent"}))
+			}([]*echojwtx.NATSDenylist{first, second}[i%2])
+		}
+
+		wg.Wait()
+	})
+}
+
+// racingKeyValue stores a newer entry after each create, before the denylist updates its cache.
+type racingKeyValue struct {
+	nats.KeyValue
+
+	newer func(key string)
+}
+
+func (kv *racingKeyValue) Create(key string, value []byte) (uint64, error) {
+	revision, err := kv.KeyValue.Create(key, value)
+	if err == nil {
+		kv.newer(key)
+	}
+
+	return revision, err
+}
+
+// conflictingKeyValue fails every create as if another instance created the key first.
+type conflictingKeyValue struct {
+	nats.KeyValue
+
+	attempts atomic.Int32
+}
+
+func (kv *conflictingKeyValue) Create(string, []byte) (uint64, error) {
+	kv.attempts.Add(1)
+
+	return 0, nats.ErrKeyExists
+}
+
+func TestNATSDenylistRevisions(t *testing.T) {
+	ctx := context.Background()
+
+	srv, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer srv.Close()
+
+	bucket, err := srv.JetStream.CreateKeyValue(&nats.KeyValueConfig{Bucket: "denylist"})
+	require.NoError(t, err)
+
+	t.Run("older revision ignored", func(t *testing.T) {
+		var denylist *echojwtx.NATSDenylist
+
+		lookupReason := func() string {
+			entries, err := denylist.Lookup(ctx, "", "urn:test:race")
+			if err != nil || len(entries) != 1 {
+				return ""
+			}
+
+			return entries[0].Reason
+		}
+
+		kv := &racingKeyValue{KeyValue: bucket}
+		kv.newer = func(key string) {
+			value, err := json.Marshal(echojwtx.DenylistEntry{Subject: "urn:test:race", Reason: "newer"})
+			require.NoError(t, err)
+
+			_, err = bucket.Put(key, value)
+			require.NoError(t, err)
+
+			require.Eventually(t, func() bool {
+				return lookupReason() == "newer"
+			}, time.Second, 10*time.Millisecond)
+		}
+
+		denylist, err = echojwtx.NewNATSDenylist(ctx, kv)
+		require.NoError(t, err)
+
+		defer denylist.Close() //nolint:errcheck // within test
+
+		require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{Subject: "urn:test:race", Reason: "older"}))
+
+		assert.Equal(t, "newer", lookupReason())
+	})
+
+	t.Run("conflicts retried until attempts exhausted", func(t *testing.T) {
+		kv := &conflictingKeyValue{KeyValue: bucket}
+
+		denylist, err := echojwtx.NewNATSDenylist(ctx, kv)
+		require.NoError(t, err)
+
+		defer denylist.Close() //nolint:errcheck // within test
+
+		err = denylist.Add(ctx, echojwtx.DenylistEntry{Subject: "urn:test:conflict"})
+		require.ErrorIs(t, err, nats.ErrKeyExists)
+		assert.Equal(t, int32(10), kv.attempts.Load())
+	})
+
+	t.Run("conflicts stop when context canceled", func(t *testing.T) {
+		kv := &conflictingKeyValue{KeyValue: bucket}
+
+		denylist, err := echojwtx.NewNATSDenylist(ctx, kv)
+		require.NoError(t, err)
+
+		defer denylist.Close() //nolint:errcheck // within test
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err = denylist.Add(cancelCtx, echojwtx.DenylistEntry{Subject: "urn:test:conflict"})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int32(1), kv.attempts.Load())
+	})
+}
+
+func TestMemoryDenylistMerge(t *testing.T) {
+	testDenylistMerge(t, echojwtx.NewMemoryDenylist())
+}
+
+// testDenylistMerge ensures adding an entry for an existing subject can't downgrade the revocation.
+func testDenylistMerge(t *testing.T, denylist echojwtx.Denylist) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	subject := "urn:test:merge"
+
+	lookup := func() echojwtx.DenylistEntry {
+		entries, err := denylist.Lookup(ctx, "", subject)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		return entries[0]
+	}
+
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{
+		Subject:      subject,
+		IssuedBefore: now,
+		ExpiresAt:    now.Add(time.Hour),
+		Reason:       "leaked",
+	}))
+
+	// An earlier issued before and expiry don't narrow the revocation.
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{
+		Subject:      subject,
+		IssuedBefore: now.Add(-time.Hour),
+		ExpiresAt:    now.Add(time.Minute),
+	}))
+
+	entry := lookup()
+	assert.True(t, now.Equal(entry.IssuedBefore))
+	assert.True(t, now.Add(time.Hour).Equal(entry.ExpiresAt))
+	assert.Equal(t, "leaked", entry.Reason)
+
+	// Later values widen the revocation.
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{
+		Subject:      subject,
+		IssuedBefore: now.Add(time.Minute),
+		ExpiresAt:    now.Add(2 * time.Hour),
+		Reason:       "rotated",
+	}))
+
+	entry = lookup()
+	assert.True(t, now.Add(time.Minute).Equal(entry.IssuedBefore))
+	assert.True(t, now.Add(2*time.Hour).Equal(entry.ExpiresAt))
+	assert.Equal(t, "rotated", entry.Reason)
+
+	// Zero values revoke all tokens and never expire.
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{Subject: subject}))
+	require.NoError(t, denylist.Add(ctx, echojwtx.DenylistEntry{
+		Subject:      subject,
+		IssuedBefore: now,
+		ExpiresAt:    now.Add(time.Hour),
+	}))
+
+	entry = lookup()
+	assert.True(t, entry.IssuedBefore.IsZero())
+	assert.True(t, entry.ExpiresAt.IsZero())
+}
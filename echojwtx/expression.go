@@ -0,0 +1,132 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// DefaultExpressionCostLimit is the default maximum runtime cost of evaluating a claims expression.
+// Expressions which exceed the limit, such as comprehensions over very large claims, fail to evaluate.
+var DefaultExpressionCostLimit uint64 = 100_000
+
+var (
+	// ErrInvalidExpression is returned when a claims expression can't be compiled.
+	ErrInvalidExpression = errors.New("invalid claims expression")
+
+	// ErrExpressionEvaluation is returned when a claims expression fails to evaluate, such as
+	// when comparing values of different types.
+	ErrExpressionEvaluation = errors.New("claims expression evaluation failed")
+)
+
+// claimsEnv returns the CEL environment claims expressions are compiled in.
+var claimsEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("scopes", cel.ListType(cel.StringType)),
+	)
+})
+
+// ClaimsExpression is a compiled CEL expression evaluated against jwt claims.
+type ClaimsExpression struct {
+	source  string
+	program cel.Program
+}
+
+// CompileClaimsExpression compiles a CEL expression which must evaluate to a bool.
+//
+// The expression may reference the jwt claims with the claims map, such as claims.sub or
+// claims["https://example.com/roles"], and the granted scopes with the scopes list, see ClaimScopes.
+// Referencing a claim which doesn't exist is an evaluation error, use has(claims.x) to check for
+// optional claims. Evaluation is limited to DefaultExpressionCostLimit. For example:
+//
+//	"admin" in claims.groups || ("write" in scopes && claims.sub.startsWith("svc-"))
+func CompileClaimsExpression(expression string) (*ClaimsExpression, error) {
+	env, err := claimsEnv()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExpression, err)
+	}
+
+	ast, iss := env.Compile(expression)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExpression, iss.Err())
+	}
+
+	if out := ast.OutputType(); !out.IsExactType(types.BoolType) && !out.IsExactType(types.DynType) {
+		return nil, fmt.Errorf("%w: expression result is %s not bool", ErrInvalidExpression, out)
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(DefaultExpressionCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExpression, err)
+	}
+
+	return &ClaimsExpression{source: expression, program: program}, nil
+}
+
+// MustCompileClaimsExpression wraps CompileClaimsExpression and panics in the event of an error.
+func MustCompileClaimsExpression(expression string) *ClaimsExpression {
+	expr, err := CompileClaimsExpression(expression)
+	if err != nil {
+		panic(err)
+	}
+
+	return expr
+}
+
+// String returns the source of the expression.
+func (e *ClaimsExpression) String() string {
+	return e.source
+}
+
+// Match reports whether the claims satisfy the expression.
+func (e *ClaimsExpression) Match(claims jwt.MapClaims) (bool, error) {
+	out, _, err := e.program.Eval(map[string]any{
+		"claims": map[string]any(claims),
+		"scopes": ClaimScopes(claims),
+	})
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrExpressionEvaluation, err)
+	}
+
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: expression result is %s not bool", ErrExpressionEvaluation, out.Type().TypeName())
+	}
+
+	return b, nil
+}
+
+// Matcher returns a ClaimsMatcher which requires the claims satisfy the expression.
+func (e *ClaimsExpression) Matcher() ClaimsMatcher {
+	return func(claims jwt.MapClaims) error {
+		ok, err := e.Match(claims)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrExpressionNotSatisfied, e.source)
+		}
+
+		return nil
+	}
+}
@@ -39,6 +39,51 @@ func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet) {
 	flags.String("oidc-issuer", "", "expected issuer of OIDC JWT")
 	viperx.MustBindFlag(v, "oidc.issuer", flags.Lookup("oidc-issuer"))
 
+	flags.Bool("oidc-allow-any-issuer", false, "trust OIDC JWTs from any issuer signed by the local JWKS keys when no issuer is set")
+	viperx.MustBindFlag(v, "oidc.allow_any_issuer", flags.Lookup("oidc-allow-any-issuer"))
+
 	flags.Duration("oidc-jwks-remote-timeout", DefaultOIDCJWKSRemoteTimeout, "timeout for remote JWKS fetching")
 	viperx.MustBindFlag(v, "oidc.jwks.remote-timeout", flags.Lookup("oidc-jwks-remote-timeout"))
+
+	flags.String("oidc-jwks-file", "", "local JWKS file used instead of OIDC discovery, reloaded when changed")
+	viperx.MustBindFlag(v, "oidc.jwks.file", flags.Lookup("oidc-jwks-file"))
+
+	flags.StringArray("oidc-jwks-pem-keys", nil, "PEM encoded public keys used instead of OIDC discovery")
+	viperx.MustBindFlag(v, "oidc.jwks.pem_keys", flags.Lookup("oidc-jwks-pem-keys"))
+
+	flags.String("oidc-jwks-cache-file", "", "file the fetched JWKS is cached in, used when OIDC discovery fails")
+	viperx.MustBindFlag(v, "oidc.jwks.cache_file", flags.Lookup("oidc-jwks-cache-file"))
+
+	flags.Duration("oidc-leeway", 0, "allowed clock skew when validating the exp, nbf and iat claims")
+	viperx.MustBindFlag(v, "oidc.claims.leeway", flags.Lookup("oidc-leeway"))
+
+	flags.StringSlice("oidc-required-claims", nil, "claims every OIDC JWT must include")
+	viperx.MustBindFlag(v, "oidc.claims.required", flags.Lookup("oidc-required-claims"))
+
+	flags.Duration("oidc-max-token-age", 0, "maximum duration since an OIDC JWT was issued, 0 for no limit")
+	viperx.MustBindFlag(v, "oidc.claims.max_age", flags.Lookup("oidc-max-token-age"))
+
+	flags.StringSlice("oidc-signing-algorithms", nil, "allowed OIDC JWT signing algorithms, any supported algorithm if empty")
+	viperx.MustBindFlag(v, "oidc.claims.algorithms", flags.Lookup("oidc-signing-algorithms"))
+
+	flags.Bool("oidc-strict-claims", false, "reject OIDC JWTs with aud claims which can't be parsed")
+	viperx.MustBindFlag(v, "oidc.claims.strict", flags.Lookup("oidc-strict-claims"))
+
+	flags.Bool("oidc-introspection", false, "validate opaque tokens with the issuer's introspection endpoint")
+	viperx.MustBindFlag(v, "oidc.introspection.enabled", flags.Lookup("oidc-introspection"))
+
+	flags.String("oidc-introspection-endpoint", "", "introspection endpoint, discovered from the issuer if not set")
+	viperx.MustBindFlag(v, "oidc.introspection.endpoint", flags.Lookup("oidc-introspection-endpoint"))
+
+	flags.String("oidc-introspection-client-id", "", "client identifier used to authenticate with the introspection endpoint")
+	viperx.MustBindFlag(v, "oidc.introspection.client.id", flags.Lookup("oidc-introspection-client-id"))
+
+	flags.String("oidc-introspection-client-secret", "", "client secret used to authenticate with the introspection endpoint")
+	viperx.MustBindFlag(v, "oidc.introspection.client.secret", flags.Lookup("oidc-introspection-client-secret"))
+
+	flags.Duration("oidc-introspection-cache-ttl", DefaultIntrospectionCacheTTL, "maximum duration introspection responses are cached")
+	viperx.MustBindFlag(v, "oidc.introspection.cache_ttl", flags.Lookup("oidc-introspection-cache-ttl"))
+
+	flags.Duration("oidc-introspection-negative-cache-ttl", DefaultIntrospectionNegativeCacheTTL, "duration inactive and failed introspection responses are cached")
+	viperx.MustBindFlag(v, "oidc.introspection.negative_cache_ttl", flags.Lookup("oidc-introspection-negative-cache-ttl"))
 }
@@ -17,12 +17,15 @@ package echojwtx
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
+
+	"go.infratographer.com/x/actorx"
 )
 
 var (
@@ -30,7 +33,8 @@ var (
 	errInvalidIssuer   = errors.New("invalid issuer")
 )
 
-// jwtHandler validates the token claims and sets the ActorKey to the token subject.
+// jwtHandler validates the token claims, sets the ActorKey to the token subject and stores the
+// structured actor in the ActorDetailsKey and request context.
 func (a *Auth) jwtHandler(c echo.Context) error {
 	token, ok := c.Get("user").(*jwt.Token)
 	if !ok {
@@ -46,21 +50,55 @@ func (a *Auth) jwtHandler(c echo.Context) error {
 		return nil
 	}
 
-	if err := a.validateClaims(claims); err != nil {
+	issuer, err := a.validateClaims(claims)
+	if err != nil {
 		a.logger.Error("jwt user claims are not valid", zap.Error(err))
 
 		return err
 	}
 
-	if subject, ok := claims["sub"]; ok {
-		// store the actor in the request context as well so it's available outside of echo contexts
-		req := c.Request()
+	actorClaim := DefaultActorClaim
+
+	if issuer != nil {
+		actorClaim = issuer.ActorClaim
+	}
+
+	actorSubject, _ := claims[actorClaim].(string)
+
+	if err := a.checkDenylist(c.Request().Context(), claims, actorSubject); err != nil {
+		return err
+	}
+
+	// store the validated claims so authorization middleware and handlers can access them
+	req := c.Request()
+	req = req.WithContext(context.WithValue(req.Context(), ClaimsCtxKey, claims))
+	c.SetRequest(req)
+	c.Set(ClaimsKey, claims)
+
+	actor := actorx.FromClaims(claims, actorClaim)
+
+	if actor.Issuer == "" && issuer != nil {
+		actor.Issuer = issuer.Issuer
+	}
+
+	setActor(c, claims[actorClaim], actor)
+
+	return nil
+}
+
+// setActor stores the actor subject in the ActorKey and the structured actor in the ActorDetailsKey.
+// Both are stored in the request context as well so they're available outside of echo contexts.
+// The subject is only stored if it's not nil.
+func setActor(c echo.Context, subject any, actor *actorx.Actor) {
+	req := c.Request()
+
+	if subject != nil {
 		req = req.WithContext(context.WithValue(req.Context(), ActorCtxKey, subject))
-		c.SetRequest(req)
 		c.Set(ActorKey, subject)
 	}
 
-	return nil
+	c.SetRequest(req.WithContext(actorx.NewContext(req.Context(), actor)))
+	c.Set(ActorDetailsKey, actor)
 }
 
 // Actor retrieves the ActorKey from echo Context.
@@ -72,26 +110,65 @@ func Actor(c echo.Context) string {
 	return ""
 }
 
-func (a *Auth) validateClaims(claims jwt.MapClaims) error {
-	if a.audience != "" {
-		if audiences, err := claims.GetAudience(); err != nil {
-			a.logger.Error("jwt user failed to get audience", zap.Error(err), zap.Any("audience", claims["aud"]))
-		} else if !slices.Contains(audiences, a.audience) {
-			a.logger.Error("jwt user claim invalid audience", zap.Any("audience", claims["aud"]))
+// ActorDetails retrieves the structured actor from the echo Context, or an anonymous actor if the
+// request isn't authenticated.
+func ActorDetails(c echo.Context) *actorx.Actor {
+	if actor, ok := c.Get(ActorDetailsKey).(*actorx.Actor); ok && actor != nil {
+		return actor
+	}
 
-			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(errInvalidAudience)
-		}
+	return actorx.FromContextOrAnonymous(c.Request().Context())
+}
+
+// Claims returns the validated jwt claims stored in the echo context, or nil if there are none.
+func Claims(c echo.Context) jwt.MapClaims {
+	if claims, ok := c.Get(ClaimsKey).(jwt.MapClaims); ok {
+		return claims
 	}
 
-	if a.issuer != "" {
-		if issuer, err := claims.GetIssuer(); err != nil {
-			a.logger.Error("jwt user failed to get issuer", zap.Error(err), zap.Any("issuer", claims["iss"]))
-		} else if issuer != a.issuer {
-			a.logger.Error("jwt user claim invalid issuer", zap.Any("issuer", claims["iss"]))
+	return nil
+}
 
-			return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(errInvalidIssuer)
-		}
+// ClaimsFromContext returns the validated jwt claims stored in the context, or nil if there are none.
+func ClaimsFromContext(ctx context.Context) jwt.MapClaims {
+	if claims, ok := ctx.Value(ClaimsCtxKey).(jwt.MapClaims); ok {
+		return claims
 	}
 
 	return nil
 }
+
+// validateClaims validates the claims against the trusted issuer selected by the iss claim
+// and returns the issuer. If no issuers are configured, no issuer is returned.
+func (a *Auth) validateClaims(claims jwt.MapClaims) (*trustedIssuer, error) {
+	if err := a.validateRequiredClaims(claims); err != nil {
+		return nil, err
+	}
+
+	if len(a.issuers) == 0 {
+		return nil, nil
+	}
+
+	issuer, err := a.issuerForClaims(claims)
+	if err != nil {
+		a.logger.Error("jwt user claim invalid issuer", zap.Error(err), zap.Any("issuer", claims["iss"]))
+
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(fmt.Errorf("%w: %w", errInvalidIssuer, err))
+	}
+
+	if len(issuer.Audiences) != 0 || a.claimsConfig.Strict {
+		if audiences, err := claims.GetAudience(); err != nil {
+			a.logger.Error("jwt user failed to get audience", zap.Error(err), zap.Any("audience", claims["aud"]))
+
+			if a.claimsConfig.Strict {
+				return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(fmt.Errorf("%w: %w", errInvalidAudience, err))
+			}
+		} else if len(issuer.Audiences) != 0 && !slices.ContainsFunc(audiences, func(aud string) bool { return slices.Contains(issuer.Audiences, aud) }) {
+			a.logger.Error("jwt user claim invalid audience", zap.Any("audience", claims["aud"]))
+
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(errInvalidAudience)
+		}
+	}
+
+	return issuer, nil
+}
@@ -8,12 +8,14 @@ import (
 	"time"
 
 	"github.com/MicahParks/jwkset"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"go.infratographer.com/x/actorx"
 	"go.infratographer.com/x/echojwtx"
 )
 
@@ -198,3 +200,72 @@ func TestAudienceValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestActorDetails(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer:         issuer,
+		RefreshTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	gotActorCh := make(chan *actorx.Actor, 2)
+
+	e := echo.New()
+
+	e.Use(auth.Middleware())
+
+	e.GET("/test", func(c echo.Context) error {
+		gotActorCh <- echojwtx.ActorDetails(c)
+
+		actor, _ := actorx.FromContext(c.Request().Context())
+
+		gotActorCh <- actor
+
+		return nil
+	})
+
+	token := testHelperSignToken(TestPrivRSAKey1ID, josejwt.Claims{
+		Issuer:  issuer,
+		Subject: "idntusr-abc",
+	}, map[string]any{
+		"azp":   "cli",
+		"scope": "read write",
+		"act":   map[string]any{"sub": "idntusr-admin", "client_id": "support"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	expect := &actorx.Actor{
+		Subject:    "idntusr-abc",
+		RawSubject: "idntusr-abc",
+		Kind:       actorx.KindUser,
+		Issuer:     issuer,
+		ClientID:   "cli",
+		Scopes:     []string{"read", "write"},
+		Impersonator: &actorx.Actor{
+			Subject:    "idntusr-admin",
+			RawSubject: "idntusr-admin",
+			Kind:       actorx.KindUser,
+			ClientID:   "support",
+		},
+	}
+
+	for range 2 {
+		select {
+		case actor := <-gotActorCh:
+			assert.Equal(t, expect, actor)
+		case <-time.After(chanTimeout):
+			t.Error("failed to receive actor")
+		}
+	}
+}
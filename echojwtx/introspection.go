@@ -0,0 +1,332 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/oauth2x"
+)
+
+const (
+	// DefaultIntrospectionCacheTTL is the default maximum duration an introspection response is cached.
+	DefaultIntrospectionCacheTTL = 5 * time.Minute
+
+	// DefaultIntrospectionNegativeCacheTTL is the default duration inactive and failed introspection responses are cached.
+	DefaultIntrospectionNegativeCacheTTL = 10 * time.Second
+
+	// DefaultIntrospectionCacheSize is the default maximum number of cached introspection responses.
+	DefaultIntrospectionCacheSize = 10000
+)
+
+var (
+	introspectionClient = &http.Client{
+		Timeout:   5 * time.Second, // nolint:mnd // clear and unexported
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+
+	// ErrIntrospectionEndpointMissing is returned when the introspection_endpoint field is not found in the issuer's
+	// oidc well-known configuration.
+	ErrIntrospectionEndpointMissing = errors.New("introspection_endpoint missing from oidc provider")
+
+	// ErrIntrospectionIssuerMissing is returned when introspection is enabled without an issuer to discover the endpoint.
+	ErrIntrospectionIssuerMissing = errors.New("introspection issuer missing")
+
+	// ErrIntrospectionFailed is returned when the introspection endpoint returns an unexpected response.
+	ErrIntrospectionFailed = errors.New("token introspection failed")
+
+	// ErrTokenInactive is returned when the introspection endpoint reports the token is not active.
+	ErrTokenInactive = errors.New("token is not active")
+)
+
+// IntrospectionConfig provides configuration for validating opaque tokens with an RFC 7662
+// token introspection endpoint. Tokens which can't be parsed as a JWT are introspected.
+type IntrospectionConfig struct {
+	// Enabled enables token introspection.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Endpoint overrides the introspection_endpoint discovered from the issuer's openid-configuration.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Client is the client credentials used to authenticate with the introspection endpoint.
+	// If Client.Issuer is empty, the first configured issuer is used for discovery.
+	Client oauth2x.Config `mapstructure:"client"`
+
+	// CacheTTL is the maximum duration active introspection responses are cached.
+	// Responses are never cached past the token's expiry. Defaults to DefaultIntrospectionCacheTTL.
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+
+	// NegativeCacheTTL is the duration inactive and failed introspection responses are cached,
+	// limiting the requests made to the introspection endpoint for invalid tokens.
+	// Defaults to DefaultIntrospectionNegativeCacheTTL.
+	NegativeCacheTTL time.Duration `mapstructure:"negative_cache_ttl"`
+
+	// CacheSize is the maximum number of cached introspection responses. Defaults to DefaultIntrospectionCacheSize.
+	CacheSize int `mapstructure:"cache_size"`
+}
+
+type introspectionCacheEntry struct {
+	claims    jwt.MapClaims
+	err       error
+	expiresAt time.Time
+}
+
+// introspector validates opaque tokens with an introspection endpoint and caches the responses.
+type introspector struct {
+	endpoint string
+	issuer   string
+	client   oauth2x.Config
+	cacheTTL time.Duration
+	negTTL   time.Duration
+	maxSize  int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]introspectionCacheEntry
+}
+
+func (a *Auth) setupIntrospection(ctx context.Context, config AuthConfig) error {
+	cfg := config.Introspection
+
+	issuer := cfg.Client.Issuer
+
+	if issuer == "" {
+		if issuerConfigs := config.issuerConfigs(); len(issuerConfigs) != 0 {
+			issuer = issuerConfigs[0].Issuer
+		}
+	}
+
+	endpoint := cfg.Endpoint
+
+	if endpoint == "" {
+		if issuer == "" {
+			return ErrIntrospectionIssuerMissing
+		}
+
+		uri, err := discoverEndpoint(ctx, issuer, "introspection_endpoint", ErrIntrospectionEndpointMissing)
+		if err != nil {
+			return err
+		}
+
+		endpoint = uri.String()
+	}
+
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = DefaultIntrospectionCacheTTL
+	}
+
+	if cfg.NegativeCacheTTL == 0 {
+		cfg.NegativeCacheTTL = DefaultIntrospectionNegativeCacheTTL
+	}
+
+	if cfg.CacheSize == 0 {
+		cfg.CacheSize = DefaultIntrospectionCacheSize
+	}
+
+	a.introspector = &introspector{
+		endpoint: endpoint,
+		issuer:   issuer,
+		client:   cfg.Client,
+		cacheTTL: cfg.CacheTTL,
+		negTTL:   cfg.NegativeCacheTTL,
+		maxSize:  cfg.CacheSize,
+		now:      time.Now,
+		cache:    make(map[string]introspectionCacheEntry),
+	}
+
+	parseJWT := a.JWTConfig.ParseTokenFunc
+	if parseJWT == nil {
+		parseJWT = a.parseJWT
+	}
+
+	a.JWTConfig.ParseTokenFunc = func(c echo.Context, auth string) (interface{}, error) {
+		token, err := parseJWT(c, auth)
+		if err == nil || !errors.Is(err, jwt.ErrTokenMalformed) {
+			return token, err
+		}
+
+		// the token isn't a jwt, attempt to validate it as an opaque token
+		claims, err := a.introspector.introspect(c.Request().Context(), auth)
+		if err != nil {
+			a.logger.Debug("token introspection failed", zap.Error(err))
+
+			return nil, &echojwt.TokenError{Err: err}
+		}
+
+		return &jwt.Token{
+			Raw:    auth,
+			Claims: claims,
+			Valid:  true,
+		}, nil
+	}
+
+	return nil
+}
+
+// introspect returns the claims for an active token, using the cached response if available.
+// Inactive and failed responses are cached for the negative cache ttl.
+func (i *introspector) introspect(ctx context.Context, token string) (jwt.MapClaims, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	now := i.now()
+
+	i.mu.Lock()
+
+	if entry, ok := i.cache[key]; ok {
+		if now.Before(entry.expiresAt) {
+			i.mu.Unlock()
+
+			return entry.claims, entry.err
+		}
+
+		delete(i.cache, key)
+	}
+
+	i.mu.Unlock()
+
+	claims, expiresAt, err := i.validate(ctx, token, now)
+	if err != nil {
+		// don't cache failures caused by the request being canceled
+		if ctx.Err() == nil {
+			i.store(key, introspectionCacheEntry{err: err, expiresAt: expiresAt}, now)
+		}
+
+		return nil, err
+	}
+
+	i.store(key, introspectionCacheEntry{claims: claims, expiresAt: expiresAt}, now)
+
+	return claims, nil
+}
+
+// validate introspects the token and returns the claims of an active token along with the time
+// the response may be cached until.
+func (i *introspector) validate(ctx context.Context, token string, now time.Time) (jwt.MapClaims, time.Time, error) {
+	negExpiresAt := now.Add(i.negTTL)
+
+	claims, err := i.request(ctx, token)
+	if err != nil {
+		return nil, negExpiresAt, err
+	}
+
+	if nbf, err := claims.GetNotBefore(); err == nil && nbf != nil && now.Before(nbf.Time) {
+		// the token becomes valid at nbf, don't cache the rejection past it
+		if nbf.Before(negExpiresAt) {
+			negExpiresAt = nbf.Time
+		}
+
+		return nil, negExpiresAt, fmt.Errorf("%w: token is not valid yet", ErrTokenInactive)
+	}
+
+	expiresAt := now.Add(i.cacheTTL)
+
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		if !now.Before(exp.Time) {
+			return nil, negExpiresAt, fmt.Errorf("%w: token is expired", ErrTokenInactive)
+		}
+
+		if exp.Before(expiresAt) {
+			expiresAt = exp.Time
+		}
+	}
+
+	return claims, expiresAt, nil
+}
+
+func (i *introspector) store(key string, entry introspectionCacheEntry, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.cache) >= i.maxSize {
+		for k, e := range i.cache {
+			if !now.Before(e.expiresAt) {
+				delete(i.cache, k)
+			}
+		}
+
+		if len(i.cache) >= i.maxSize {
+			return
+		}
+	}
+
+	i.cache[key] = entry
+}
+
+func (i *introspector) request(ctx context.Context, token string) (jwt.MapClaims, error) {
+	form := url.Values{
+		"token":           []string{token},
+		"token_type_hint": []string{"access_token"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if i.client.ID != "" {
+		// client credentials are form encoded as defined by RFC 6749 section 2.3.1
+		req.SetBasicAuth(url.QueryEscape(i.client.ID), url.QueryEscape(i.client.Secret))
+	}
+
+	res, err := introspectionClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospectionFailed, err)
+	}
+	defer res.Body.Close() //nolint:errcheck // no need to check
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code %d", ErrIntrospectionFailed, res.StatusCode)
+	}
+
+	claims := jwt.MapClaims{}
+
+	if err := json.NewDecoder(res.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospectionFailed, err)
+	}
+
+	if active, _ := claims["active"].(bool); !active {
+		return nil, ErrTokenInactive
+	}
+
+	delete(claims, "active")
+
+	// opaque tokens are validated by the introspection issuer, ensure the claims reference it
+	// so they're validated against the same trusted issuer config as jwts.
+	if _, ok := claims["iss"]; !ok && i.issuer != "" {
+		claims["iss"] = i.issuer
+	}
+
+	return claims, nil
+}
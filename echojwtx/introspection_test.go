@@ -0,0 +1,185 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/echojwtx"
+	"go.infratographer.com/x/oauth2x"
+)
+
+// testHelperIntrospectionProvider returns an issuer with an introspection endpoint for the tokens.
+// The returned counter is incremented for every introspection request.
+func testHelperIntrospectionProvider(t *testing.T, tokens map[string]echo.Map) (string, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+
+	e := echo.New()
+	srv := httptest.NewServer(e)
+
+	t.Cleanup(srv.Close)
+
+	e.GET("/.well-known/openid-configuration", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, echo.Map{
+			"jwks_uri":               srv.URL + "/.well-known/jwks.json",
+			"introspection_endpoint": srv.URL + "/oauth/introspect",
+		})
+	})
+
+	e.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, testHelperJoseJWKSProvider(TestPrivRSAKey1ID))
+	})
+
+	e.POST("/oauth/introspect", func(c echo.Context) error {
+		requests.Add(1)
+
+		id, secret, ok := c.Request().BasicAuth()
+		if !ok || id != "introspector" || secret != "s3cret" {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+
+		if c.FormValue("token_type_hint") != "access_token" {
+			return c.NoContent(http.StatusBadRequest)
+		}
+
+		if resp, ok := tokens[c.FormValue("token")]; ok {
+			return c.JSON(http.StatusOK, resp)
+		}
+
+		return c.JSON(http.StatusOK, echo.Map{"active": false})
+	})
+
+	return srv.URL, &requests
+}
+
+func TestIntrospection(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+
+	issuer, requests := testHelperIntrospectionProvider(t, map[string]echo.Map{
+		"opaque-active":  {"active": true, "sub": "urn:test:user", "aud": "test-aud", "scope": "read", "exp": exp},
+		"opaque-expired": {"active": true, "sub": "urn:test:user", "aud": "test-aud", "exp": time.Now().Add(-time.Minute).Unix()},
+		"opaque-aud":     {"active": true, "sub": "urn:test:user", "aud": "other-aud", "exp": exp},
+		"opaque-nbf":     {"active": true, "sub": "urn:test:user", "aud": "test-aud", "exp": exp, "nbf": time.Now().Add(time.Minute).Unix()},
+	})
+
+	auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuers: []echojwtx.IssuerConfig{{Issuer: issuer, Audiences: []string{"test-aud"}}},
+		Introspection: echojwtx.IntrospectionConfig{
+			Enabled: true,
+			Client: oauth2x.Config{
+				ID:     "introspector",
+				Secret: "s3cret",
+			},
+		},
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	e := echo.New()
+
+	e.Use(auth.Middleware())
+
+	e.GET("/test", func(c echo.Context) error {
+		return c.String(http.StatusOK, echojwtx.Actor(c))
+	}, echojwtx.RequireScopes("read"))
+
+	e.GET("/any", func(c echo.Context) error {
+		return c.String(http.StatusOK, echojwtx.Actor(c))
+	})
+
+	do := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("active opaque token", func(t *testing.T) {
+		requests.Store(0)
+
+		for range 3 {
+			rec := do("/test", "opaque-active")
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "urn:test:user", rec.Body.String())
+		}
+
+		assert.Equal(t, int32(1), requests.Load(), "expected introspection response to be cached")
+	})
+
+	t.Run("inactive opaque token", func(t *testing.T) {
+		requests.Store(0)
+
+		for range 3 {
+			assert.Equal(t, http.StatusUnauthorized, do("/any", "opaque-unknown").Code)
+		}
+
+		assert.Equal(t, int32(1), requests.Load(), "expected inactive introspection response to be cached")
+	})
+
+	t.Run("not yet valid opaque token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do("/any", "opaque-nbf").Code)
+	})
+
+	t.Run("expired opaque token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do("/any", "opaque-expired").Code)
+	})
+
+	t.Run("opaque token audience", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do("/any", "opaque-aud").Code)
+	})
+
+	t.Run("jwt is not introspected", func(t *testing.T) {
+		requests.Store(0)
+
+		token := testHelperSignToken(TestPrivRSAKey1ID, jwt.Claims{
+			Issuer:   issuer,
+			Subject:  "urn:test:jwt",
+			Audience: jwt.Audience{"test-aud"},
+		}, nil)
+
+		rec := do("/any", token)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "urn:test:jwt", rec.Body.String())
+		assert.Equal(t, int32(0), requests.Load())
+	})
+}
+
+func TestIntrospectionEndpointMissing(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	_, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer:        issuer,
+		Introspection: echojwtx.IntrospectionConfig{Enabled: true},
+	})
+	assert.ErrorIs(t, err, echojwtx.ErrIntrospectionEndpointMissing)
+}
@@ -0,0 +1,233 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultActorClaim is the claim used for the actor when an issuer doesn't configure one.
+	DefaultActorClaim = "sub"
+)
+
+var (
+	// ErrUnknownIssuer is returned when a token's iss claim doesn't match any trusted issuer.
+	ErrUnknownIssuer = errors.New("unknown issuer")
+
+	// ErrIssuerMissing is returned when keys must be loaded but no issuers are configured.
+	ErrIssuerMissing = errors.New("no trusted issuers configured")
+
+	// ErrDuplicateIssuer is returned when the same issuer is configured more than once.
+	ErrDuplicateIssuer = errors.New("duplicate issuer")
+
+	// ErrIssuerRequired is returned when keys are configured without an issuer and AllowAnyIssuer isn't set.
+	ErrIssuerRequired = errors.New("issuer required")
+)
+
+// IssuerConfig provides the configuration for a trusted token issuer.
+type IssuerConfig struct {
+	// Issuer is the expected iss claim and the base url used for OIDC discovery.
+	Issuer string `mapstructure:"issuer"`
+
+	// Audiences are the accepted audiences for tokens from this issuer, a token must include one of them.
+	// If empty the audience is not validated.
+	Audiences []string `mapstructure:"audiences"`
+
+	// JWKSURI overrides the jwks_uri discovered from the issuer's openid-configuration.
+	JWKSURI string `mapstructure:"jwks_uri"`
+
+	// ActorClaim is the claim containing the actor for tokens from this issuer, defaults to DefaultActorClaim.
+	ActorClaim string `mapstructure:"actor_claim"`
+
+	// JWKS configures local key sources used instead of, or as a fallback for, OIDC discovery.
+	JWKS JWKSConfig `mapstructure:"jwks"`
+
+	// AllowAnyIssuer trusts tokens from any issuer which isn't explicitly configured when Issuer is empty,
+	// such as for static JWKS sources shared by several issuers. Keys without an Issuer are rejected otherwise.
+	AllowAnyIssuer bool `mapstructure:"allow_any_issuer"`
+}
+
+// trustedIssuer is an issuer configured with its own key storage.
+type trustedIssuer struct {
+	IssuerConfig
+
+	keyfunc jwt.Keyfunc
+}
+
+// issuerConfigs returns the configured issuers, including the legacy single Issuer, Audience and JWKS.
+func (c AuthConfig) issuerConfigs() []IssuerConfig {
+	issuers := append([]IssuerConfig{}, c.Issuers...)
+
+	if c.Issuer != "" || c.Audience != "" || c.JWKS.static() {
+		legacy := IssuerConfig{
+			Issuer:         c.Issuer,
+			JWKS:           c.JWKS,
+			AllowAnyIssuer: c.AllowAnyIssuer,
+		}
+
+		if c.Audience != "" {
+			legacy.Audiences = []string{c.Audience}
+		}
+
+		issuers = append([]IssuerConfig{legacy}, issuers...)
+	}
+
+	return issuers
+}
+
+// setupIssuers configures the trusted issuers. When loadKeys is true a JWKS keyfunc is created for each issuer.
+func (a *Auth) setupIssuers(ctx context.Context, config AuthConfig, loadKeys bool) error {
+	a.issuers = make(map[string]*trustedIssuer)
+
+	issuerConfigs := config.issuerConfigs()
+
+	if loadKeys && len(issuerConfigs) == 0 {
+		return ErrIssuerMissing
+	}
+
+	for _, issuerConfig := range issuerConfigs {
+		if _, ok := a.issuers[issuerConfig.Issuer]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateIssuer, issuerConfig.Issuer)
+		}
+
+		if loadKeys && issuerConfig.Issuer == "" && !issuerConfig.AllowAnyIssuer {
+			return fmt.Errorf("%w: set allow any issuer to trust keys for tokens from any issuer", ErrIssuerRequired)
+		}
+
+		if issuerConfig.ActorClaim == "" {
+			issuerConfig.ActorClaim = DefaultActorClaim
+		}
+
+		issuer := &trustedIssuer{
+			IssuerConfig: issuerConfig,
+		}
+
+		if loadKeys {
+			kf, err := a.issuerKeyfunc(ctx, config, issuerConfig)
+			if err != nil {
+				return fmt.Errorf("issuer %s: %w", issuerConfig.Issuer, err)
+			}
+
+			issuer.keyfunc = kf
+		}
+
+		a.issuers[issuerConfig.Issuer] = issuer
+	}
+
+	return nil
+}
+
+// issuerKeyfunc returns the keyfunc for the issuer's key source. Static JWKS sources are used instead of
+// OIDC discovery when configured.
+func (a *Auth) issuerKeyfunc(ctx context.Context, config AuthConfig, issuerConfig IssuerConfig) (jwt.Keyfunc, error) {
+	if issuerConfig.JWKS.static() {
+		return a.staticKeyfunc(ctx, issuerConfig.JWKS)
+	}
+
+	if issuerConfig.JWKS.CacheFile != "" {
+		return a.cachedKeyfunc(ctx, config, issuerConfig)
+	}
+
+	jwksURL, err := issuerJWKSURI(ctx, issuerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := jwkset.NewStorageFromHTTP(jwksURL, a.HTTPClientStorageOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return clientKeyfunc(ctx, config, storage)
+}
+
+// clientKeyfunc returns a keyfunc reading keys from the storage through a jwkset HTTP client.
+func clientKeyfunc(ctx context.Context, config AuthConfig, storage jwkset.Storage) (jwt.Keyfunc, error) {
+	clientOptions := jwkset.HTTPClientOptions{
+		Given:            storage,
+		RateLimitWaitMax: config.RateLimitWaitMax,
+	}
+
+	clientStorage, err := jwkset.NewHTTPClient(clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	keyfuncOptions := keyfunc.Options{
+		Ctx:     ctx,
+		Storage: clientStorage,
+	}
+
+	jwks, err := keyfunc.New(keyfuncOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwks.Keyfunc, nil
+}
+
+// issuerJWKSURI returns the configured JWKSURI or discovers it from the issuer.
+func issuerJWKSURI(ctx context.Context, issuerConfig IssuerConfig) (string, error) {
+	if issuerConfig.JWKSURI != "" {
+		return issuerConfig.JWKSURI, nil
+	}
+
+	uri, err := jwksURI(ctx, issuerConfig.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	return uri.String(), nil
+}
+
+// issuerForClaims returns the trusted issuer for the iss claim. An issuer configured without
+// an Issuer value and with AllowAnyIssuer trusts tokens from any issuer which isn't explicitly
+// configured. Tokens with an iss claim which can't be parsed are always rejected.
+func (a *Auth) issuerForClaims(claims jwt.Claims) (*trustedIssuer, error) {
+	iss, err := claims.GetIssuer()
+	if err != nil {
+		a.logger.Error("jwt user failed to get issuer", zap.Error(err))
+
+		return nil, err
+	}
+
+	if issuer, ok := a.issuers[iss]; ok {
+		return issuer, nil
+	}
+
+	if issuer, ok := a.issuers[""]; ok {
+		return issuer, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownIssuer, iss)
+}
+
+// keyfunc selects the issuer's keys using the token's unverified iss claim before the signature is verified.
+func (a *Auth) keyfunc(token *jwt.Token) (any, error) {
+	issuer, err := a.issuerForClaims(token.Claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return issuer.keyfunc(token)
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/echojwtx"
+)
+
+// testHelperSignToken returns a token signed by the key with the extra claims.
+func testHelperSignToken(keyID string, cl jwt.Claims, extra map[string]any) string {
+	rawKey, _ := keyMap.Load(keyID)
+
+	signer := testHelperMustMakeSigner(jose.RS256, keyID, rawKey)
+
+	raw, err := jwt.Signed(signer).Claims(cl).Claims(extra).CompactSerialize()
+	if err != nil {
+		panic(err)
+	}
+
+	return raw
+}
+
+func TestMultipleIssuers(t *testing.T) {
+	userIssuer, userCloser := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer userCloser()
+
+	serviceIssuer, serviceCloser := testHelperOIDCProvider(TestPrivRSAKey2ID)
+	defer serviceCloser()
+
+	auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		RefreshTimeout: 5 * time.Second,
+		Issuers: []echojwtx.IssuerConfig{
+			{
+				Issuer:    userIssuer,
+				Audiences: []string{"user-aud", "other-aud"},
+			},
+			{
+				Issuer:     serviceIssuer,
+				Audiences:  []string{"service-aud"},
+				ActorClaim: "client_id",
+			},
+		},
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	e := echo.New()
+
+	e.Use(auth.Middleware())
+
+	e.GET("/test", func(c echo.Context) error {
+		return c.String(http.StatusOK, echojwtx.Actor(c))
+	})
+
+	now := time.Now()
+
+	testCases := []struct {
+		name        string
+		keyID       string
+		claims      jwt.Claims
+		extra       map[string]any
+		expectCode  int
+		expectActor string
+	}{
+		{
+			name:        "user issuer",
+			keyID:       TestPrivRSAKey1ID,
+			claims:      jwt.Claims{Issuer: userIssuer, Subject: "urn:test:user", Audience: jwt.Audience{"other-aud"}, IssuedAt: jwt.NewNumericDate(now)},
+			expectCode:  http.StatusOK,
+			expectActor: "urn:test:user",
+		},
+		{
+			name:        "service issuer with actor claim",
+			keyID:       TestPrivRSAKey2ID,
+			claims:      jwt.Claims{Issuer: serviceIssuer, Subject: "urn:test:service", Audience: jwt.Audience{"service-aud"}, IssuedAt: jwt.NewNumericDate(now)},
+			extra:       map[string]any{"client_id": "svc-client"},
+			expectCode:  http.StatusOK,
+			expectActor: "svc-client",
+		},
+		{
+			name:       "audience of another issuer",
+			keyID:      TestPrivRSAKey2ID,
+			claims:     jwt.Claims{Issuer: serviceIssuer, Subject: "urn:test:service", Audience: jwt.Audience{"user-aud"}, IssuedAt: jwt.NewNumericDate(now)},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "signed by another issuer's key",
+			keyID:      TestPrivRSAKey1ID,
+			claims:     jwt.Claims{Issuer: serviceIssuer, Subject: "urn:test:service", Audience: jwt.Audience{"service-aud"}, IssuedAt: jwt.NewNumericDate(now)},
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown issuer",
+			keyID:      TestPrivRSAKey1ID,
+			claims:     jwt.Claims{Issuer: "https://unknown.example.com", Subject: "urn:test:user", Audience: jwt.Audience{"user-aud"}, IssuedAt: jwt.NewNumericDate(now)},
+			expectCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, "/test", nil)
+			require.NoError(t, err, "expected new request without error")
+
+			req.Header.Set("Authorization", "Bearer "+testHelperSignToken(tc.keyID, tc.claims, tc.extra))
+
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+
+			if tc.expectCode == http.StatusOK {
+				assert.Equal(t, tc.expectActor, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestIssuerConfigErrors(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	_, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer:  issuer,
+		Issuers: []echojwtx.IssuerConfig{{Issuer: issuer}},
+	})
+	assert.ErrorIs(t, err, echojwtx.ErrDuplicateIssuer)
+
+	_, err = echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{})
+	assert.ErrorIs(t, err, echojwtx.ErrIssuerMissing)
+}
@@ -0,0 +1,432 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// PEMKeyIDHeader is the PEM header used to set the key id of a PEM encoded key.
+	PEMKeyIDHeader = "kid"
+)
+
+var (
+	// ErrInvalidPEMKey is returned when a configured PEM key can't be parsed.
+	ErrInvalidPEMKey = errors.New("invalid pem key")
+)
+
+// JWKSConfig configures alternative sources for an issuer's signing keys.
+type JWKSConfig struct {
+	// File is a local JWKS file used instead of OIDC discovery. The file is reloaded when it changes.
+	File string `mapstructure:"file"`
+
+	// PEMKeys are PEM encoded public keys or certificates used instead of OIDC discovery. The key id
+	// is set with the PEMKeyIDHeader PEM header. Tokens with an unknown key id are verified with the
+	// keys which don't have a key id.
+	PEMKeys []string `mapstructure:"pem_keys"`
+
+	// CacheFile is where the last successfully fetched JWKS is stored. If OIDC discovery or fetching
+	// the JWKS fails, the cached keys are used until the JWKS can be fetched.
+	CacheFile string `mapstructure:"cache_file"`
+}
+
+// static returns true if keys are loaded from local sources instead of OIDC discovery.
+func (c JWKSConfig) static() bool {
+	return c.File != "" || len(c.PEMKeys) != 0
+}
+
+// staticKeys loads keys from the configured JWKS file and PEM keys.
+type staticKeys struct {
+	logger  *zap.Logger
+	file    string
+	pemKeys []jwkset.JWK
+	storage *jwkset.MemoryJWKSet
+}
+
+// staticKeyfunc returns a keyfunc for the JWKS file and PEM keys. The JWKS file is watched for
+// changes until the context is canceled.
+func (a *Auth) staticKeyfunc(ctx context.Context, config JWKSConfig) (jwt.Keyfunc, error) {
+	pemKeys, err := parsePEMKeys(config.PEMKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &staticKeys{
+		logger:  a.logger,
+		file:    config.File,
+		pemKeys: pemKeys,
+		storage: jwkset.NewMemoryStorage(),
+	}
+
+	if err := keys.load(ctx); err != nil {
+		return nil, err
+	}
+
+	if keys.file != "" {
+		if err := keys.watch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	kf, err := keyfunc.New(keyfunc.Options{
+		Ctx:     ctx,
+		Storage: keys.storage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keyfuncWithUnnamedKeys(kf), nil
+}
+
+// load replaces the stored keys with the PEM keys and the keys in the JWKS file.
+func (s *staticKeys) load(ctx context.Context) error {
+	keys := append([]jwkset.JWK{}, s.pemKeys...)
+
+	if s.file != "" {
+		fileKeys, err := readJWKSFile(s.file)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, fileKeys...)
+	}
+
+	return s.storage.KeyReplaceAll(ctx, keys)
+}
+
+// watch reloads the keys when the JWKS file changes. The file's directory is watched so files
+// which are replaced, such as mounted kubernetes config maps, are reloaded as well.
+func (s *staticKeys) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Clean(s.file)
+
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+
+		return fmt.Errorf("failed watching jwks file: %w", err)
+	}
+
+	realFile, _ := filepath.EvalSymlinks(file)
+
+	go func() {
+		defer watcher.Close() //nolint:errcheck // no need to check
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				currentFile, _ := filepath.EvalSymlinks(file)
+
+				// ignore events for other files unless the file's symlink target changed
+				if filepath.Clean(event.Name) != file && currentFile == realFile {
+					continue
+				}
+
+				realFile = currentFile
+
+				// keep the previous keys while the file is removed
+				if currentFile == "" {
+					continue
+				}
+
+				if err := s.load(ctx); err != nil {
+					s.logger.Error("failed to reload jwks file, continuing to use previous keys", zap.String("file", file), zap.Error(err))
+
+					continue
+				}
+
+				s.logger.Info("reloaded jwks file", zap.String("file", file))
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				s.logger.Error("error watching jwks file", zap.String("file", file), zap.Error(err))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// keyfuncWithUnnamedKeys verifies tokens with an unknown key id using the keys without a key id.
+func keyfuncWithUnnamedKeys(kf keyfunc.Keyfunc) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		key, err := kf.Keyfunc(token)
+		if err == nil || !errors.Is(err, jwkset.ErrKeyNotFound) {
+			return key, err
+		}
+
+		jwks, readErr := kf.Storage().KeyReadAll(context.Background())
+		if readErr != nil {
+			return nil, errors.Join(err, readErr)
+		}
+
+		var keys jwt.VerificationKeySet
+
+		for _, jwk := range jwks {
+			if jwk.Marshal().KID == "" {
+				keys.Keys = append(keys.Keys, jwk.Key())
+			}
+		}
+
+		if len(keys.Keys) == 0 {
+			return nil, err
+		}
+
+		return keys, nil
+	}
+}
+
+// parsePEMKeys parses the PEM encoded public keys, private keys or certificates into public JWKs.
+func parsePEMKeys(pemKeys []string) ([]jwkset.JWK, error) {
+	var keys []jwkset.JWK
+
+	for i, raw := range pemKeys {
+		rest := []byte(raw)
+
+		for {
+			var block *pem.Block
+
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+
+			key, err := pemPublicKey(block)
+			if err != nil {
+				return nil, fmt.Errorf("%w: key %d: %w", ErrInvalidPEMKey, i, err)
+			}
+
+			jwk, err := jwkset.NewJWKFromKey(key, jwkset.JWKOptions{
+				Metadata: jwkset.JWKMetadataOptions{
+					KID: block.Headers[PEMKeyIDHeader],
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%w: key %d: %w", ErrInvalidPEMKey, i, err)
+			}
+
+			keys = append(keys, jwk)
+		}
+	}
+
+	if len(keys) == 0 && len(pemKeys) != 0 {
+		return nil, fmt.Errorf("%w: no pem blocks found", ErrInvalidPEMKey)
+	}
+
+	return keys, nil
+}
+
+func pemPublicKey(block *pem.Block) (any, error) {
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return cert.PublicKey, nil
+	}
+
+	key, err := jwkset.LoadX509KeyInfer(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if signer, ok := key.(crypto.Signer); ok {
+		return signer.Public(), nil
+	}
+
+	return key, nil
+}
+
+// readJWKSFile reads the keys from a JWKS file.
+func readJWKSFile(path string) ([]jwkset.JWK, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks jwkset.JWKSMarshal
+
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks file %s: %w", path, err)
+	}
+
+	keys, err := jwks.JWKSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwks file %s: %w", path, err)
+	}
+
+	return keys, nil
+}
+
+// jwksFileCache is key storage which writes the keys to a file whenever they're replaced by a refresh.
+type jwksFileCache struct {
+	*jwkset.MemoryJWKSet
+
+	logger *zap.Logger
+	path   string
+}
+
+// KeyReplaceAll replaces the stored keys and writes them to the cache file.
+func (c *jwksFileCache) KeyReplaceAll(ctx context.Context, given []jwkset.JWK) error {
+	if err := c.MemoryJWKSet.KeyReplaceAll(ctx, given); err != nil {
+		return err
+	}
+
+	if err := c.save(ctx); err != nil {
+		c.logger.Warn("failed to write jwks cache file", zap.String("file", c.path), zap.Error(err))
+	}
+
+	return nil
+}
+
+// load reads the keys from the cache file without rewriting it.
+func (c *jwksFileCache) load(ctx context.Context) error {
+	keys, err := readJWKSFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	return c.MemoryJWKSet.KeyReplaceAll(ctx, keys)
+}
+
+// save atomically writes the public keys to the cache file.
+func (c *jwksFileCache) save(ctx context.Context) error {
+	data, err := c.JSONPublic(ctx)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "."+filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name()) //nolint:errcheck // removed after rename
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// cachedKeyfunc returns a keyfunc for the issuer's discovered JWKS which is stored in the cache
+// file. If the JWKS can't be fetched, the cached keys are used and discovery is retried every
+// refresh interval. Keys are read through a jwkset HTTP client, the same as discovered keys.
+func (a *Auth) cachedKeyfunc(ctx context.Context, config AuthConfig, issuerConfig IssuerConfig) (jwt.Keyfunc, error) {
+	cache := &jwksFileCache{
+		MemoryJWKSet: jwkset.NewMemoryStorage(),
+		logger:       a.logger,
+		path:         issuerConfig.JWKS.CacheFile,
+	}
+
+	cacheErr := cache.load(ctx)
+	if cacheErr != nil && !errors.Is(cacheErr, fs.ErrNotExist) {
+		a.logger.Warn("failed to load jwks cache file", zap.String("file", cache.path), zap.Error(cacheErr))
+	}
+
+	options := a.HTTPClientStorageOptions
+	options.Storage = cache
+
+	// once cached keys are available, a failed fetch is reported to the refresh error handler and retried.
+	options.NoErrorReturnFirstHTTPReq = cacheErr == nil
+
+	if err := a.fetchJWKS(ctx, issuerConfig, options); err != nil {
+		if cacheErr != nil {
+			return nil, err
+		}
+
+		a.logger.Warn("failed to discover jwks, using cached keys", zap.String("issuer", issuerConfig.Issuer), zap.String("file", cache.path), zap.Error(err))
+
+		go a.retryFetchJWKS(ctx, issuerConfig, options)
+	}
+
+	return clientKeyfunc(ctx, config, cache)
+}
+
+// fetchJWKS discovers the issuer's JWKS and fetches it into the options storage.
+func (a *Auth) fetchJWKS(ctx context.Context, issuerConfig IssuerConfig, options jwkset.HTTPClientStorageOptions) error {
+	jwksURL, err := issuerJWKSURI(ctx, issuerConfig)
+	if err != nil {
+		return err
+	}
+
+	_, err = jwkset.NewStorageFromHTTP(jwksURL, options)
+
+	return err
+}
+
+func (a *Auth) retryFetchJWKS(ctx context.Context, issuerConfig IssuerConfig, options jwkset.HTTPClientStorageOptions) {
+	interval := options.RefreshInterval
+	if interval == 0 {
+		interval = DefaultHTTPClientStorageOptionRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.fetchJWKS(ctx, issuerConfig, options); err != nil {
+				a.logger.Error("failed to discover jwks", zap.String("issuer", issuerConfig.Issuer), zap.Error(err))
+
+				continue
+			}
+
+			a.logger.Info("discovered jwks", zap.String("issuer", issuerConfig.Issuer))
+
+			return
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/echojwtx"
+)
+
+// testHelperKeyStatus returns a function which returns the response code for a token signed by the key.
+func testHelperKeyStatus(t *testing.T, auth *echojwtx.Auth, issuer string) func(keyID string) int {
+	t.Helper()
+
+	e := echo.New()
+
+	e.Use(auth.Middleware())
+
+	e.GET("/test", func(c echo.Context) error {
+		return c.String(http.StatusOK, echojwtx.Actor(c))
+	})
+
+	return func(keyID string) int {
+		token := testHelperSignToken(keyID, jwt.Claims{
+			Issuer:   issuer,
+			Subject:  "urn:test:user",
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+}
+
+func testHelperWriteJWKSFile(t *testing.T, path string, keyIDs ...string) {
+	t.Helper()
+
+	data, err := json.Marshal(testHelperJoseJWKSProvider(keyIDs...))
+	require.NoError(t, err)
+
+	// write and rename so the watcher never reads a partially written file
+	require.NoError(t, os.WriteFile(path+".tmp", data, 0o600))
+	require.NoError(t, os.Rename(path+".tmp", path))
+}
+
+func testHelperPEMKey(t *testing.T, key *rsa.PrivateKey, headers map[string]string) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Headers: headers, Bytes: der}))
+}
+
+func TestJWKSFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	file := filepath.Join(t.TempDir(), "jwks.json")
+
+	testHelperWriteJWKSFile(t, file, TestPrivRSAKey1ID)
+
+	_, err := echojwtx.NewAuth(ctx, echojwtx.AuthConfig{
+		JWKS: echojwtx.JWKSConfig{File: file},
+	})
+	require.ErrorIs(t, err, echojwtx.ErrIssuerRequired, "expected keys without an issuer to require allow any issuer")
+
+	auth, err := echojwtx.NewAuth(ctx, echojwtx.AuthConfig{
+		JWKS:           echojwtx.JWKSConfig{File: file},
+		AllowAnyIssuer: true,
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	status := testHelperKeyStatus(t, auth, "https://offline.example.com")
+
+	assert.Equal(t, http.StatusOK, status(TestPrivRSAKey1ID))
+	assert.Equal(t, http.StatusUnauthorized, status(TestPrivRSAKey2ID))
+
+	testHelperWriteJWKSFile(t, file, TestPrivRSAKey2ID)
+
+	assert.Eventually(t, func() bool {
+		return status(TestPrivRSAKey2ID) == http.StatusOK && status(TestPrivRSAKey1ID) == http.StatusUnauthorized
+	}, 2*time.Second, 10*time.Millisecond, "expected jwks file to be reloaded")
+
+	require.NoError(t, os.WriteFile(file, []byte("invalid"), 0o600))
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, status(TestPrivRSAKey2ID), "expected previous keys after invalid file")
+
+	_, err = echojwtx.NewAuth(ctx, echojwtx.AuthConfig{
+		JWKS:           echojwtx.JWKSConfig{File: filepath.Join(t.TempDir(), "missing.json")},
+		AllowAnyIssuer: true,
+	})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestJWKSPEMKeys(t *testing.T) {
+	issuer := "https://offline.example.com"
+
+	auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer: issuer,
+		JWKS: echojwtx.JWKSConfig{
+			PEMKeys: []string{testHelperPEMKey(t, TestPrivRSAKey2, nil)},
+		},
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	status := testHelperKeyStatus(t, auth, issuer)
+
+	assert.Equal(t, http.StatusOK, status(TestPrivRSAKey2ID), "expected key without kid to verify unknown kid")
+	assert.Equal(t, http.StatusUnauthorized, status(TestPrivRSAKey1ID))
+
+	auth, err = echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuers: []echojwtx.IssuerConfig{{
+			Issuer: issuer,
+			JWKS: echojwtx.JWKSConfig{
+				PEMKeys: []string{testHelperPEMKey(t, TestPrivRSAKey1, map[string]string{echojwtx.PEMKeyIDHeader: TestPrivRSAKey1ID})},
+			},
+		}},
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	status = testHelperKeyStatus(t, auth, issuer)
+
+	assert.Equal(t, http.StatusOK, status(TestPrivRSAKey1ID))
+	assert.Equal(t, http.StatusUnauthorized, status(TestPrivRSAKey2ID))
+
+	_, err = echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer: issuer,
+		JWKS:   echojwtx.JWKSConfig{PEMKeys: []string{"not a pem key"}},
+	})
+	assert.ErrorIs(t, err, echojwtx.ErrInvalidPEMKey)
+}
+
+func TestJWKSCacheFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cacheFile := filepath.Join(t.TempDir(), "jwks-cache.json")
+
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+
+	auth, err := echojwtx.NewAuth(ctx, echojwtx.AuthConfig{
+		Issuer: issuer,
+		JWKS:   echojwtx.JWKSConfig{CacheFile: cacheFile},
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	assert.Equal(t, http.StatusOK, testHelperKeyStatus(t, auth, issuer)(TestPrivRSAKey1ID))
+	assert.FileExists(t, cacheFile)
+
+	closer()
+
+	_, err = echojwtx.NewAuth(ctx, echojwtx.AuthConfig{Issuer: issuer})
+	require.Error(t, err, "expected discovery to fail without a cache file")
+
+	auth, err = echojwtx.NewAuth(ctx, echojwtx.AuthConfig{
+		Issuer: issuer,
+		JWKS:   echojwtx.JWKSConfig{CacheFile: cacheFile},
+	})
+	require.NoError(t, err, "expected cached keys to be used when discovery fails")
+
+	status := testHelperKeyStatus(t, auth, issuer)
+
+	assert.Equal(t, http.StatusOK, status(TestPrivRSAKey1ID))
+	assert.Equal(t, http.StatusUnauthorized, status(TestPrivRSAKey2ID))
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+
+	"go.infratographer.com/x/actorx"
+)
+
+const (
+	// SPIFFEScheme is the uri scheme of SPIFFE IDs.
+	SPIFFEScheme = "spiffe"
+)
+
+var (
+	// ErrClientCertMissing is returned when a request doesn't include a verified client certificate.
+	ErrClientCertMissing = errors.New("missing verified client certificate")
+
+	// ErrClientCertNoIdentity is returned when an identity can't be found in a client certificate.
+	ErrClientCertNoIdentity = errors.New("client certificate has no identity")
+
+	// ErrClientCertNotAllowed is returned when a client certificate's identity isn't allowed.
+	ErrClientCertNotAllowed = errors.New("client certificate identity not allowed")
+)
+
+// ClientCertIdentityFunc returns the actor subject and issuer for a verified client certificate.
+type ClientCertIdentityFunc func(cert *x509.Certificate) (subject string, issuer string, err error)
+
+// ClientCertConfig provides configuration for client certificate authentication.
+type ClientCertConfig struct {
+	// TrustDomains are the SPIFFE trust domains client certificates are accepted from. If set, client
+	// certificates without a SPIFFE ID are rejected. If empty, SPIFFE IDs from any trust domain are accepted.
+	TrustDomains []string `mapstructure:"trust_domains"`
+
+	// AllowedSubjects are the actor subjects which are accepted.
+	AllowedSubjects []string `mapstructure:"allowed_subjects"`
+
+	// AllowAnySubject accepts every client certificate verified by the server's tls.Config, along with
+	// the TrustDomains restriction, instead of requiring the subject be in AllowedSubjects.
+	// WARNING: this trusts every certificate signed by the server's client CAs. If AllowedSubjects is empty
+	// and AllowAnySubject is false, every client certificate is rejected.
+	AllowAnySubject bool `mapstructure:"allow_any_subject"`
+}
+
+// ClientCertOpts defines options for the ClientCertAuth middleware.
+type ClientCertOpts func(*ClientCertAuth)
+
+// WithClientCertLogger sets the logger for the client certificate middleware.
+func WithClientCertLogger(logger *zap.Logger) ClientCertOpts {
+	return func(a *ClientCertAuth) {
+		a.logger = logger
+	}
+}
+
+// WithClientCertIdentityFunc sets the function used to find the identity of a client certificate,
+// replacing DefaultClientCertIdentity.
+func WithClientCertIdentityFunc(fn ClientCertIdentityFunc) ClientCertOpts {
+	return func(a *ClientCertAuth) {
+		a.identityFunc = fn
+	}
+}
+
+// ClientCertAuth handles mutual TLS client certificate authentication as echo middleware.
+// The server's tls.Config must verify client certificates, using tls.VerifyClientCertIfGiven
+// allows the same server to accept requests authenticated with a JWT instead.
+type ClientCertAuth struct {
+	logger *zap.Logger
+
+	identityFunc ClientCertIdentityFunc
+
+	trustDomains    []string
+	allowedSubjects []string
+	allowAnySubject bool
+}
+
+// NewClientCertAuth creates a new client certificate auth middleware handler.
+func NewClientCertAuth(config ClientCertConfig, options ...ClientCertOpts) *ClientCertAuth {
+	auth := &ClientCertAuth{
+		allowedSubjects: config.AllowedSubjects,
+		allowAnySubject: config.AllowAnySubject,
+	}
+
+	for _, domain := range config.TrustDomains {
+		auth.trustDomains = append(auth.trustDomains, strings.TrimPrefix(domain, SPIFFEScheme+"://"))
+	}
+
+	for _, opt := range options {
+		opt(auth)
+	}
+
+	if auth.logger == nil {
+		auth.logger = zap.NewNop()
+	}
+
+	if auth.identityFunc == nil {
+		auth.identityFunc = DefaultClientCertIdentity
+	}
+
+	if len(auth.allowedSubjects) == 0 && !auth.allowAnySubject {
+		auth.logger.Warn("client certificate auth has no allowed subjects and allow any subject is disabled, every client certificate will be rejected")
+	}
+
+	return auth
+}
+
+// Middleware returns echo middleware which authenticates requests with a verified client certificate.
+// The certificate's identity is stored in the same ActorKey and ActorDetailsKey as the JWT middleware.
+func (a *ClientCertAuth) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			actor, err := a.authenticate(c.Request())
+			if err != nil {
+				a.logger.Debug("client certificate authentication failed", zap.Error(err))
+
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or missing client certificate").SetInternal(err)
+			}
+
+			setActor(c, actor.String(), actor)
+
+			return next(c)
+		}
+	}
+}
+
+func (a *ClientCertAuth) authenticate(req *http.Request) (*actorx.Actor, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrClientCertMissing
+	}
+
+	cert := req.TLS.VerifiedChains[0][0]
+
+	subject, issuer, err := a.identityFunc(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	if subject == "" {
+		return nil, ErrClientCertNoIdentity
+	}
+
+	if len(a.trustDomains) != 0 {
+		id := spiffeID(cert)
+		if id == "" {
+			return nil, fmt.Errorf("%w: no SPIFFE ID for trust domains", ErrClientCertNotAllowed)
+		}
+
+		domain := strings.TrimPrefix(id, SPIFFEScheme+"://")
+		domain, _, _ = strings.Cut(domain, "/")
+
+		if !slices.Contains(a.trustDomains, domain) {
+			return nil, fmt.Errorf("%w: trust domain %s", ErrClientCertNotAllowed, domain)
+		}
+	}
+
+	if !a.allowAnySubject && !slices.Contains(a.allowedSubjects, subject) {
+		return nil, fmt.Errorf("%w: %s", ErrClientCertNotAllowed, subject)
+	}
+
+	actor := actorx.New(subject, actorx.KindService)
+	actor.Issuer = issuer
+
+	return actor, nil
+}
+
+// DefaultClientCertIdentity returns the certificate's SPIFFE ID, common name or first DNS name as the
+// subject, in that order. The issuer is the SPIFFE trust domain or the certificate issuer's distinguished name.
+func DefaultClientCertIdentity(cert *x509.Certificate) (string, string, error) {
+	if id := spiffeID(cert); id != "" {
+		domain, _, _ := strings.Cut(strings.TrimPrefix(id, SPIFFEScheme+"://"), "/")
+
+		return id, SPIFFEScheme + "://" + domain, nil
+	}
+
+	issuer := cert.Issuer.String()
+
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName, issuer, nil
+	}
+
+	if len(cert.DNSNames) != 0 {
+		return cert.DNSNames[0], issuer, nil
+	}
+
+	return "", "", ErrClientCertNoIdentity
+}
+
+// spiffeID returns the first SPIFFE ID uri SAN of the certificate.
+func spiffeID(cert *x509.Certificate) string {
+	for _, uri := range cert.URIs {
+		if uri.Scheme == SPIFFEScheme && uri.Host != "" {
+			return uri.String()
+		}
+	}
+
+	return ""
+}
+
+// Either returns middleware which authenticates requests with the first of the middlewares to succeed,
+// such as client certificate or JWT authentication. If every middleware fails, the first error is returned.
+// If a failing middleware has already written a response, its error is returned without trying the rest.
+func Either(middlewares ...echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var firstErr error
+
+			for _, mdw := range middlewares {
+				called := false
+
+				err := mdw(func(c echo.Context) error {
+					called = true
+
+					return next(c)
+				})(c)
+
+				// the middleware succeeded or responded itself, return its result
+				if called || err == nil || c.Response().Committed {
+					return err
+				}
+
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+
+			return firstErr
+		}
+	}
+}
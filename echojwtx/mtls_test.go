@@ -0,0 +1,290 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echojwtx_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/actorx"
+	"go.infratographer.com/x/echojwtx"
+)
+
+// testHelperClientCert returns a self-signed client certificate with the common name and uri SANs.
+func testHelperClientCert(t *testing.T, commonName string, uris ...string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		require.NoError(t, err)
+
+		template.URIs = append(template.URIs, u)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// testHelperClientCertRequest runs the request through the middleware and returns the response and actor.
+// The certificate is only included in the verified chains if verified is true.
+func testHelperClientCertRequest(mdw echo.MiddlewareFunc, cert *x509.Certificate, verified bool, token string) (*httptest.ResponseRecorder, *actorx.Actor) {
+	e := echo.New()
+
+	var actor *actorx.Actor
+
+	e.GET("/test", func(c echo.Context) error {
+		actor = echojwtx.ActorDetails(c)
+
+		return c.String(http.StatusOK, echojwtx.Actor(c))
+	}, mdw)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+
+	if cert != nil {
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+		}
+
+		if verified {
+			req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	return rec, actor
+}
+
+func TestClientCertAuth(t *testing.T) {
+	spiffeCert := testHelperClientCert(t, "", "spiffe://example.org/ns/default/sa/api")
+	otherDomainCert := testHelperClientCert(t, "", "spiffe://other.org/ns/default/sa/api")
+	commonNameCert := testHelperClientCert(t, "billing-service")
+	noIdentityCert := testHelperClientCert(t, "")
+
+	testCases := []struct {
+		name        string
+		config      echojwtx.ClientCertConfig
+		cert        *x509.Certificate
+		unverified  bool
+		expectCode  int
+		expectActor *actorx.Actor
+	}{
+		{
+			name:       "spiffe id",
+			config:     echojwtx.ClientCertConfig{TrustDomains: []string{"spiffe://example.org"}, AllowAnySubject: true},
+			cert:       spiffeCert,
+			expectCode: http.StatusOK,
+			expectActor: &actorx.Actor{
+				RawSubject: "spiffe://example.org/ns/default/sa/api",
+				Kind:       actorx.KindService,
+				Issuer:     "spiffe://example.org",
+			},
+		},
+		{
+			name:       "common name",
+			config:     echojwtx.ClientCertConfig{AllowedSubjects: []string{"billing-service"}},
+			cert:       commonNameCert,
+			expectCode: http.StatusOK,
+			expectActor: &actorx.Actor{
+				Subject:    "billing-service",
+				RawSubject: "billing-service",
+				Kind:       actorx.KindService,
+				Issuer:     "CN=billing-service",
+			},
+		},
+		{
+			name:       "untrusted domain",
+			config:     echojwtx.ClientCertConfig{TrustDomains: []string{"example.org"}, AllowAnySubject: true},
+			cert:       otherDomainCert,
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "trust domain without spiffe id",
+			config:     echojwtx.ClientCertConfig{TrustDomains: []string{"example.org"}, AllowAnySubject: true},
+			cert:       commonNameCert,
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "no allowed subjects",
+			cert:       commonNameCert,
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "allow any subject",
+			config:     echojwtx.ClientCertConfig{AllowAnySubject: true},
+			cert:       commonNameCert,
+			expectCode: http.StatusOK,
+			expectActor: &actorx.Actor{
+				Subject:    "billing-service",
+				RawSubject: "billing-service",
+				Kind:       actorx.KindService,
+				Issuer:     "CN=billing-service",
+			},
+		},
+		{
+			name:       "subject not allowed",
+			config:     echojwtx.ClientCertConfig{AllowedSubjects: []string{"other-service"}},
+			cert:       commonNameCert,
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "no identity",
+			config:     echojwtx.ClientCertConfig{AllowAnySubject: true},
+			cert:       noIdentityCert,
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "unverified certificate",
+			config:     echojwtx.ClientCertConfig{AllowAnySubject: true},
+			cert:       commonNameCert,
+			unverified: true,
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "no certificate",
+			expectCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mdw := echojwtx.NewClientCertAuth(tc.config).Middleware()
+
+			rec, actor := testHelperClientCertRequest(mdw, tc.cert, !tc.unverified, "")
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+
+			if tc.expectActor != nil {
+				assert.Equal(t, tc.expectActor, actor)
+				assert.Equal(t, tc.expectActor.String(), rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestClientCertIdentityFunc(t *testing.T) {
+	mdw := echojwtx.NewClientCertAuth(echojwtx.ClientCertConfig{AllowedSubjects: []string{"idntsvc-billing"}},
+		echojwtx.WithClientCertIdentityFunc(func(cert *x509.Certificate) (string, string, error) {
+			return "idntsvc-" + cert.Subject.CommonName, "internal-ca", nil
+		}),
+	).Middleware()
+
+	rec, actor := testHelperClientCertRequest(mdw, testHelperClientCert(t, "billing"), true, "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "idntsvc-billing", rec.Body.String())
+	assert.Equal(t, "internal-ca", actor.Issuer)
+}
+
+func TestEitherClientCertOrJWT(t *testing.T) {
+	issuer, closer := testHelperOIDCProvider(TestPrivRSAKey1ID)
+	defer closer()
+
+	auth, err := echojwtx.NewAuth(context.Background(), echojwtx.AuthConfig{
+		Issuer: issuer,
+	})
+	require.NoError(t, err, "no error expected for NewAuth")
+
+	mdw := echojwtx.Either(
+		echojwtx.NewClientCertAuth(echojwtx.ClientCertConfig{AllowedSubjects: []string{"billing-service"}}).Middleware(),
+		auth.Middleware(),
+	)
+
+	token := testHelperSignToken(TestPrivRSAKey1ID, jwt.Claims{
+		Issuer:  issuer,
+		Subject: "urn:test:user",
+	}, nil)
+
+	rec, actor := testHelperClientCertRequest(mdw, testHelperClientCert(t, "billing-service"), true, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "billing-service", rec.Body.String())
+	assert.Equal(t, actorx.KindService, actor.Kind)
+
+	rec, actor = testHelperClientCertRequest(mdw, nil, false, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "urn:test:user", rec.Body.String())
+	assert.Equal(t, actorx.KindUser, actor.Kind)
+
+	rec, _ = testHelperClientCertRequest(mdw, nil, false, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, _ = testHelperClientCertRequest(mdw, nil, false, "invalid")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestEitherStopsAfterCommittedResponse(t *testing.T) {
+	var fallbackCalled bool
+
+	responding := func(_ echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := c.NoContent(http.StatusTooManyRequests); err != nil {
+				return err
+			}
+
+			return echo.ErrTooManyRequests
+		}
+	}
+
+	fallback := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			fallbackCalled = true
+
+			return next(c)
+		}
+	}
+
+	rec, _ := testHelperClientCertRequest(echojwtx.Either(responding, fallback), nil, false, "")
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.False(t, fallbackCalled, "expected middleware after a committed response to be skipped")
+}
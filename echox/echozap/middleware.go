@@ -114,6 +114,22 @@ func (config MiddlewareConfig) ToMiddleware() (echo.MiddlewareFunc, error) {
 				zap.String("actor", echojwtx.Actor(c)),
 			)
 
+			if actor := echojwtx.ActorDetails(c); !actor.IsAnonymous() {
+				fields = append(fields, zap.String("actor_kind", string(actor.Kind)))
+
+				if actor.Issuer != "" {
+					fields = append(fields, zap.String("actor_issuer", actor.Issuer))
+				}
+
+				if actor.ClientID != "" {
+					fields = append(fields, zap.String("actor_client_id", actor.ClientID))
+				}
+
+				if actor.IsImpersonated() {
+					fields = append(fields, zap.String("actor_impersonator", actor.Impersonator.String()))
+				}
+			}
+
 			if err != nil {
 				fields = append(fields, zap.Error(err))
 				if httpErr, ok := err.(*echo.HTTPError); ok {
@@ -80,3 +80,27 @@ func EventsHookJSONFieldWithDiff() *EventsHookAnnotation {
 }
 
 var _ schema.Annotation = EventsHookAnnotation{}
+
+// NodeResolverAnnotationName is the value of the node resolver annotation when read during ent compilation
+var NodeResolverAnnotationName = "INFRA9_NODE_RESOLVER"
+
+// NodeResolverAnnotation provides a ent.Annotation spec for registering a type with a NodeResolverRegistry.
+// These shouldn't be set directly, you should use NodeResolverPrefix() instead
+type NodeResolverAnnotation struct {
+	Prefix string
+}
+
+// Name implements the ent Annotation interface.
+func (a NodeResolverAnnotation) Name() string {
+	return NodeResolverAnnotationName
+}
+
+// NodeResolverPrefix sets the gidx prefix used for IDs of this ent type, the generated
+// RegisterNodeResolvers func will register a loader for the type using this prefix
+func NodeResolverPrefix(prefix string) *NodeResolverAnnotation {
+	return &NodeResolverAnnotation{
+		Prefix: prefix,
+	}
+}
+
+var _ schema.Annotation = NodeResolverAnnotation{}
@@ -0,0 +1,52 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entx
+
+import (
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/gidx"
+)
+
+type testEventHooksSchema struct {
+	ent.Schema
+}
+
+func (testEventHooksSchema) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("id").GoType(gidx.PrefixedID("")),
+		field.Strings("tags"),
+	}
+}
+
+func (testEventHooksSchema) Annotations() []schema.Annotation {
+	return []schema.Annotation{EventsHookSubjectName("testing")}
+}
+
+func TestEventHooksTemplateFieldValues(t *testing.T) {
+	generated, err := generateTemplate(t, EventHooksTemplate, "eventhooks/hooks.go", testSchema{"Tagged", testEventHooksSchema{}})
+	require.NoError(t, err)
+
+	// slice fields must be formatted the same way as events.DiffFieldChanges
+	assert.Contains(t, string(generated), "cv_tags, err = events.FormatFieldChangeValue(tags)")
+	assert.Contains(t, string(generated), "pv_tags, err = events.FormatFieldChangeValue(ov)")
+	assert.NotContains(t, string(generated), "fmt.Sprint(")
+}
@@ -28,6 +28,8 @@ type Extension struct {
 	templates []*gen.Template
 
 	gqlSchemaHooks []entgql.SchemaHook
+
+	nodeResolver bool
 }
 
 // ExtensionOption allow for control over the behavior of the generator
@@ -71,6 +73,22 @@ func WithEventHooks() ExtensionOption {
 	}
 }
 
+// WithNodeResolver adds the templates for registering annotated types with a NodeResolverRegistry,
+// and adds the node() and nodes() query calls back to the schema when they were removed by WithFederation
+// so a gateway can resolve any ID owned by the service. Annotated types must have a gidx.PrefixedID or string ID.
+//
+// A NodeResolver is generated implementing the node and nodes queries, the federation _entities
+// lookup and a Find<Type>ByID entity finder for each annotated type, so the gqlgen resolvers only
+// need to delegate to it.
+func WithNodeResolver() ExtensionOption {
+	return func(ex *Extension) error {
+		ex.templates = append(ex.templates, NodeResolverTemplate)
+		ex.nodeResolver = true
+
+		return nil
+	}
+}
+
 // NewExtension returns an entc Extension that allows the entx package to generate
 // the schema changes and templates needed to function
 func NewExtension(opts ...ExtensionOption) (*Extension, error) {
@@ -85,6 +103,11 @@ func NewExtension(opts ...ExtensionOption) (*Extension, error) {
 		}
 	}
 
+	// node queries are added last so they are restored after any removed by WithFederation
+	if e.nodeResolver {
+		e.gqlSchemaHooks = append(e.gqlSchemaHooks, addNodeQueries)
+	}
+
 	return e, nil
 }
 
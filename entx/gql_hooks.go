@@ -71,6 +71,37 @@ var (
 		return nil
 	}
 
+	addNodeQueries = func(_ *gen.Graph, s *ast.Schema) error {
+		q, ok := s.Types["Query"]
+		if !ok {
+			return errors.New("failed to find query definition in schema")
+		}
+
+		if q.Fields.ForName("node") == nil {
+			q.Fields = append(q.Fields, &ast.FieldDefinition{
+				Name:        "node",
+				Description: "Fetches an object given its ID.",
+				Arguments: ast.ArgumentDefinitionList{
+					{Name: "id", Description: "ID of the object.", Type: ast.NonNullNamedType("ID", nil)},
+				},
+				Type: ast.NamedType("Node", nil),
+			})
+		}
+
+		if q.Fields.ForName("nodes") == nil {
+			q.Fields = append(q.Fields, &ast.FieldDefinition{
+				Name:        "nodes",
+				Description: "Lookup nodes by a list of IDs.",
+				Arguments: ast.ArgumentDefinitionList{
+					{Name: "ids", Description: "The list of node IDs.", Type: ast.NonNullListType(ast.NonNullNamedType("ID", nil), nil)},
+				},
+				Type: ast.NonNullListType(ast.NamedType("Node", nil), nil),
+			})
+		}
+
+		return nil
+	}
+
 	setPageInfoShareable = func(_ *gen.Graph, s *ast.Schema) error {
 		q, ok := s.Types["PageInfo"]
 		if !ok {
@@ -0,0 +1,331 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.infratographer.com/x/gidx"
+)
+
+var (
+	// ErrNodeResolverExists is returned when a loader is already registered for a prefix.
+	ErrNodeResolverExists = errors.New("node resolver already registered for prefix")
+
+	// ErrNodeResolverNotFound is returned when no loader is registered for the prefix of an ID.
+	ErrNodeResolverNotFound = errors.New("no node resolver registered for prefix")
+
+	// ErrNodeNotFound is returned when the loader for an ID did not return a node.
+	ErrNodeNotFound = errors.New("node not found")
+
+	// ErrNodeLoaderResults is returned when a loader returns a different number of results than requested IDs.
+	ErrNodeLoaderResults = errors.New("node loader returned an unexpected number of results")
+
+	// ErrInvalidEntityRepresentation is returned when a federation entity representation is missing
+	// a valid id or has a __typename which doesn't match the type registered for the prefix.
+	ErrInvalidEntityRepresentation = errors.New("invalid entity representation")
+)
+
+// NodeLoaderFunc loads the nodes for a batch of IDs sharing a prefix. The returned slice must be
+// the same length as ids, with each node at the index of its ID and nil for IDs which don't exist.
+type NodeLoaderFunc func(ctx context.Context, ids []gidx.PrefixedID) ([]any, error)
+
+// NodeLoader returns a NodeLoaderFunc for loaders which return the nodes found in any order, such
+// as an ent query using IDIn. The id func returns the ID for a loaded node.
+func NodeLoader[T any](load func(ctx context.Context, ids []gidx.PrefixedID) ([]T, error), id func(T) gidx.PrefixedID) NodeLoaderFunc {
+	return func(ctx context.Context, ids []gidx.PrefixedID) ([]any, error) {
+		nodes, err := load(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		byID := make(map[gidx.PrefixedID]T, len(nodes))
+
+		for _, node := range nodes {
+			byID[id(node)] = node
+		}
+
+		results := make([]any, len(ids))
+
+		for i, nodeID := range ids {
+			if node, ok := byID[nodeID]; ok {
+				results[i] = node
+			}
+		}
+
+		return results, nil
+	}
+}
+
+// SingleNodeLoader returns a NodeLoaderFunc for callbacks which load one node at a time.
+// The callback should return ErrNodeNotFound, or a nil node, when the ID doesn't exist.
+func SingleNodeLoader(load func(ctx context.Context, id gidx.PrefixedID) (any, error)) NodeLoaderFunc {
+	return func(ctx context.Context, ids []gidx.PrefixedID) ([]any, error) {
+		results := make([]any, len(ids))
+
+		for i, id := range ids {
+			node, err := load(ctx, id)
+			if err != nil {
+				if errors.Is(err, ErrNodeNotFound) {
+					continue
+				}
+
+				return nil, err
+			}
+
+			results[i] = node
+		}
+
+		return results, nil
+	}
+}
+
+type nodeResolver struct {
+	typeName string
+	load     NodeLoaderFunc
+}
+
+// NodeResolverRegistry resolves any PrefixedID to its node using the loader registered for the
+// ID's prefix. It is used to implement the relay node and nodes queries, as well as federation
+// _entities lookups, for services which own many types.
+type NodeResolverRegistry struct {
+	mu        sync.RWMutex
+	resolvers map[string]nodeResolver
+}
+
+// NewNodeResolverRegistry returns an empty NodeResolverRegistry.
+func NewNodeResolverRegistry() *NodeResolverRegistry {
+	return &NodeResolverRegistry{
+		resolvers: make(map[string]nodeResolver),
+	}
+}
+
+// Register adds the loader for the prefix. The typeName is the graphql type returned by the
+// loader and is used to validate the __typename of federation entity representations.
+func (r *NodeResolverRegistry) Register(prefix, typeName string, load NodeLoaderFunc) error {
+	prefix = strings.ToLower(prefix)
+
+	if err := gidx.ValidatePrefix(prefix); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.resolvers[prefix]; ok {
+		return fmt.Errorf("%w: %s", ErrNodeResolverExists, prefix)
+	}
+
+	r.resolvers[prefix] = nodeResolver{
+		typeName: typeName,
+		load:     load,
+	}
+
+	return nil
+}
+
+// MustRegister wraps Register and panics in the event of an error.
+func (r *NodeResolverRegistry) MustRegister(prefix, typeName string, load NodeLoaderFunc) {
+	if err := r.Register(prefix, typeName, load); err != nil {
+		panic(err)
+	}
+}
+
+// Prefixes returns the sorted list of prefixes with a registered loader.
+func (r *NodeResolverRegistry) Prefixes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefixes := make([]string, 0, len(r.resolvers))
+
+	for prefix := range r.resolvers {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Strings(prefixes)
+
+	return prefixes
+}
+
+// TypeName returns the graphql type name registered for the prefix of the id.
+func (r *NodeResolverRegistry) TypeName(id gidx.PrefixedID) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resolver, ok := r.resolvers[id.Prefix()]
+
+	return resolver.typeName, ok
+}
+
+// Node resolves a single node by its ID.
+func (r *NodeResolverRegistry) Node(ctx context.Context, id gidx.PrefixedID) (any, error) {
+	nodes, err := r.Nodes(ctx, []gidx.PrefixedID{id})
+	if err != nil {
+		return nil, err
+	}
+
+	if nodes[0] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, id)
+	}
+
+	return nodes[0], nil
+}
+
+// Nodes resolves the nodes for the ids. IDs are grouped by prefix so each loader is called once.
+// The results are in the same order as ids, with nil for IDs which were not found.
+func (r *NodeResolverRegistry) Nodes(ctx context.Context, ids []gidx.PrefixedID) ([]any, error) {
+	batches := make(map[string][]int)
+
+	r.mu.RLock()
+
+	for i, id := range ids {
+		if _, err := gidx.Parse(id.String()); err != nil {
+			r.mu.RUnlock()
+
+			return nil, err
+		}
+
+		prefix := id.Prefix()
+
+		if _, ok := r.resolvers[prefix]; !ok {
+			r.mu.RUnlock()
+
+			return nil, fmt.Errorf("%w: %s", ErrNodeResolverNotFound, prefix)
+		}
+
+		batches[prefix] = append(batches[prefix], i)
+	}
+
+	resolvers := make(map[string]nodeResolver, len(batches))
+
+	for prefix := range batches {
+		resolvers[prefix] = r.resolvers[prefix]
+	}
+
+	r.mu.RUnlock()
+
+	results := make([]any, len(ids))
+
+	for prefix, indexes := range batches {
+		batchIDs := make([]gidx.PrefixedID, len(indexes))
+
+		for i, idx := range indexes {
+			batchIDs[i] = ids[idx]
+		}
+
+		nodes, err := resolvers[prefix].load(ctx, batchIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(nodes) != len(batchIDs) {
+			return nil, fmt.Errorf("%w: %s expected %d got %d", ErrNodeLoaderResults, prefix, len(batchIDs), len(nodes))
+		}
+
+		for i, idx := range indexes {
+			results[idx] = nodes[i]
+		}
+	}
+
+	return results, nil
+}
+
+// Entities resolves federation _entities representations which reference their node by id.
+// Representations for IDs which were not found are returned as nil.
+func (r *NodeResolverRegistry) Entities(ctx context.Context, representations []map[string]any) ([]any, error) {
+	ids := make([]gidx.PrefixedID, len(representations))
+
+	for i, rep := range representations {
+		id, err := r.entityID(rep)
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = id
+	}
+
+	return r.Nodes(ctx, ids)
+}
+
+func (r *NodeResolverRegistry) entityID(rep map[string]any) (gidx.PrefixedID, error) {
+	rawID, ok := rep["id"].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: missing id", ErrInvalidEntityRepresentation)
+	}
+
+	id, err := gidx.Parse(rawID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidEntityRepresentation, err)
+	}
+
+	typeName, ok := rep["__typename"].(string)
+	if !ok {
+		return id, nil
+	}
+
+	if registered, ok := r.TypeName(id); ok && registered != "" && registered != typeName {
+		return "", fmt.Errorf("%w: id %s is a %s not a %s", ErrInvalidEntityRepresentation, id, registered, typeName)
+	}
+
+	return id, nil
+}
+
+// ResolveNode resolves the node for the id using the registry and returns it as the type N,
+// which is usually the generated graphql Node interface.
+func ResolveNode[N any](ctx context.Context, r *NodeResolverRegistry, id gidx.PrefixedID) (N, error) {
+	var empty N
+
+	node, err := r.Node(ctx, id)
+	if err != nil {
+		return empty, err
+	}
+
+	n, ok := node.(N)
+	if !ok {
+		return empty, fmt.Errorf("%w: %T is not a %T", ErrNodeNotFound, node, empty)
+	}
+
+	return n, nil
+}
+
+// ResolveNodes resolves the nodes for the ids using the registry and returns them as the type N.
+func ResolveNodes[N any](ctx context.Context, r *NodeResolverRegistry, ids []gidx.PrefixedID) ([]N, error) {
+	nodes, err := r.Nodes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]N, len(nodes))
+
+	for i, node := range nodes {
+		if node == nil {
+			continue
+		}
+
+		n, ok := node.(N)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T is not a %T", ErrNodeNotFound, node, results[i])
+		}
+
+		results[i] = n
+	}
+
+	return results, nil
+}
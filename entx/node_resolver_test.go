@@ -0,0 +1,311 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entx
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"go.infratographer.com/x/gidx"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+type testNode struct {
+	ID gidx.PrefixedID
+}
+
+func (testNode) IsNode() {}
+
+type testNoder interface {
+	IsNode()
+}
+
+var errTestLoader = errors.New("loader failed")
+
+func TestNodeResolverRegistry(t *testing.T) {
+	users := map[gidx.PrefixedID]*testNode{}
+
+	for range 3 {
+		id := gidx.MustNewID("testusr")
+		users[id] = &testNode{ID: id}
+	}
+
+	group := &testNode{ID: gidx.MustNewID("testgrp")}
+
+	var userCalls [][]gidx.PrefixedID
+
+	r := NewNodeResolverRegistry()
+
+	require.NoError(t, r.Register("testusr", "User", NodeLoader(
+		func(_ context.Context, ids []gidx.PrefixedID) ([]*testNode, error) {
+			userCalls = append(userCalls, ids)
+
+			nodes := []*testNode{}
+
+			// return nodes out of order to ensure they're matched by id
+			for i := len(ids) - 1; i >= 0; i-- {
+				if n, ok := users[ids[i]]; ok {
+					nodes = append(nodes, n)
+				}
+			}
+
+			return nodes, nil
+		},
+		func(n *testNode) gidx.PrefixedID { return n.ID },
+	)))
+
+	require.NoError(t, r.Register("testgrp", "Group", SingleNodeLoader(func(_ context.Context, id gidx.PrefixedID) (any, error) {
+		if id == group.ID {
+			return group, nil
+		}
+
+		return nil, ErrNodeNotFound
+	})))
+
+	require.NoError(t, r.Register("testerr", "", func(context.Context, []gidx.PrefixedID) ([]any, error) {
+		return nil, errTestLoader
+	}))
+
+	t.Run("register", func(t *testing.T) {
+		assert.ErrorIs(t, r.Register("testusr", "User", nil), ErrNodeResolverExists)
+		assert.Error(t, r.Register("a", "A", nil))
+		assert.Equal(t, []string{"testerr", "testgrp", "testusr"}, r.Prefixes())
+
+		typeName, ok := r.TypeName(group.ID)
+		assert.True(t, ok)
+		assert.Equal(t, "Group", typeName)
+	})
+
+	t.Run("node", func(t *testing.T) {
+		node, err := r.Node(context.Background(), group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, group, node)
+
+		_, err = r.Node(context.Background(), gidx.MustNewID("testgrp"))
+		assert.ErrorIs(t, err, ErrNodeNotFound)
+
+		_, err = r.Node(context.Background(), gidx.MustNewID("testunk"))
+		assert.ErrorIs(t, err, ErrNodeResolverNotFound)
+
+		_, err = r.Node(context.Background(), gidx.MustNewID("testerr"))
+		assert.ErrorIs(t, err, errTestLoader)
+
+		_, err = r.Node(context.Background(), "invalid")
+		assert.Error(t, err)
+	})
+
+	t.Run("batched nodes", func(t *testing.T) {
+		userCalls = nil
+
+		ids := []gidx.PrefixedID{}
+		for id := range users {
+			ids = append(ids, id)
+		}
+
+		missing := gidx.MustNewID("testusr")
+		ids = append(ids[:1], append([]gidx.PrefixedID{group.ID, missing}, ids[1:]...)...)
+
+		nodes, err := r.Nodes(context.Background(), ids)
+		require.NoError(t, err)
+		require.Len(t, nodes, len(ids))
+
+		require.Len(t, userCalls, 1, "expected users to be loaded in a single batch")
+		assert.Len(t, userCalls[0], len(users)+1)
+
+		for i, id := range ids {
+			switch id {
+			case missing:
+				assert.Nil(t, nodes[i])
+			case group.ID:
+				assert.Equal(t, group, nodes[i])
+			default:
+				assert.Equal(t, users[id], nodes[i])
+			}
+		}
+
+		typed, err := ResolveNodes[testNoder](context.Background(), r, ids)
+		require.NoError(t, err)
+		assert.Nil(t, typed[2])
+		assert.Equal(t, testNoder(group), typed[1])
+
+		node, err := ResolveNode[testNoder](context.Background(), r, group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, testNoder(group), node)
+	})
+
+	t.Run("entities", func(t *testing.T) {
+		nodes, err := r.Entities(context.Background(), []map[string]any{
+			{"__typename": "Group", "id": group.ID.String()},
+			{"id": group.ID.String()},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []any{group, group}, nodes)
+
+		_, err = r.Entities(context.Background(), []map[string]any{{"__typename": "User", "id": group.ID.String()}})
+		assert.ErrorIs(t, err, ErrInvalidEntityRepresentation)
+
+		_, err = r.Entities(context.Background(), []map[string]any{{"__typename": "User"}})
+		assert.ErrorIs(t, err, ErrInvalidEntityRepresentation)
+	})
+
+	t.Run("loader results", func(t *testing.T) {
+		bad := NewNodeResolverRegistry()
+		bad.MustRegister("testbad", "Bad", func(context.Context, []gidx.PrefixedID) ([]any, error) {
+			return nil, nil
+		})
+
+		_, err := bad.Node(context.Background(), gidx.MustNewID("testbad"))
+		assert.ErrorIs(t, err, ErrNodeLoaderResults)
+	})
+}
+
+func TestAddNodeQueries(t *testing.T) {
+	s := &ast.Schema{
+		Types: map[string]*ast.Definition{
+			"Query": {Name: "Query", Fields: ast.FieldList{{Name: "node"}, {Name: "nodes"}, {Name: "users"}}},
+		},
+	}
+
+	require.NoError(t, removeNodeQueries(nil, s))
+	assert.Nil(t, s.Types["Query"].Fields.ForName("node"))
+
+	require.NoError(t, addNodeQueries(nil, s))
+	require.NotNil(t, s.Types["Query"].Fields.ForName("node"))
+	require.NotNil(t, s.Types["Query"].Fields.ForName("nodes"))
+	assert.Equal(t, "Node", s.Types["Query"].Fields.ForName("node").Type.Name())
+
+	// adding again doesn't duplicate the queries
+	require.NoError(t, addNodeQueries(nil, s))
+	assert.Len(t, s.Types["Query"].Fields, 3)
+}
+
+type testPrefixedIDSchema struct {
+	ent.Schema
+}
+
+func (testPrefixedIDSchema) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("id").GoType(gidx.PrefixedID("")),
+	}
+}
+
+func (testPrefixedIDSchema) Annotations() []schema.Annotation {
+	return []schema.Annotation{NodeResolverPrefix("testusr")}
+}
+
+type testStringIDSchema struct {
+	ent.Schema
+}
+
+func (testStringIDSchema) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("id"),
+	}
+}
+
+func (testStringIDSchema) Annotations() []schema.Annotation {
+	return []schema.Annotation{NodeResolverPrefix("testgrp")}
+}
+
+type testIntIDSchema struct {
+	ent.Schema
+}
+
+func (testIntIDSchema) Annotations() []schema.Annotation {
+	return []schema.Annotation{NodeResolverPrefix("testint")}
+}
+
+type testSchema struct {
+	name   string
+	schema ent.Interface
+}
+
+func generateNodeResolver(t *testing.T, schemas ...testSchema) ([]byte, error) {
+	t.Helper()
+
+	return generateTemplate(t, NodeResolverTemplate, "node_resolver.go", schemas...)
+}
+
+// generateTemplate generates the template for the schemas returning the contents of the generated file.
+func generateTemplate(t *testing.T, tmpl *gen.Template, file string, schemas ...testSchema) ([]byte, error) {
+	t.Helper()
+
+	loaded := make([]*load.Schema, len(schemas))
+
+	for i, s := range schemas {
+		b, err := load.MarshalSchema(s.schema)
+		require.NoError(t, err)
+
+		loaded[i], err = load.UnmarshalSchema(b)
+		require.NoError(t, err)
+
+		loaded[i].Name = s.name
+	}
+
+	storage, err := gen.NewStorage("sql")
+	require.NoError(t, err)
+
+	target := filepath.Join(t.TempDir(), "ent")
+
+	graph, err := gen.NewGraph(&gen.Config{
+		Package:   "example.com/ent",
+		Target:    target,
+		Storage:   storage,
+		Templates: []*gen.Template{tmpl},
+	}, loaded...)
+	require.NoError(t, err)
+
+	if err := graph.Gen(); err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(filepath.Join(target, file))
+}
+
+func TestNodeResolverTemplate(t *testing.T) {
+	generated, err := generateNodeResolver(t,
+		testSchema{"Group", testStringIDSchema{}},
+		testSchema{"User", testPrefixedIDSchema{}},
+	)
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "node_resolver.golden")
+
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(golden, generated, 0o600))
+	}
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(generated))
+
+	_, err = generateNodeResolver(t, testSchema{"Counter", testIntIDSchema{}})
+	assert.ErrorContains(t, err, "node resolver for Counter requires a gidx.PrefixedID or string ID")
+}
@@ -37,6 +37,9 @@ var (
 	// CollectionTemplate adds support for adding the nodes field to relay connections
 	CollectionTemplate = parseT("template/collection.tmpl")
 
+	// NodeResolverTemplate adds support for generating the registration of node resolvers
+	NodeResolverTemplate = parseT("template/node_resolver.tmpl")
+
 	// TemplateFuncs contains the extra template functions used by entx.
 	TemplateFuncs = template.FuncMap{
 		"contains":                          strings.Contains,
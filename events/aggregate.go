@@ -0,0 +1,326 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.infratographer.com/x/gidx"
+)
+
+// AggregateEvent is an event appended to the history of an aggregate.
+type AggregateEvent[E any] struct {
+	// AggregateID is the PrefixedID of the aggregate the event belongs to.
+	AggregateID gidx.PrefixedID `json:"aggregateID"`
+	// Type is the type of the event.
+	Type string `json:"type"`
+	// Timestamp is the time the event was appended.
+	Timestamp time.Time `json:"timestamp"`
+	// Data is the event payload.
+	Data E `json:"data"`
+	// Sequence is the stream sequence of the event, set when the event is loaded.
+	Sequence uint64 `json:"-"`
+}
+
+// Aggregate is the state of an aggregate folded from its events.
+type Aggregate[S any] struct {
+	// ID is the PrefixedID of the aggregate.
+	ID gidx.PrefixedID `json:"id"`
+	// State is the folded state of the aggregate.
+	State S `json:"state"`
+	// Version is the stream sequence of the last event applied to the state, zero if the aggregate has no events.
+	// The version is used as the expected version when appending new events.
+	Version uint64 `json:"version"`
+}
+
+// AggregateApplyFunc applies an event to the state of an aggregate and returns the new state.
+type AggregateApplyFunc[S, E any] func(state S, event AggregateEvent[E]) (S, error)
+
+// AggregateStoreConfig configures an AggregateStore.
+type AggregateStoreConfig struct {
+	// Stream is the JetStream stream the events are stored in.
+	// The stream must include the subjects of the aggregate, see AggregateStore.Subject.
+	Stream string
+	// Name is the aggregate type name, events are published to the subject aggregates.<name>.<id>.
+	Name string
+	// SnapshotBucket is the KV bucket snapshots are stored in, the bucket is created if it does not exist.
+	// Snapshots are disabled if empty.
+	SnapshotBucket string
+	// SnapshotInterval is the number of events loaded after the latest snapshot before a new snapshot is saved.
+	// Defaults to DefaultAggregateSnapshotInterval.
+	SnapshotInterval uint64
+}
+
+// DefaultAggregateSnapshotInterval is the default number of events loaded after the latest snapshot before a new snapshot is saved.
+var DefaultAggregateSnapshotInterval uint64 = 100
+
+// AggregateStore stores the events of aggregates in a JetStream stream, one subject per aggregate.
+//
+// Appends use the expected last subject sequence of the aggregate for optimistic concurrency,
+// ErrAggregateConcurrencyConflict is returned when another event was appended since the aggregate was loaded.
+// Loading replays the events of the aggregate through the apply func, starting from the latest snapshot if
+// snapshots are enabled.
+type AggregateStore[S, E any] struct {
+	conn     *NATSConnection
+	config   AggregateStoreConfig
+	apply    AggregateApplyFunc[S, E]
+	snapshot nats.KeyValue
+}
+
+// NewAggregateStore creates a new AggregateStore using the JetStream context of the connection.
+func NewAggregateStore[S, E any](conn *NATSConnection, config AggregateStoreConfig, apply AggregateApplyFunc[S, E]) (*AggregateStore[S, E], error) {
+	if config.Stream == "" || config.Name == "" {
+		return nil, ErrAggregateStoreInvalidConfig
+	}
+
+	if config.SnapshotInterval == 0 {
+		config.SnapshotInterval = DefaultAggregateSnapshotInterval
+	}
+
+	s := &AggregateStore[S, E]{
+		conn:   conn,
+		config: config,
+		apply:  apply,
+	}
+
+	if config.SnapshotBucket != "" {
+		kv, err := conn.jetstream.KeyValue(config.SnapshotBucket)
+		if errors.Is(err, nats.ErrBucketNotFound) {
+			kv, err = conn.jetstream.CreateKeyValue(&nats.KeyValueConfig{
+				Bucket:      config.SnapshotBucket,
+				Description: "aggregate snapshots for " + config.Name,
+			})
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed loading snapshot bucket: %w", err)
+		}
+
+		s.snapshot = kv
+	}
+
+	return s, nil
+}
+
+// Subject returns the subject the events of the aggregate are published to.
+func (s *AggregateStore[S, E]) Subject(id gidx.PrefixedID) string {
+	return s.conn.buildPublishSubject("aggregates", s.config.Name, id.String())
+}
+
+// Append appends the events to the aggregate and returns the new version of the aggregate.
+// The expectedVersion must be the version of the aggregate the events were produced from, zero for a new aggregate.
+//
+// Each event is published individually, if an error is returned after some events were appended the
+// version of the last appended event is returned with the error.
+func (s *AggregateStore[S, E]) Append(ctx context.Context, id gidx.PrefixedID, expectedVersion uint64, events ...AggregateEvent[E]) (uint64, error) {
+	ctx, span := s.conn.tracer.Start(ctx, "events.nats.AggregateAppend", trace.WithAttributes(
+		attribute.String("events.aggregate", s.config.Name),
+		attribute.String("events.subject_id", id.String()),
+		attribute.Int("events.count", len(events)),
+	))
+
+	defer span.End()
+
+	version := expectedVersion
+
+	for _, event := range events {
+		event.AggregateID = id
+
+		if event.Timestamp.IsZero() {
+			event.Timestamp = time.Now().UTC()
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			return version, err
+		}
+
+		ack, err := s.conn.jetstream.Publish(s.Subject(id), data,
+			nats.Context(ctx),
+			nats.ExpectStream(s.config.Stream),
+			nats.ExpectLastSequencePerSubject(version),
+		)
+		if err != nil {
+			var apiErr *nats.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence {
+				err = fmt.Errorf("%w: %s expected version %d: %w", ErrAggregateConcurrencyConflict, id, version, err)
+			}
+
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			return version, err
+		}
+
+		version = ack.Sequence
+	}
+
+	return version, nil
+}
+
+// Load loads the aggregate by folding its events into the zero value of S, starting from the latest snapshot
+// if snapshots are enabled. A new snapshot is saved once SnapshotInterval events were applied after the latest snapshot.
+// An aggregate without events is returned with a zero version.
+func (s *AggregateStore[S, E]) Load(ctx context.Context, id gidx.PrefixedID) (Aggregate[S], error) {
+	ctx, span := s.conn.tracer.Start(ctx, "events.nats.AggregateLoad", trace.WithAttributes(
+		attribute.String("events.aggregate", s.config.Name),
+		attribute.String("events.subject_id", id.String()),
+	))
+
+	defer span.End()
+
+	agg, applied, err := s.load(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return agg, err
+	}
+
+	span.SetAttributes(attribute.Int64("events.applied", int64(applied)))
+
+	if s.snapshot != nil && applied >= s.config.SnapshotInterval {
+		if err := s.SaveSnapshot(agg); err != nil {
+			s.conn.logger.Warnw("failed saving aggregate snapshot", "aggregate", s.config.Name, "id", id, "error", err)
+		}
+	}
+
+	return agg, nil
+}
+
+func (s *AggregateStore[S, E]) load(ctx context.Context, id gidx.PrefixedID) (Aggregate[S], uint64, error) {
+	agg, err := s.loadSnapshot(id)
+	if err != nil {
+		return agg, 0, err
+	}
+
+	subject := s.Subject(id)
+
+	last, err := s.conn.jetstream.GetLastMsg(s.config.Stream, subject, nats.Context(ctx))
+	if err != nil {
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			return agg, 0, nil
+		}
+
+		return agg, 0, err
+	}
+
+	if last.Sequence <= agg.Version {
+		return agg, 0, nil
+	}
+
+	sub, err := s.conn.jetstream.SubscribeSync(subject,
+		nats.BindStream(s.config.Stream),
+		nats.OrderedConsumer(),
+		nats.StartSequence(agg.Version+1),
+	)
+	if err != nil {
+		return agg, 0, err
+	}
+
+	defer sub.Unsubscribe() //nolint:errcheck // ephemeral consumer is removed by the server
+
+	var applied uint64
+
+	for agg.Version < last.Sequence {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return agg, applied, err
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return agg, applied, err
+		}
+
+		var event AggregateEvent[E]
+
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			return agg, applied, fmt.Errorf("%w: sequence %d: %w", ErrAggregateInvalidEvent, meta.Sequence.Stream, err)
+		}
+
+		event.Sequence = meta.Sequence.Stream
+
+		agg.State, err = s.apply(agg.State, event)
+		if err != nil {
+			return agg, applied, fmt.Errorf("failed applying event sequence %d: %w", event.Sequence, err)
+		}
+
+		agg.Version = event.Sequence
+		applied++
+	}
+
+	return agg, applied, nil
+}
+
+func (s *AggregateStore[S, E]) loadSnapshot(id gidx.PrefixedID) (Aggregate[S], error) {
+	agg := Aggregate[S]{ID: id}
+
+	if s.snapshot == nil {
+		return agg, nil
+	}
+
+	entry, err := s.snapshot.Get(id.String())
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return agg, nil
+		}
+
+		return agg, err
+	}
+
+	if err := json.Unmarshal(entry.Value(), &agg); err != nil {
+		// An unreadable snapshot is ignored and the aggregate is loaded from its full history.
+		s.conn.logger.Warnw("ignoring invalid aggregate snapshot", "aggregate", s.config.Name, "id", id, "error", err)
+
+		return Aggregate[S]{ID: id}, nil
+	}
+
+	return agg, nil
+}
+
+// SaveSnapshot saves the aggregate as the latest snapshot.
+// Snapshots older than the current snapshot are not saved.
+func (s *AggregateStore[S, E]) SaveSnapshot(agg Aggregate[S]) error {
+	if s.snapshot == nil {
+		return ErrAggregateSnapshotsDisabled
+	}
+
+	data, err := json.Marshal(agg)
+	if err != nil {
+		return err
+	}
+
+	key := agg.ID.String()
+
+	entry, err := s.snapshot.Get(key)
+	if err != nil {
+		if !errors.Is(err, nats.ErrKeyNotFound) {
+			return err
+		}
+
+		_, err = s.snapshot.Create(key, data)
+
+		return err
+	}
+
+	var current Aggregate[S]
+
+	if err := json.Unmarshal(entry.Value(), &current); err == nil && current.Version >= agg.Version {
+		return nil
+	}
+
+	_, err = s.snapshot.Update(key, data, entry.Revision())
+
+	return err
+}
@@ -0,0 +1,157 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	nc "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+var errUnknownAccountEvent = errors.New("unknown account event")
+
+type testAccount struct {
+	Name    string `json:"name"`
+	Balance int    `json:"balance"`
+	Events  int    `json:"events"`
+}
+
+type testAccountEvent struct {
+	Name   string `json:"name,omitempty"`
+	Amount int    `json:"amount,omitempty"`
+}
+
+func applyTestAccountEvent(state testAccount, event events.AggregateEvent[testAccountEvent]) (testAccount, error) {
+	switch event.Type {
+	case "opened":
+		state.Name = event.Data.Name
+	case "deposited":
+		state.Balance += event.Data.Amount
+	case "withdrawn":
+		state.Balance -= event.Data.Amount
+	default:
+		return state, errUnknownAccountEvent
+	}
+
+	state.Events++
+
+	return state, nil
+}
+
+func newTestAggregateStore(t *testing.T, snapshotBucket string, snapshotInterval uint64) (*eventtools.TestNats, *events.AggregateStore[testAccount, testAccountEvent]) {
+	t.Helper()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	t.Cleanup(nats.Close)
+
+	_, err = nats.JetStream.AddStream(&nc.StreamConfig{
+		Name:     "aggregates-tests",
+		Subjects: []string{eventtools.Prefix + ".aggregates.>"},
+	})
+	require.NoError(t, err)
+
+	conn, err := events.NewNATSConnection(nats.Config.NATS)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Shutdown(context.Background()) //nolint:errcheck // within test
+	})
+
+	store, err := events.NewAggregateStore(conn, events.AggregateStoreConfig{
+		Stream:           "aggregates-tests",
+		Name:             "account",
+		SnapshotBucket:   snapshotBucket,
+		SnapshotInterval: snapshotInterval,
+	}, applyTestAccountEvent)
+	require.NoError(t, err)
+
+	return nats, store
+}
+
+func TestAggregateStore(t *testing.T) {
+	ctx := context.Background()
+
+	_, store := newTestAggregateStore(t, "", 0)
+
+	id := gidx.PrefixedID("testacc-abc123")
+	other := gidx.PrefixedID("testacc-def456")
+
+	agg, err := store.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), agg.Version)
+	assert.Equal(t, testAccount{}, agg.State)
+
+	version, err := store.Append(ctx, id, 0,
+		events.AggregateEvent[testAccountEvent]{Type: "opened", Data: testAccountEvent{Name: "savings"}},
+		events.AggregateEvent[testAccountEvent]{Type: "deposited", Data: testAccountEvent{Amount: 100}},
+	)
+	require.NoError(t, err)
+
+	// Events of other aggregates do not affect the expected version.
+	_, err = store.Append(ctx, other, 0, events.AggregateEvent[testAccountEvent]{Type: "opened", Data: testAccountEvent{Name: "other"}})
+	require.NoError(t, err)
+
+	version, err = store.Append(ctx, id, version, events.AggregateEvent[testAccountEvent]{Type: "withdrawn", Data: testAccountEvent{Amount: 30}})
+	require.NoError(t, err)
+
+	_, err = store.Append(ctx, id, 0, events.AggregateEvent[testAccountEvent]{Type: "deposited", Data: testAccountEvent{Amount: 1}})
+	require.ErrorIs(t, err, events.ErrAggregateConcurrencyConflict)
+
+	agg, err = store.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, id, agg.ID)
+	assert.Equal(t, version, agg.Version)
+	assert.Equal(t, testAccount{Name: "savings", Balance: 70, Events: 3}, agg.State)
+
+	err = store.SaveSnapshot(agg)
+	require.ErrorIs(t, err, events.ErrAggregateSnapshotsDisabled)
+}
+
+func TestAggregateStoreSnapshots(t *testing.T) {
+	ctx := context.Background()
+
+	nats, store := newTestAggregateStore(t, "account-snapshots", 2)
+
+	id := gidx.PrefixedID("testacc-abc123")
+
+	version, err := store.Append(ctx, id, 0,
+		events.AggregateEvent[testAccountEvent]{Type: "opened", Data: testAccountEvent{Name: "savings"}},
+		events.AggregateEvent[testAccountEvent]{Type: "deposited", Data: testAccountEvent{Amount: 100}},
+	)
+	require.NoError(t, err)
+
+	agg, err := store.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, testAccount{Name: "savings", Balance: 100, Events: 2}, agg.State)
+
+	kv, err := nats.JetStream.KeyValue("account-snapshots")
+	require.NoError(t, err)
+
+	entry, err := kv.Get(id.String())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"testacc-abc123","state":{"name":"savings","balance":100,"events":2},"version":2}`, string(entry.Value()))
+
+	_, err = store.Append(ctx, id, version, events.AggregateEvent[testAccountEvent]{Type: "deposited", Data: testAccountEvent{Amount: 5}})
+	require.NoError(t, err)
+
+	// Only the events after the snapshot are applied.
+	agg, err = store.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, testAccount{Name: "savings", Balance: 105, Events: 3}, agg.State)
+	assert.Equal(t, uint64(3), agg.Version)
+
+	// Older snapshots do not replace newer ones.
+	require.NoError(t, store.SaveSnapshot(events.Aggregate[testAccount]{ID: id, Version: 1}))
+
+	entry, err = kv.Get(id.String())
+	require.NoError(t, err)
+	assert.Contains(t, string(entry.Value()), `"version":2`)
+}
@@ -0,0 +1,174 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// AuthRelationshipHandler processes a single AuthRelationshipRequest.
+type AuthRelationshipHandler func(ctx context.Context, request AuthRelationshipRequest) error
+
+// ProcessAuthRelationshipBatch calls the handler for each request in the batch and returns a response
+// with a result for each request, in the same order as the requests.
+// Requests which fail validation are not passed to the handler. A failing request does not stop the
+// remaining requests from being processed, the failure is only reported in its result.
+func ProcessAuthRelationshipBatch(ctx context.Context, batch AuthRelationshipBatchRequest, handler AuthRelationshipHandler) AuthRelationshipResponse {
+	var response AuthRelationshipResponse
+
+	if err := batch.Validate(); err != nil {
+		response.Errors = append(response.Errors, err)
+
+		return response
+	}
+
+	response.Results = make([]AuthRelationshipResult, len(batch.Requests))
+
+	for i, request := range batch.Requests {
+		result := AuthRelationshipResult{
+			ObjectID: request.ObjectID,
+		}
+
+		err := request.Validate()
+		if err == nil {
+			err = handler(ctx, request)
+		}
+
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+
+		response.Results[i] = result
+	}
+
+	return response
+}
+
+// ReplyAuthRelationshipBatch processes the batch request with the handler and replies with the per request results.
+// Requests which could not be decoded are replied to with the decode error.
+func ReplyAuthRelationshipBatch(ctx context.Context, request Request[AuthRelationshipBatchRequest, AuthRelationshipResponse], handler AuthRelationshipHandler) (Message[AuthRelationshipResponse], error) {
+	if err := request.Error(); err != nil {
+		return request.Reply(ctx, AuthRelationshipResponse{
+			Errors: Errors{fmt.Errorf("failed decoding auth relationship batch request: %w", err)},
+		})
+	}
+
+	batch := request.Message()
+
+	ctx = batch.GetTraceContext(ctx)
+
+	return request.Reply(ctx, ProcessAuthRelationshipBatch(ctx, batch, handler))
+}
+
+type authRelationshipBatchContext struct{}
+
+// AuthRelationshipBatch collects AuthRelationshipRequests so they may be published with a single
+// AuthRelationshipBatchRequest per topic instead of one request per object.
+type AuthRelationshipBatch struct {
+	mu       sync.Mutex
+	topics   []string
+	requests map[string][]AuthRelationshipRequest
+}
+
+// NewAuthRelationshipBatchContext returns a new context with an empty AuthRelationshipBatch stored in it.
+// Code which supports batching, such as the generated entx event hooks, adds its requests to the batch
+// instead of publishing them. The batch must be published once the work using the context is complete.
+func NewAuthRelationshipBatchContext(ctx context.Context) (context.Context, *AuthRelationshipBatch) {
+	batch := &AuthRelationshipBatch{
+		requests: make(map[string][]AuthRelationshipRequest),
+	}
+
+	return context.WithValue(ctx, authRelationshipBatchContext{}, batch), batch
+}
+
+// AuthRelationshipBatchFromContext returns the AuthRelationshipBatch stored in the context.
+func AuthRelationshipBatchFromContext(ctx context.Context) (*AuthRelationshipBatch, bool) {
+	batch, ok := ctx.Value(authRelationshipBatchContext{}).(*AuthRelationshipBatch)
+
+	return batch, ok && batch != nil
+}
+
+// Add adds the requests to the batch for the topic.
+func (b *AuthRelationshipBatch) Add(topic string, requests ...AuthRelationshipRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.requests[topic]; !ok {
+		b.topics = append(b.topics, topic)
+	}
+
+	b.requests[topic] = append(b.requests[topic], requests...)
+}
+
+// Len returns the number of requests in the batch.
+func (b *AuthRelationshipBatch) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var count int
+
+	for _, requests := range b.requests {
+		count += len(requests)
+	}
+
+	return count
+}
+
+// Publish publishes an AuthRelationshipBatchRequest for each topic in the batch, in the order the topics were first added,
+// and empties the batch. Errors from publishing and any failed results from the responses are returned.
+// ErrAuthRelationshipBatchUnsupported is returned if the publisher does not implement AuthRelationshipBatchPublisher.
+func (b *AuthRelationshipBatch) Publish(ctx context.Context, publisher AuthRelationshipPublisher) error {
+	b.mu.Lock()
+
+	topics := b.topics
+	requests := b.requests
+
+	b.topics = nil
+	b.requests = make(map[string][]AuthRelationshipRequest)
+
+	b.mu.Unlock()
+
+	if len(topics) == 0 {
+		return nil
+	}
+
+	batchPublisher, ok := publisher.(AuthRelationshipBatchPublisher)
+	if !ok {
+		return ErrAuthRelationshipBatchUnsupported
+	}
+
+	var err error
+
+	for _, topic := range topics {
+		resp, pubErr := batchPublisher.PublishAuthRelationshipBatchRequest(ctx, topic, AuthRelationshipBatchRequest{
+			Requests: requests[topic],
+		})
+		if pubErr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed publishing auth relationship batch request to %s: %w", topic, pubErr))
+
+			continue
+		}
+
+		if respErr := resp.Error(); respErr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed decoding auth relationship batch response from %s: %w", topic, respErr))
+
+			continue
+		}
+
+		response := resp.Message()
+
+		for _, respErr := range response.Errors {
+			err = multierr.Append(err, respErr)
+		}
+
+		for _, result := range response.FailedResults() {
+			for _, resultErr := range result.Errors {
+				err = multierr.Append(err, fmt.Errorf("auth relationship request for %s failed: %w", result.ObjectID, resultErr))
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,314 @@
+package events
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// DecodeFieldChanges returns a new T with the current values of the changes set.
+// See ApplyFieldChanges for details on how fields are matched and decoded.
+func DecodeFieldChanges[T any](changes []FieldChange) (T, error) {
+	var v T
+
+	err := ApplyFieldChanges(&v, changes)
+
+	return v, err
+}
+
+// ApplyFieldChanges sets the current value of each change on the struct pointed to by dst.
+//
+// Changes are matched to struct fields using the same naming as the entx event hooks,
+// the field's json tag name, or the snake case of the field name if no json tag is defined.
+// Changes for fields which do not exist on the struct are skipped.
+//
+// Values are decoded from the format produced by the event hooks and DiffFieldChanges.
+// Empty values set the zero value of the field.
+func ApplyFieldChanges(dst any, changes []FieldChange) error {
+	rv := reflect.ValueOf(dst)
+
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: got %T", ErrFieldChangeInvalidTarget, dst)
+	}
+
+	rv = rv.Elem()
+
+	fields := changesetFields(rv.Type())
+
+	for _, change := range changes {
+		index, ok := fields[change.Field]
+		if !ok {
+			continue
+		}
+
+		field, err := rv.FieldByIndexErr(index)
+		if err != nil {
+			return fmt.Errorf("%w: field %s: %w", ErrFieldChangeInvalidValue, change.Field, err)
+		}
+
+		if err := decodeFieldChangeValue(field, change.CurrentValue); err != nil {
+			return fmt.Errorf("%w: field %s: %w", ErrFieldChangeInvalidValue, change.Field, err)
+		}
+	}
+
+	return nil
+}
+
+// InvertFieldChanges returns the changes needed to undo the provided changes.
+// The previous and current values of each change are swapped.
+func InvertFieldChanges(changes []FieldChange) []FieldChange {
+	inverted := make([]FieldChange, len(changes))
+
+	for i, change := range changes {
+		inverted[i] = FieldChange{
+			Field:         change.Field,
+			PreviousValue: change.CurrentValue,
+			CurrentValue:  change.PreviousValue,
+		}
+	}
+
+	return inverted
+}
+
+// DiffFieldChanges returns a change for every field which differs between before and after,
+// in struct field order, using the same field naming as the entx event hooks.
+// Values are formatted with FormatFieldChangeValue, as they are by the event hooks.
+func DiffFieldChanges[T any](before, after T) ([]FieldChange, error) {
+	bv := reflect.Indirect(reflect.ValueOf(&before).Elem())
+	av := reflect.Indirect(reflect.ValueOf(&after).Elem())
+
+	if !bv.IsValid() || !av.IsValid() {
+		return nil, fmt.Errorf("%w: got nil %T", ErrFieldChangeInvalidTarget, before)
+	}
+
+	if bv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: got %T", ErrFieldChangeInvalidTarget, before)
+	}
+
+	changes := []FieldChange{}
+
+	for _, f := range reflect.VisibleFields(bv.Type()) {
+		name, ok := changesetFieldName(f)
+		if !ok {
+			continue
+		}
+
+		// Fields promoted from nil embedded pointers are treated as empty.
+		previousField, _ := bv.FieldByIndexErr(f.Index)
+		currentField, _ := av.FieldByIndexErr(f.Index)
+
+		previous, err := encodeFieldChangeValue(previousField)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %s: %w", ErrFieldChangeInvalidValue, name, err)
+		}
+
+		current, err := encodeFieldChangeValue(currentField)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %s: %w", ErrFieldChangeInvalidValue, name, err)
+		}
+
+		if previous != current {
+			changes = append(changes, FieldChange{
+				Field:         name,
+				PreviousValue: previous,
+				CurrentValue:  current,
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// changesetFields maps the change field names of the struct type to their field index.
+func changesetFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+
+	for _, f := range reflect.VisibleFields(t) {
+		if name, ok := changesetFieldName(f); ok {
+			fields[name] = f.Index
+		}
+	}
+
+	return fields
+}
+
+// changesetFieldName returns the change field name for the struct field.
+// False is returned for unexported, embedded and json ignored fields.
+func changesetFieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() || f.Anonymous {
+		return "", false
+	}
+
+	tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return snakeCase(f.Name), true
+	default:
+		return tag, true
+	}
+}
+
+// FormatFieldChangeValue formats a field value for a FieldChange. It is used by DiffFieldChanges and the
+// entx event hooks so changes from either source are formatted the same way.
+// Times are formatted as RFC3339, text marshalers use their text and byte slices are used as is.
+// Maps, slices, arrays and structs are json encoded and all other values are formatted with fmt.Sprint.
+// Nil values are formatted as an empty string.
+func FormatFieldChangeValue(v any) (string, error) {
+	return encodeFieldChangeValue(reflect.ValueOf(v))
+}
+
+func encodeFieldChangeValue(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return "", nil
+		}
+
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+
+		b, err := json.Marshal(v.Interface())
+
+		return string(b), err
+	case reflect.Array, reflect.Struct:
+		b, err := json.Marshal(v.Interface())
+
+		return string(b), err
+	default:
+		return fmt.Sprint(v.Interface()), nil
+	}
+}
+
+func decodeFieldChangeValue(field reflect.Value, value string) error {
+	if value == "" {
+		field.SetZero()
+
+		return nil
+	}
+
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+
+		if err := decodeFieldChangeValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+
+		return nil
+	}
+
+	if reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, base10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, base10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes([]byte(value))
+
+			return nil
+		}
+
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	case reflect.Map, reflect.Array, reflect.Struct:
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	default:
+		return fmt.Errorf("%w: %s", ErrFieldChangeUnsupportedType, field.Type())
+	}
+
+	return nil
+}
+
+// snakeCase converts a go field name such as OwnerID into owner_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package events_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+)
+
+type testChangesetBase struct {
+	ID        gidx.PrefixedID `json:"id,omitempty"`
+	CreatedAt time.Time       `json:"created_at,omitempty"`
+}
+
+type testChangesetSubject struct {
+	testChangesetBase
+
+	Name        string          `json:"name,omitempty"`
+	Port        int             `json:"port,omitempty"`
+	Enabled     bool            `json:"enabled,omitempty"`
+	Weight      *float64        `json:"weight,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	OwnerID     gidx.PrefixedID
+	Ignored     string `json:"-"`
+	notExported string
+}
+
+func TestDiffAndApplyFieldChanges(t *testing.T) {
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	weight := 1.5
+
+	before := testChangesetSubject{
+		testChangesetBase: testChangesetBase{
+			ID:        "testing-abc",
+			CreatedAt: created,
+		},
+		Name:    "before",
+		Port:    80,
+		OwnerID: "testtnt-abc",
+	}
+
+	after := before
+	after.Name = "after"
+	after.Port = 443
+	after.Enabled = true
+	after.Weight = &weight
+	after.Tags = []string{"a", "b"}
+	after.Metadata = json.RawMessage(`{"key":"value"}`)
+	after.Ignored = "ignored"
+	after.notExported = "not exported"
+
+	changes, err := events.DiffFieldChanges(before, after)
+	require.NoError(t, err)
+
+	assert.Equal(t, []events.FieldChange{
+		{Field: "name", PreviousValue: "before", CurrentValue: "after"},
+		{Field: "port", PreviousValue: "80", CurrentValue: "443"},
+		{Field: "enabled", PreviousValue: "false", CurrentValue: "true"},
+		{Field: "weight", PreviousValue: "", CurrentValue: "1.5"},
+		{Field: "tags", PreviousValue: "", CurrentValue: `["a","b"]`},
+		{Field: "metadata", PreviousValue: "", CurrentValue: `{"key":"value"}`},
+	}, changes)
+
+	applied := before
+
+	require.NoError(t, events.ApplyFieldChanges(&applied, changes))
+
+	after.Ignored = ""
+	after.notExported = ""
+
+	assert.Equal(t, after, applied)
+
+	require.NoError(t, events.ApplyFieldChanges(&applied, events.InvertFieldChanges(changes)))
+
+	assert.Equal(t, before, applied)
+}
+
+func TestFormatFieldChangeValue(t *testing.T) {
+	tags := []string{"a", "b"}
+
+	// the entx event hooks format field values with FormatFieldChangeValue,
+	// changes from the hooks and DiffFieldChanges must be formatted the same way.
+	formatted, err := events.FormatFieldChangeValue(tags)
+	require.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, formatted)
+
+	changes, err := events.DiffFieldChanges(testChangesetSubject{}, testChangesetSubject{Tags: tags})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, formatted, changes[0].CurrentValue)
+
+	formatted, err = events.FormatFieldChangeValue(nil)
+	require.NoError(t, err)
+	assert.Empty(t, formatted)
+
+	formatted, err = events.FormatFieldChangeValue(gidx.PrefixedID("testing-abc"))
+	require.NoError(t, err)
+	assert.Equal(t, "testing-abc", formatted)
+}
+
+func TestDecodeFieldChanges(t *testing.T) {
+	changes := []events.FieldChange{
+		{Field: "id", CurrentValue: "testing-abc"},
+		{Field: "created_at", CurrentValue: "2023-01-02T03:04:05Z"},
+		{Field: "name", PreviousValue: "old", CurrentValue: "new"},
+		{Field: "owner_id", CurrentValue: "testtnt-abc"},
+		{Field: "unknown", CurrentValue: "skipped"},
+	}
+
+	subject, err := events.DecodeFieldChanges[testChangesetSubject](changes)
+	require.NoError(t, err)
+
+	assert.Equal(t, gidx.PrefixedID("testing-abc"), subject.ID)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), subject.CreatedAt.UTC())
+	assert.Equal(t, "new", subject.Name)
+	assert.Equal(t, gidx.PrefixedID("testtnt-abc"), subject.OwnerID)
+
+	_, err = events.DecodeFieldChanges[testChangesetSubject]([]events.FieldChange{{Field: "port", CurrentValue: "eighty"}})
+	require.ErrorIs(t, err, events.ErrFieldChangeInvalidValue)
+
+	err = events.ApplyFieldChanges(subject, changes)
+	require.ErrorIs(t, err, events.ErrFieldChangeInvalidTarget)
+}
@@ -15,12 +15,15 @@
 package events
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
+
+	"go.infratographer.com/x/viperx"
 )
 
 const (
@@ -31,11 +34,52 @@ const (
 // Config contains event provider configs.
 type Config struct {
 	NATS NATSConfig `mapstructure:"nats"`
+
+	// Clusters are additional named NATS connections used by NewMultiConnection.
+	// Each cluster has its own publish and subscribe prefixes.
+	Clusters map[string]NATSConfig `mapstructure:"clusters"`
+	// ClusterURLs are additional named NATS connections used by NewMultiConnection, configured with only a url.
+	// Each cluster uses the NATS config with its own url.
+	ClusterURLs map[string]string `mapstructure:"clusterURLs"`
+	// PublishMode sets whether a MultiConnection publish must succeed on all or any of the clusters.
+	PublishMode string `mapstructure:"publishMode"`
+}
+
+// Validate ensures the configuration is valid.
+func (c Config) Validate() error {
+	err := c.NATS.Validate()
+
+	for name, cluster := range c.Clusters {
+		if cErr := cluster.Validate(); cErr != nil {
+			err = multierr.Append(err, fmt.Errorf("cluster %s: %w", name, cErr))
+		}
+	}
+
+	for name := range c.ClusterURLs {
+		if _, ok := c.Clusters[name]; ok || (name == DefaultClusterName && c.NATS.Configured()) {
+			err = multierr.Append(err, fmt.Errorf("%w: %s", ErrDuplicateClusterName, name))
+		}
+	}
+
+	switch c.PublishMode {
+	case "", PublishModeAll, PublishModeAny:
+	default:
+		err = multierr.Append(err, ErrInvalidPublishMode)
+	}
+
+	return err
 }
 
 // MustViperFlags returns the cobra flags and viper config for events.
 func MustViperFlags(v *viper.Viper, flags *pflag.FlagSet, appName string) {
 	MustViperFlagsForNATS(v, flags, appName)
+
+	flags.StringToString("events-clusters", nil, "additional nats clusters for a multi connection, each cluster uses the nats config with its own url (name=url)")
+	viperx.MustBindFlag(v, "events.clusterURLs", flags.Lookup("events-clusters"))
+
+	v.MustBindEnv("events.publishMode")
+
+	v.SetDefault("events.publishMode", PublishModeAll)
 }
 
 // Option configures a connection option.
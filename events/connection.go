@@ -49,6 +49,20 @@ type AuthRelationshipPublisher interface {
 	PublishAuthRelationshipRequest(ctx context.Context, topic string, message AuthRelationshipRequest) (Message[AuthRelationshipResponse], error)
 }
 
+// AuthRelationshipBatchSubscriber specifies the optional auth relationship batch subscriber methods.
+// Use a type assertion on a Connection to check if batch requests are supported.
+type AuthRelationshipBatchSubscriber interface {
+	// SubscribeAuthRelationshipBatchRequests subscribes to the provided topic responding with an AuthRelationshipBatchRequest message.
+	SubscribeAuthRelationshipBatchRequests(ctx context.Context, topic string) (<-chan Request[AuthRelationshipBatchRequest, AuthRelationshipResponse], error)
+}
+
+// AuthRelationshipBatchPublisher specifies the optional auth relationship batch publisher methods.
+// Use a type assertion on a Connection to check if batch requests are supported.
+type AuthRelationshipBatchPublisher interface {
+	// PublishAuthRelationshipBatchRequest publishes to the specified topic with the batch message given.
+	PublishAuthRelationshipBatchRequest(ctx context.Context, topic string, message AuthRelationshipBatchRequest) (Message[AuthRelationshipResponse], error)
+}
+
 // NewConnection creates a new Connection from the provided config.
 func NewConnection(config Config, options ...Option) (Connection, error) {
 	var err error
@@ -27,6 +27,50 @@ var (
 	// ErrMissingAuthRelationshipRequestRelationSubjectID is returned when the event message Relations has the incorrect field SubjectID value.
 	ErrMissingAuthRelationshipRequestRelationSubjectID = errors.New("auth relationship request message Relations SubjectID field required")
 
+	// ErrMissingAuthRelationshipBatchRequests is returned when the batch request message has no requests defined.
+	ErrMissingAuthRelationshipBatchRequests = errors.New("auth relationship batch request message Requests field required")
+
+	// ErrAuthRelationshipBatchUnsupported is returned when a connection does not support auth relationship batch requests.
+	ErrAuthRelationshipBatchUnsupported = errors.New("connection does not support auth relationship batch requests")
+
+	// ErrInvalidPublishMode is returned when the publish mode is not all or any.
+	ErrInvalidPublishMode = errors.New("invalid publish mode, expected all|any")
+
+	// ErrDuplicateClusterName is returned when a cluster name is used more than once.
+	ErrDuplicateClusterName = errors.New("duplicate cluster name")
+
+	// ErrPublishFailed is returned when a MultiConnection publish did not succeed on the required clusters.
+	ErrPublishFailed = errors.New("failed publishing to clusters")
+
+	// ErrPublishRateLimited is returned when a publish is rejected by the publish rate limit.
+	ErrPublishRateLimited = errors.New("publish rate limit exceeded")
+
+	// ErrAggregateStoreInvalidConfig is returned when the aggregate store config is missing the stream or name.
+	ErrAggregateStoreInvalidConfig = errors.New("aggregate store Stream and Name fields required")
+
+	// ErrAggregateConcurrencyConflict is returned when an aggregate was modified since the expected version.
+	ErrAggregateConcurrencyConflict = errors.New("aggregate concurrency conflict")
+
+	// ErrAggregateInvalidEvent is returned when an aggregate event could not be decoded.
+	ErrAggregateInvalidEvent = errors.New("invalid aggregate event")
+
+	// ErrAggregateSnapshotsDisabled is returned when saving a snapshot for a store without a snapshot bucket.
+	ErrAggregateSnapshotsDisabled = errors.New("aggregate snapshots are disabled")
+
+	// ErrMessageAlreadySettled is returned when a message is acked, nacked or terminated after it was already settled,
+	// for example when it was nacked because it was not processed before the connection shutdown timed out.
+	ErrMessageAlreadySettled = errors.New("message already settled")
+
 	// ErrRequestNoResponders is returned when a request is attempted but no responder is listening.
 	ErrRequestNoResponders = errors.New("no responders for request")
+
+	// ErrFieldChangeInvalidTarget is returned when field changes are applied to or diffed from a value which is not a struct.
+	ErrFieldChangeInvalidTarget = errors.New("field changes target must be a struct or non-nil pointer to a struct")
+	// ErrFieldChangeInvalidValue is returned when a field change value cannot be encoded or decoded.
+	ErrFieldChangeInvalidValue = errors.New("invalid field change value")
+	// ErrFieldChangeUnsupportedType is returned when a field change is applied to a field of an unsupported type.
+	ErrFieldChangeUnsupportedType = errors.New("unsupported field change type")
+
+	// ErrProjectionUnsupportedEventType is returned when a change with an event type other than create, update or delete is applied to a projection.
+	ErrProjectionUnsupportedEventType = errors.New("projection unsupported change event type")
 )
@@ -59,6 +59,33 @@ type Message[T any] interface {
 	Source() any
 }
 
+// MessageWrapper is implemented by messages which wrap another message, such as the messages from a MultiConnection.
+type MessageWrapper interface {
+	// Unwrap returns the wrapped message.
+	Unwrap() any
+}
+
+// MessageAs returns the first message in the chain of wrapped messages, starting with msg, which implements I.
+// Use it instead of a type assertion to check for optional methods, such as NATSMessage InProgress, so wrapped messages are supported.
+func MessageAs[I any](msg any) (I, bool) {
+	for msg != nil {
+		if target, ok := msg.(I); ok {
+			return target, true
+		}
+
+		wrapper, ok := msg.(MessageWrapper)
+		if !ok {
+			break
+		}
+
+		msg = wrapper.Unwrap()
+	}
+
+	var zero I
+
+	return zero, false
+}
+
 // Request extends Message by allowing replies to be sent for the received message.
 type Request[TRequest, TResponse any] interface {
 	Message[TRequest]
@@ -282,6 +309,32 @@ func (m AuthRelationshipRequest) Validate() error {
 	return err
 }
 
+// AuthRelationshipBatchRequest contains many AuthRelationshipRequests to be processed with a single request.
+// Each request is processed independently and the AuthRelationshipResponse includes a result for each request.
+type AuthRelationshipBatchRequest struct {
+	// Requests are the auth relationship requests to process.
+	Requests []AuthRelationshipRequest `json:"requests"`
+	// TraceContext is a map of values used for OpenTelemetry context propagation.
+	TraceContext map[string]string `json:"traceContext"`
+}
+
+// GetTraceContext creates a new OpenTelementry context for the message.
+func (m AuthRelationshipBatchRequest) GetTraceContext(ctx context.Context) context.Context {
+	tp := otel.GetTextMapPropagator()
+
+	return tp.Extract(ctx, propagation.MapCarrier(m.TraceContext))
+}
+
+// Validate ensures the message has at least one request.
+// Individual requests are validated when the batch is processed so invalid requests only fail their own result.
+func (m AuthRelationshipBatchRequest) Validate() error {
+	if len(m.Requests) == 0 {
+		return ErrMissingAuthRelationshipBatchRequests
+	}
+
+	return nil
+}
+
 // AuthRelationshipRelation defines the relation an object from an AuthRelationshipRequest has to a subject.
 type AuthRelationshipRelation struct {
 	// Relation is the name of the relation the object from AuthRelationshipRequest has to the subject.
@@ -367,11 +420,21 @@ func (e Errors) Error() string {
 	return strings.Join(errs, "\n")
 }
 
+// AuthRelationshipResult contains the result of a single request from an AuthRelationshipBatchRequest.
+type AuthRelationshipResult struct {
+	// ObjectID is the PrefixedID of the object from the request.
+	ObjectID gidx.PrefixedID `json:"objectID"`
+	// Errors contains any errors, if empty the request was successful.
+	Errors Errors `json:"errors"`
+}
+
 // AuthRelationshipResponse contains the data structure expected to be received from an AuthRelationshipRequest
 // message to write or delete an auth relationship from PermissionsAPI
 type AuthRelationshipResponse struct {
 	// Errors contains any errors, if empty the request was successful
 	Errors Errors `json:"errors"`
+	// Results contains the result of each request from an AuthRelationshipBatchRequest, in the same order as the requests.
+	Results []AuthRelationshipResult `json:"results,omitempty"`
 	// TraceContext is a map of values used for OpenTelemetry context propagation.
 	TraceContext map[string]string `json:"traceContext"`
 	// TraceID is the ID of the trace for this event
@@ -394,6 +457,19 @@ func (m AuthRelationshipResponse) Validate() error {
 	return nil
 }
 
+// FailedResults returns the batch results which have errors.
+func (m AuthRelationshipResponse) FailedResults() []AuthRelationshipResult {
+	var failed []AuthRelationshipResult
+
+	for _, result := range m.Results {
+		if len(result.Errors) != 0 {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
 // UnmarshalChangeMessage returns a ChangeMessage from a json []byte.
 func UnmarshalChangeMessage(b []byte) (ChangeMessage, error) {
 	var c ChangeMessage
@@ -421,6 +497,15 @@ func UnmarshalAuthRelationshipRequest(b []byte) (AuthRelationshipRequest, error)
 	return m, err
 }
 
+// UnmarshalAuthRelationshipBatchRequest returns an AuthRelationshipBatchRequest from a json []byte.
+func UnmarshalAuthRelationshipBatchRequest(b []byte) (AuthRelationshipBatchRequest, error) {
+	var m AuthRelationshipBatchRequest
+
+	err := json.Unmarshal(b, &m)
+
+	return m, err
+}
+
 // UnmarshalAuthRelationshipResponse returns an AuthRelationshipRsponse from a json []byte.
 func UnmarshalAuthRelationshipResponse(b []byte) (AuthRelationshipResponse, error) {
 	var m AuthRelationshipResponse
@@ -0,0 +1,537 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultClusterName is the name given to the Config NATS connection in a MultiConnection.
+	DefaultClusterName = "default"
+
+	// PublishModeAll requires a publish to succeed on every cluster.
+	// If any cluster fails, the publish returns only the error, even though other clusters may have received the message.
+	PublishModeAll = "all"
+	// PublishModeAny requires a publish to succeed on at least one cluster.
+	// Failures on the other clusters are logged and not returned.
+	PublishModeAny = "any"
+)
+
+var _ Connection = (*MultiConnection)(nil)
+
+var (
+	_ AuthRelationshipBatchSubscriber = (*MultiConnection)(nil)
+	_ AuthRelationshipBatchPublisher  = (*MultiConnection)(nil)
+)
+
+// MultiConnection implements Connection over multiple named connections, such as a local and a central cluster.
+//
+// Changes and events are published to every connection concurrently, each connection using its own
+// publish prefix. Whether a publish must succeed on all or any of the connections is set by the Config PublishMode.
+// Auth relationship requests are sent to each connection in order until one responds.
+//
+// Subscriptions merge the messages received from every connection, each message is annotated with the
+// name of the connection it was received from, see MessageCluster.
+type MultiConnection struct {
+	logger      *zap.SugaredLogger
+	publishMode string
+	names       []string
+	conns       map[string]Connection
+}
+
+// NewMultiConnection creates a new MultiConnection connecting to the Config NATS connection, named DefaultClusterName,
+// and each of the Config Clusters and ClusterURLs.
+// Options are applied to the Config NATS connection, clusters without a logger use the NATS connection logger.
+func NewMultiConnection(config Config, options ...Option) (*MultiConnection, error) {
+	var err error
+
+	for _, opt := range options {
+		err = multierr.Append(err, opt(&config))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]NATSConfig, len(config.Clusters)+len(config.ClusterURLs)+1)
+
+	if config.NATS.Configured() {
+		configs[DefaultClusterName] = config.NATS
+	}
+
+	for name, cluster := range config.Clusters {
+		if _, ok := configs[name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateClusterName, name)
+		}
+
+		if cluster.logger == nil {
+			cluster.logger = config.NATS.logger
+		}
+
+		configs[name] = cluster
+	}
+
+	for name, url := range config.ClusterURLs {
+		if _, ok := configs[name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateClusterName, name)
+		}
+
+		cluster := config.NATS
+		cluster.URL = url
+
+		configs[name] = cluster
+	}
+
+	conns := make(map[string]Connection, len(configs))
+
+	for name, cfg := range configs {
+		if !cfg.Configured() {
+			err = fmt.Errorf("%w: cluster %s", ErrProviderNotConfigured, name)
+
+			break
+		}
+
+		conn, cErr := NewNATSConnection(cfg)
+		if cErr != nil {
+			err = fmt.Errorf("failed connecting to cluster %s: %w", name, cErr)
+
+			break
+		}
+
+		conns[name] = conn
+	}
+
+	if err != nil {
+		for _, conn := range conns {
+			_ = conn.Shutdown(context.Background())
+		}
+
+		return nil, err
+	}
+
+	return NewMultiConnectionFromConnections(config.PublishMode, conns, WithMultiConnectionLogger(config.NATS.logger))
+}
+
+// MultiConnectionOption configures a MultiConnection.
+type MultiConnectionOption func(c *MultiConnection)
+
+// WithMultiConnectionLogger sets the logger for the connection.
+func WithMultiConnectionLogger(logger *zap.SugaredLogger) MultiConnectionOption {
+	return func(c *MultiConnection) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// NewMultiConnectionFromConnections creates a new MultiConnection from existing named connections.
+// Connections are used in name order, with DefaultClusterName always first.
+func NewMultiConnectionFromConnections(publishMode string, conns map[string]Connection, options ...MultiConnectionOption) (*MultiConnection, error) {
+	if len(conns) == 0 {
+		return nil, ErrProviderNotConfigured
+	}
+
+	if publishMode == "" {
+		publishMode = PublishModeAll
+	}
+
+	if publishMode != PublishModeAll && publishMode != PublishModeAny {
+		return nil, ErrInvalidPublishMode
+	}
+
+	names := slices.SortedFunc(maps.Keys(conns), func(a, b string) int {
+		switch {
+		case a == b:
+			return 0
+		case a == DefaultClusterName:
+			return -1
+		case b == DefaultClusterName:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	c := &MultiConnection{
+		logger:      zap.NewNop().Sugar(),
+		publishMode: publishMode,
+		names:       names,
+		conns:       conns,
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Clusters returns the names of the connections, in the order they are used.
+func (c *MultiConnection) Clusters() []string {
+	return slices.Clone(c.names)
+}
+
+// Cluster returns the named connection.
+func (c *MultiConnection) Cluster(name string) (Connection, bool) {
+	conn, ok := c.conns[name]
+
+	return conn, ok
+}
+
+// Shutdown gracefully closes all connections.
+func (c *MultiConnection) Shutdown(ctx context.Context) error {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		err error
+	)
+
+	for _, name := range c.names {
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			if sErr := c.conns[name].Shutdown(ctx); sErr != nil {
+				mu.Lock()
+				err = multierr.Append(err, fmt.Errorf("cluster %s: %w", name, sErr))
+				mu.Unlock()
+			}
+		}(name)
+	}
+
+	wg.Wait()
+
+	return err
+}
+
+// Source returns the named connections.
+func (c *MultiConnection) Source() any {
+	return maps.Clone(c.conns)
+}
+
+// PublishChange publishes the change to every connection.
+// The message returned is from the first connection in order which succeeded.
+// On failure, only an ErrPublishFailed error is returned, see PublishModeAll and PublishModeAny.
+func (c *MultiConnection) PublishChange(ctx context.Context, topic string, message ChangeMessage) (Message[ChangeMessage], error) {
+	return multiPublish(ctx, c, func(ctx context.Context, conn Connection) (Message[ChangeMessage], error) {
+		return conn.PublishChange(ctx, topic, message)
+	})
+}
+
+// PublishEvent publishes the event to every connection.
+// The message returned is from the first connection in order which succeeded.
+// On failure, only an ErrPublishFailed error is returned, see PublishModeAll and PublishModeAny.
+func (c *MultiConnection) PublishEvent(ctx context.Context, topic string, message EventMessage) (Message[EventMessage], error) {
+	return multiPublish(ctx, c, func(ctx context.Context, conn Connection) (Message[EventMessage], error) {
+		return conn.PublishEvent(ctx, topic, message)
+	})
+}
+
+// PublishAuthRelationshipRequest sends the request to each connection in order until one responds.
+func (c *MultiConnection) PublishAuthRelationshipRequest(ctx context.Context, topic string, message AuthRelationshipRequest) (Message[AuthRelationshipResponse], error) {
+	return multiRequest(ctx, c, func(ctx context.Context, conn Connection) (Message[AuthRelationshipResponse], error) {
+		return conn.PublishAuthRelationshipRequest(ctx, topic, message)
+	})
+}
+
+// PublishAuthRelationshipBatchRequest sends the batch request to each connection in order until one responds.
+// Connections which do not implement AuthRelationshipBatchPublisher are skipped with ErrAuthRelationshipBatchUnsupported.
+func (c *MultiConnection) PublishAuthRelationshipBatchRequest(ctx context.Context, topic string, message AuthRelationshipBatchRequest) (Message[AuthRelationshipResponse], error) {
+	return multiRequest(ctx, c, func(ctx context.Context, conn Connection) (Message[AuthRelationshipResponse], error) {
+		publisher, ok := conn.(AuthRelationshipBatchPublisher)
+		if !ok {
+			return nil, ErrAuthRelationshipBatchUnsupported
+		}
+
+		return publisher.PublishAuthRelationshipBatchRequest(ctx, topic, message)
+	})
+}
+
+// SubscribeChanges subscribes to the topic on every connection and merges the received messages.
+func (c *MultiConnection) SubscribeChanges(ctx context.Context, topic string) (<-chan Message[ChangeMessage], error) {
+	return multiSubscribe(ctx, c, func(ctx context.Context, conn Connection) (<-chan Message[ChangeMessage], error) {
+		return conn.SubscribeChanges(ctx, topic)
+	}, newMultiMessage[ChangeMessage])
+}
+
+// SubscribeEvents subscribes to the topic on every connection and merges the received messages.
+func (c *MultiConnection) SubscribeEvents(ctx context.Context, topic string) (<-chan Message[EventMessage], error) {
+	return multiSubscribe(ctx, c, func(ctx context.Context, conn Connection) (<-chan Message[EventMessage], error) {
+		return conn.SubscribeEvents(ctx, topic)
+	}, newMultiMessage[EventMessage])
+}
+
+// SubscribeAuthRelationshipRequests subscribes to the topic on every connection and merges the received requests.
+func (c *MultiConnection) SubscribeAuthRelationshipRequests(ctx context.Context, topic string) (<-chan Request[AuthRelationshipRequest, AuthRelationshipResponse], error) {
+	return multiSubscribe(ctx, c, func(ctx context.Context, conn Connection) (<-chan Request[AuthRelationshipRequest, AuthRelationshipResponse], error) {
+		return conn.SubscribeAuthRelationshipRequests(ctx, topic)
+	}, newMultiRequest[AuthRelationshipRequest, AuthRelationshipResponse])
+}
+
+// SubscribeAuthRelationshipBatchRequests subscribes to the topic on every connection and merges the received requests.
+// Every connection must implement AuthRelationshipBatchSubscriber, otherwise ErrAuthRelationshipBatchUnsupported is returned.
+func (c *MultiConnection) SubscribeAuthRelationshipBatchRequests(ctx context.Context, topic string) (<-chan Request[AuthRelationshipBatchRequest, AuthRelationshipResponse], error) {
+	return multiSubscribe(ctx, c, func(ctx context.Context, conn Connection) (<-chan Request[AuthRelationshipBatchRequest, AuthRelationshipResponse], error) {
+		subscriber, ok := conn.(AuthRelationshipBatchSubscriber)
+		if !ok {
+			return nil, ErrAuthRelationshipBatchUnsupported
+		}
+
+		return subscriber.SubscribeAuthRelationshipBatchRequests(ctx, topic)
+	}, newMultiRequest[AuthRelationshipBatchRequest, AuthRelationshipResponse])
+}
+
+func multiPublish[T any](ctx context.Context, c *MultiConnection, publish func(context.Context, Connection) (Message[T], error)) (Message[T], error) {
+	msgs := make([]Message[T], len(c.names))
+	errs := make([]error, len(c.names))
+
+	var wg sync.WaitGroup
+
+	for i, name := range c.names {
+		wg.Add(1)
+
+		go func(i int, name string) {
+			defer wg.Done()
+
+			msg, err := publish(ctx, c.conns[name])
+			if err != nil {
+				c.logger.Warnw("error publishing to cluster", "cluster", name, "error", err)
+
+				errs[i] = fmt.Errorf("cluster %s: %w", name, err)
+
+				return
+			}
+
+			msgs[i] = newMultiMessage(name, msg)
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	err := errors.Join(errs...)
+
+	var first Message[T]
+
+	for _, msg := range msgs {
+		if msg != nil {
+			first = msg
+
+			break
+		}
+	}
+
+	if first == nil {
+		return nil, fmt.Errorf("%w: %w", ErrPublishFailed, err)
+	}
+
+	if err != nil && c.publishMode == PublishModeAll {
+		return nil, fmt.Errorf("%w: %w", ErrPublishFailed, err)
+	}
+
+	return first, nil
+}
+
+func multiRequest[T any](ctx context.Context, c *MultiConnection, request func(context.Context, Connection) (Message[T], error)) (Message[T], error) {
+	var errs []error
+
+	for _, name := range c.names {
+		resp, err := request(ctx, c.conns[name])
+		if err == nil {
+			return newMultiMessage(name, resp), nil
+		}
+
+		errs = append(errs, fmt.Errorf("cluster %s: %w", name, err))
+
+		// Only fail over when the cluster had nobody to answer the request.
+		if !errors.Is(err, ErrRequestNoResponders) {
+			break
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func multiSubscribe[T any, M Message[T]](
+	ctx context.Context,
+	c *MultiConnection,
+	subscribe func(context.Context, Connection) (<-chan M, error),
+	annotate func(cluster string, msg M) M,
+) (<-chan M, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	channels := make(map[string]<-chan M, len(c.names))
+
+	for _, name := range c.names {
+		ch, err := subscribe(ctx, c.conns[name])
+		if err != nil {
+			cancel()
+
+			return nil, fmt.Errorf("cluster %s: %w", name, err)
+		}
+
+		channels[name] = ch
+	}
+
+	merged := make(chan M)
+
+	var wg sync.WaitGroup
+
+	for name, ch := range channels {
+		wg.Add(1)
+
+		go func(name string, ch <-chan M) {
+			defer wg.Done()
+
+			for msg := range ch {
+				select {
+				case merged <- annotate(name, msg):
+				case <-ctx.Done():
+					// Release the message so it may be redelivered.
+					if err := msg.Nak(0); err != nil {
+						c.logger.Debugw("error nacking message", "cluster", name, "error", err)
+					}
+				}
+			}
+		}(name, ch)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(merged)
+	}()
+
+	return merged, nil
+}
+
+// ClusterMessage is implemented by messages sent or received by a MultiConnection.
+type ClusterMessage interface {
+	// Cluster returns the name of the connection the message was sent or received on.
+	Cluster() string
+}
+
+// MessageCluster returns the name of the MultiConnection connection the message was sent or received on.
+// False is returned if the message is not from a MultiConnection.
+func MessageCluster(msg any) (string, bool) {
+	if cMsg, ok := msg.(ClusterMessage); ok {
+		return cMsg.Cluster(), true
+	}
+
+	return "", false
+}
+
+// multiMessage annotates a Message with the cluster it was sent or received on.
+type multiMessage[T any] struct {
+	msg     Message[T]
+	cluster string
+}
+
+func newMultiMessage[T any](cluster string, msg Message[T]) Message[T] {
+	return &multiMessage[T]{
+		msg:     msg,
+		cluster: cluster,
+	}
+}
+
+// Cluster returns the name of the connection the message was sent or received on.
+func (m *multiMessage[T]) Cluster() string {
+	return m.cluster
+}
+
+// Connection implements Message.
+func (m *multiMessage[T]) Connection() Connection {
+	return m.msg.Connection()
+}
+
+// ID implements Message.
+func (m *multiMessage[T]) ID() string {
+	return m.msg.ID()
+}
+
+// Topic implements Message.
+func (m *multiMessage[T]) Topic() string {
+	return m.msg.Topic()
+}
+
+// Message implements Message.
+func (m *multiMessage[T]) Message() T {
+	return m.msg.Message()
+}
+
+// Ack implements Message.
+func (m *multiMessage[T]) Ack() error {
+	return m.msg.Ack()
+}
+
+// Nak implements Message.
+func (m *multiMessage[T]) Nak(delay time.Duration) error {
+	return m.msg.Nak(delay)
+}
+
+// Term implements Message.
+func (m *multiMessage[T]) Term() error {
+	return m.msg.Term()
+}
+
+// Timestamp implements Message.
+func (m *multiMessage[T]) Timestamp() time.Time {
+	return m.msg.Timestamp()
+}
+
+// Deliveries implements Message.
+func (m *multiMessage[T]) Deliveries() uint64 {
+	return m.msg.Deliveries()
+}
+
+// Error implements Message.
+func (m *multiMessage[T]) Error() error {
+	return m.msg.Error()
+}
+
+// Source implements Message.
+func (m *multiMessage[T]) Source() any {
+	return m.msg.Source()
+}
+
+// Unwrap implements MessageWrapper, returning the message from the connection.
+func (m *multiMessage[T]) Unwrap() any {
+	return m.msg
+}
+
+type multiRequestMessage[TRequest, TResponse any] struct {
+	Request[TRequest, TResponse]
+
+	cluster string
+}
+
+func newMultiRequest[TRequest, TResponse any](cluster string, req Request[TRequest, TResponse]) Request[TRequest, TResponse] {
+	return &multiRequestMessage[TRequest, TResponse]{
+		Request: req,
+		cluster: cluster,
+	}
+}
+
+// Cluster returns the name of the connection the request was received on.
+func (m *multiRequestMessage[TRequest, TResponse]) Cluster() string {
+	return m.cluster
+}
+
+// Unwrap implements MessageWrapper, returning the request from the connection.
+func (m *multiRequestMessage[TRequest, TResponse]) Unwrap() any {
+	return m.Request
+}
@@ -0,0 +1,171 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+var errClusterUnavailable = errors.New("cluster unavailable")
+
+func TestMultiConnectionPublishAndSubscribe(t *testing.T) {
+	ctx := context.Background()
+
+	local, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer local.Close()
+
+	central, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer central.Close()
+
+	config := local.Config
+	config.Clusters = map[string]events.NATSConfig{
+		"central": central.Config.NATS,
+	}
+
+	conn, err := events.NewMultiConnection(config)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	assert.Equal(t, []string{events.DefaultClusterName, "central"}, conn.Clusters())
+
+	change := testCreateChange()
+
+	msg, err := conn.PublishChange(ctx, "test", change)
+	require.NoError(t, err)
+
+	cluster, ok := events.MessageCluster(msg)
+	require.True(t, ok)
+	assert.Equal(t, events.DefaultClusterName, cluster)
+
+	messages, err := conn.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	received := map[string]events.ChangeMessage{}
+
+	for range 2 {
+		receivedMsg, err := getSingleMessage(messages, time.Second*2)
+		require.NoError(t, err)
+		require.NoError(t, receivedMsg.Error())
+		require.NoError(t, receivedMsg.Ack())
+
+		cluster, ok := events.MessageCluster(receivedMsg)
+		require.True(t, ok)
+
+		// NATS specific methods are reachable through the wrapped message.
+		seqMsg, ok := events.MessageAs[interface{ StreamSequence() uint64 }](receivedMsg)
+		require.True(t, ok)
+		assert.NotZero(t, seqMsg.StreamSequence())
+
+		_, ok = events.MessageAs[interface{ InProgress() error }](receivedMsg)
+		assert.True(t, ok)
+
+		received[cluster] = receivedMsg.Message()
+	}
+
+	require.Len(t, received, 2)
+	assert.EqualValues(t, change, received[events.DefaultClusterName])
+	assert.EqualValues(t, change, received["central"])
+}
+
+func TestMultiConnectionPublishMode(t *testing.T) {
+	ctx := context.Background()
+
+	change := testCreateChange()
+
+	newConns := func() map[string]events.Connection {
+		healthy := new(eventtools.MockConnection)
+		healthy.On("PublishChange", "test", change).Return(new(eventtools.MockMessage[events.ChangeMessage]), nil)
+
+		unhealthy := new(eventtools.MockConnection)
+		unhealthy.On("PublishChange", "test", mock.Anything).Return((*eventtools.MockMessage[events.ChangeMessage])(nil), errClusterUnavailable)
+
+		return map[string]events.Connection{
+			"central": unhealthy,
+			"local":   healthy,
+		}
+	}
+
+	conn, err := events.NewMultiConnectionFromConnections(events.PublishModeAny, newConns())
+	require.NoError(t, err)
+
+	msg, err := conn.PublishChange(ctx, "test", change)
+	require.NoError(t, err)
+
+	cluster, _ := events.MessageCluster(msg)
+	assert.Equal(t, "local", cluster)
+
+	conn, err = events.NewMultiConnectionFromConnections(events.PublishModeAll, newConns())
+	require.NoError(t, err)
+
+	msg, err = conn.PublishChange(ctx, "test", change)
+	require.ErrorIs(t, err, events.ErrPublishFailed)
+	require.ErrorIs(t, err, errClusterUnavailable)
+	assert.ErrorContains(t, err, "cluster central")
+	assert.Nil(t, msg)
+
+	_, err = events.NewMultiConnectionFromConnections("some", newConns())
+	require.ErrorIs(t, err, events.ErrInvalidPublishMode)
+}
+
+func TestMultiConnectionClusterURLs(t *testing.T) {
+	ctx := context.Background()
+
+	local, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer local.Close()
+
+	central, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer central.Close()
+
+	v := viper.New()
+	flags := pflag.NewFlagSet("flags", pflag.ContinueOnError)
+
+	events.MustViperFlags(v, flags, "test")
+
+	require.NoError(t, flags.Parse([]string{"--events-clusters", "central=" + central.Config.NATS.URL}))
+
+	var appConfig struct {
+		Events events.Config `mapstructure:"events"`
+	}
+
+	require.NoError(t, v.Unmarshal(&appConfig))
+
+	assert.Equal(t, map[string]string{"central": central.Config.NATS.URL}, appConfig.Events.ClusterURLs)
+
+	config := local.Config
+	config.ClusterURLs = appConfig.Events.ClusterURLs
+
+	conn, err := events.NewMultiConnection(config)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	assert.Equal(t, []string{events.DefaultClusterName, "central"}, conn.Clusters())
+
+	_, err = conn.PublishChange(ctx, "test", testCreateChange())
+	require.NoError(t, err)
+
+	config.Clusters = map[string]events.NATSConfig{
+		"central": central.Config.NATS,
+	}
+
+	assert.ErrorIs(t, config.Validate(), events.ErrDuplicateClusterName)
+}
@@ -23,6 +23,8 @@ var (
 	NATSDefaultSubscriberFetchBackoff = 5 * time.Second
 	// NATSDefaultShutdownTimeout is the timeout for a shutdown to complete.
 	NATSDefaultShutdownTimeout = 5 * time.Second
+	// NATSDefaultRequestNoRespondersBackoff is the delay between request attempts when there are no responders.
+	NATSDefaultRequestNoRespondersBackoff = 500 * time.Millisecond
 )
 
 // NATSConfig defines the NATS connection configuration.
@@ -47,6 +49,16 @@ type NATSConfig struct {
 	SubscriberStartSequence  uint64
 	SubscriberStartTime      time.Time
 
+	RequestTimeout             time.Duration
+	RequestNoRespondersRetries int
+	RequestNoRespondersBackoff time.Duration
+
+	PublishRateLimit        float64
+	PublishRateBurst        int
+	PublishRateLimitMode    string
+	PublishTopicRateLimits  map[string]float64
+	PublishSourceRateLimits map[string]float64
+
 	logger           *zap.SugaredLogger
 	connectOptions   []nats.Option
 	jetStreamOptions []nats.JSOpt
@@ -72,6 +84,26 @@ func (c NATSConfig) Validate() error {
 		err = multierr.Append(err, ErrNATSInvalidDeliveryPolicy)
 	}
 
+	switch c.PublishRateLimitMode {
+	case "", NATSPublishRateLimitModeBlock, NATSPublishRateLimitModeFail:
+	default:
+		err = multierr.Append(err, ErrNATSInvalidPublishRateLimit)
+	}
+
+	if c.PublishRateLimit < 0 || c.PublishRateBurst < 0 {
+		err = multierr.Append(err, ErrNATSInvalidPublishRateLimit)
+	}
+
+	for _, limits := range []map[string]float64{c.PublishTopicRateLimits, c.PublishSourceRateLimits} {
+		for _, limit := range limits {
+			if limit < 0 {
+				err = multierr.Append(err, ErrNATSInvalidPublishRateLimit)
+
+				break
+			}
+		}
+	}
+
 	return err
 }
 
@@ -116,6 +148,14 @@ func (c NATSConfig) WithDefaults() NATSConfig {
 		c.subscribeOptions = append(c.subscribeOptions, nats.StartTime(c.SubscriberStartTime))
 	}
 
+	if c.RequestNoRespondersBackoff == 0 {
+		c.RequestNoRespondersBackoff = NATSDefaultRequestNoRespondersBackoff
+	}
+
+	if c.PublishRateLimitMode == "" {
+		c.PublishRateLimitMode = NATSPublishRateLimitModeBlock
+	}
+
 	if c.ShutdownTimeout == 0 {
 		c.ShutdownTimeout = NATSDefaultShutdownTimeout
 	}
@@ -201,6 +241,24 @@ func MustViperFlagsForNATS(v *viper.Viper, flags *pflag.FlagSet, appName string)
 	v.MustBindEnv("events.nats.subscriberDeliveryPolicy")
 	v.MustBindEnv("events.nats.subscriberStartSequence")
 	v.MustBindEnv("events.nats.subscriberStartTime")
+	v.MustBindEnv("events.nats.requestTimeout")
+	v.MustBindEnv("events.nats.requestNoRespondersRetries")
+	v.MustBindEnv("events.nats.requestNoRespondersBackoff")
+
+	flags.Float64("events-nats-publish-rate-limit", 0, "max messages per second published to each topic, 0 disables rate limiting")
+	viperx.MustBindFlag(v, "events.nats.publishRateLimit", flags.Lookup("events-nats-publish-rate-limit"))
+
+	flags.Int("events-nats-publish-rate-burst", 0, "number of messages which may be published to a topic at once, defaults to the rate limit")
+	viperx.MustBindFlag(v, "events.nats.publishRateBurst", flags.Lookup("events-nats-publish-rate-burst"))
+
+	flags.String("events-nats-publish-rate-limit-mode", NATSPublishRateLimitModeBlock, "publish rate limit mode, block waits for the rate limit and fail returns an error (block|fail)")
+	viperx.MustBindFlag(v, "events.nats.publishRateLimitMode", flags.Lookup("events-nats-publish-rate-limit-mode"))
+
+	flags.StringToString("events-nats-publish-source-rate-limits", nil, "per source publish rate limits overriding the default rate limit, a rate of 0 disables rate limiting for the source (source=rate)")
+	viperx.MustBindFlag(v, "events.nats.publishSourceRateLimits", flags.Lookup("events-nats-publish-source-rate-limits"))
+
+	flags.StringToString("events-nats-publish-topic-rate-limits", nil, "per topic publish rate limits overriding the default rate limit, a rate of 0 disables rate limiting for the topic (topic=rate)")
+	viperx.MustBindFlag(v, "events.nats.publishTopicRateLimits", flags.Lookup("events-nats-publish-topic-rate-limits"))
 
 	v.SetDefault("events.nats.connectTimeout", defaultTimeout)
 	v.SetDefault("events.nats.source", appName)
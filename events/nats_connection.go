@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"strings"
+	"sync"
 
 	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
@@ -21,6 +22,11 @@ const (
 
 var _ Connection = (*NATSConnection)(nil)
 
+var (
+	_ AuthRelationshipBatchSubscriber = (*NATSConnection)(nil)
+	_ AuthRelationshipBatchPublisher  = (*NATSConnection)(nil)
+)
+
 // NATSConnection implements Connection.
 type NATSConnection struct {
 	logger    *zap.SugaredLogger
@@ -28,15 +34,27 @@ type NATSConnection struct {
 	conn      *nats.Conn
 	jetstream nats.JetStreamContext
 	cfg       NATSConfig
+	limiter   *natsPublishLimiter
+
+	mu            sync.Mutex
+	subscriptions map[*natsSubscription]struct{}
 }
 
 // Shutdown gracefully drains the connection.
+// Subscriptions first stop fetching new messages, messages which have not yet been received
+// by the subscriber are nacked and in-flight messages are given until the shutdown deadline
+// to be processed before they too are nacked so they may be redelivered immediately.
+// The connection is then drained, flushing the acks and nacks. The whole shutdown is bound
+// by the deadline of ctx, capped at the ShutdownTimeout.
 func (c *NATSConnection) Shutdown(ctx context.Context) error {
 	ctx, cancelTimeout := context.WithTimeout(ctx, c.cfg.ShutdownTimeout)
-	ctx, cancel := context.WithCancelCause(ctx)
 
 	defer cancelTimeout()
 
+	c.drainSubscriptions(ctx)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+
 	closedCB := c.conn.Opts.ClosedCB
 
 	c.conn.Opts.ClosedCB = func(c *nats.Conn) {
@@ -58,6 +76,52 @@ func (c *NATSConnection) Shutdown(ctx context.Context) error {
 	return ctx.Err()
 }
 
+func (c *NATSConnection) drainSubscriptions(ctx context.Context) {
+	c.mu.Lock()
+
+	subs := make([]*natsSubscription, 0, len(c.subscriptions))
+
+	for sub := range c.subscriptions {
+		subs = append(subs, sub)
+	}
+
+	c.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.stop()
+	}
+
+	for _, sub := range subs {
+		if err := sub.wait(ctx); err != nil {
+			sub.logger.Warnw("timed out waiting for in-flight messages to be processed", "error", err)
+
+			sub.nakInFlight()
+		}
+	}
+}
+
+func (c *NATSConnection) addSubscription(ctx context.Context, logger *zap.SugaredLogger) *natsSubscription {
+	sub := newNATSSubscription(ctx, logger)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[*natsSubscription]struct{})
+	}
+
+	c.subscriptions[sub] = struct{}{}
+
+	return sub
+}
+
+func (c *NATSConnection) removeSubscription(sub *natsSubscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.subscriptions, sub)
+}
+
 // Source returns the underlying NATS Connection.
 func (c *NATSConnection) Source() any {
 	return c.conn
@@ -137,12 +201,20 @@ func NewNATSConnection(config NATSConfig, options ...NATSOption) (*NATSConnectio
 		return nil, err
 	}
 
+	limiter, err := newNATSPublishLimiter(nc)
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
 	return &NATSConnection{
 		logger:    nc.logger,
 		tracer:    otel.GetTracerProvider().Tracer(natsTracerName),
 		conn:      conn,
 		jetstream: js,
 		cfg:       nc,
+		limiter:   limiter,
 	}, nil
 }
 
@@ -9,6 +9,9 @@ var (
 	// ErrNATSInvalidDeliveryPolicy is returned when an incorrect delivery policy is provided.
 	ErrNATSInvalidDeliveryPolicy = errors.New("invalid delivery policy, expected all|last|last-per-subject|new|start-sequence|start-time")
 
+	// ErrNATSInvalidPublishRateLimit is returned when an incorrect publish rate limit configuration is provided.
+	ErrNATSInvalidPublishRateLimit = errors.New("invalid publish rate limit, rates and burst must not be negative and mode must be block|fail")
+
 	// ErrNATSMessageNoReplySubject is returned when calling ReplyAuthRelationshipRequest when the request has no reply subject defined.
 	ErrNATSMessageNoReplySubject = errors.New("unable to reply to auth relationship request, no reply subject specified")
 )
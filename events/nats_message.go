@@ -14,18 +14,39 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 )
 
-func natsSubscriptionMessageChan[T any](ctx context.Context, conn *NATSConnection, batchSize int, natsCh <-chan *nats.Msg) chan Message[T] {
+func natsSubscriptionMessageChan[T any](conn *NATSConnection, tracker *natsSubscription, batchSize int, natsCh <-chan *nats.Msg) chan Message[T] {
 	msgCh := make(chan Message[T], batchSize)
 
 	go func() {
 		defer close(msgCh)
 
 		for nMsg := range natsCh {
-			msg := natsDecodeMessage[T](conn, nMsg)
+			select {
+			case <-tracker.stopped():
+				tracker.nak(nMsg)
+
+				continue
+			default:
+			}
+
+			msg := natsDecodeMessage[T](conn, nMsg).(*NATSMessage[T])
+			msg.tracker = tracker
 
 			select {
 			case msgCh <- msg:
-			case <-ctx.Done():
+			case <-tracker.stopped():
+				tracker.nak(nMsg)
+			}
+		}
+
+		// Fetching has stopped, release any messages which were never received by the subscriber.
+		for {
+			select {
+			case msg := <-msgCh:
+				if err := msg.Nak(0); err != nil {
+					conn.logger.Warnw("error nacking unprocessed message", "nats.subject", msg.Topic(), "error", err)
+				}
+			default:
 				return
 			}
 		}
@@ -34,18 +55,22 @@ func natsSubscriptionMessageChan[T any](ctx context.Context, conn *NATSConnectio
 	return msgCh
 }
 
-func natsSubscriptionAuthRelationshipRequestChan(ctx context.Context, conn *NATSConnection, batchSize int, natsCh <-chan *nats.Msg) chan Request[AuthRelationshipRequest, AuthRelationshipResponse] {
-	msgCh := make(chan Request[AuthRelationshipRequest, AuthRelationshipResponse], batchSize)
+func natsSubscriptionRequestChan[T any](
+	ctx context.Context,
+	conn *NATSConnection,
+	batchSize int,
+	natsCh <-chan *nats.Msg,
+	newRequest func(msg *NATSMessage[T]) Request[T, AuthRelationshipResponse],
+) chan Request[T, AuthRelationshipResponse] {
+	msgCh := make(chan Request[T, AuthRelationshipResponse], batchSize)
 
 	go func() {
 		defer close(msgCh)
 
 		for nMsg := range natsCh {
-			msg := natsDecodeMessage[AuthRelationshipRequest](conn, nMsg)
+			msg := natsDecodeMessage[T](conn, nMsg)
 
-			req := &NATSAuthRelationshipRequest{
-				NATSMessage: msg.(*NATSMessage[AuthRelationshipRequest]),
-			}
+			req := newRequest(msg.(*NATSMessage[T]))
 
 			select {
 			case msgCh <- req:
@@ -78,6 +103,7 @@ type NATSMessage[T any] struct {
 	conn           *NATSConnection
 	source         *nats.Msg
 	sourceMetadata *nats.MsgMetadata
+	tracker        *natsSubscription
 	message        T
 	err            error
 }
@@ -109,6 +135,16 @@ func (m *NATSMessage[T]) ID() string {
 	return strconv.FormatUint(m.metadata().Sequence.Consumer, base10)
 }
 
+// StreamSequence returns the jetstream stream sequence number of the message.
+func (m *NATSMessage[T]) StreamSequence() uint64 {
+	return m.metadata().Sequence.Stream
+}
+
+// Pending returns the number of messages pending for the consumer after this message.
+func (m *NATSMessage[T]) Pending() uint64 {
+	return m.metadata().NumPending
+}
+
 // Topic returns the nats subject.
 func (m *NATSMessage[T]) Topic() string {
 	return m.source.Subject
@@ -121,16 +157,39 @@ func (m *NATSMessage[T]) Message() T {
 
 // Ack acks the message.
 func (m *NATSMessage[T]) Ack() error {
+	if !m.tracker.claim(m.source) {
+		return ErrMessageAlreadySettled
+	}
+
+	defer m.tracker.release(m.source)
+
 	return m.source.Ack()
 }
 
 // Nak calls a Nak with the provided delay.
 func (m *NATSMessage[T]) Nak(delay time.Duration) error {
+	if !m.tracker.claim(m.source) {
+		return ErrMessageAlreadySettled
+	}
+
+	defer m.tracker.release(m.source)
+
 	return m.source.NakWithDelay(delay)
 }
 
+// InProgress resets the redelivery timer of the message, signaling it is still being processed.
+func (m *NATSMessage[T]) InProgress() error {
+	return m.source.InProgress()
+}
+
 // Term terminates the message from being processed again.
 func (m *NATSMessage[T]) Term() error {
+	if !m.tracker.claim(m.source) {
+		return ErrMessageAlreadySettled
+	}
+
+	defer m.tracker.release(m.source)
+
 	return m.source.Term()
 }
 
@@ -162,24 +221,51 @@ func (m *NATSMessage[T]) publish() error {
 	return m.conn.conn.PublishMsg(m.source)
 }
 
+// request sends the message and waits for a response.
+// When no responders are available the request is retried up to RequestNoRespondersRetries times,
+// waiting RequestNoRespondersBackoff between attempts. Each attempt is limited by RequestTimeout if set.
 func (m *NATSMessage[T]) request(ctx context.Context) (Message[AuthRelationshipResponse], error) {
 	if m.source.Reply == "" {
 		m.source.Reply = m.conn.conn.NewRespInbox()
 	}
 
-	nMsg, err := m.conn.conn.RequestMsgWithContext(ctx, m.source)
-	if err != nil {
+	for attempt := 0; ; attempt++ {
+		nMsg, err := m.requestAttempt(ctx)
+		if err == nil {
+			return natsDecodeMessage[AuthRelationshipResponse](m.conn, nMsg), nil
+		}
+
+		if !errors.Is(err, nats.ErrNoResponders) {
+			return nil, err
+		}
+
 		// ensure we wrap no responder errors with ErrRequestNoResponders.
-		if errors.Is(err, nats.ErrNoResponders) {
-			return nil, fmt.Errorf("%w: %w", ErrRequestNoResponders, err)
+		err = fmt.Errorf("%w: %w", ErrRequestNoResponders, err)
+
+		if attempt >= m.conn.cfg.RequestNoRespondersRetries {
+			return nil, err
 		}
 
-		return nil, err
+		m.conn.logger.Debugw("no responders for request, retrying", "subject", m.source.Subject, "attempt", attempt+1)
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(m.conn.cfg.RequestNoRespondersBackoff):
+		}
 	}
+}
 
-	respMsg := natsDecodeMessage[AuthRelationshipResponse](m.conn, nMsg)
+func (m *NATSMessage[T]) requestAttempt(ctx context.Context) (*nats.Msg, error) {
+	if m.conn.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
 
-	return respMsg, nil
+		ctx, cancel = context.WithTimeout(ctx, m.conn.cfg.RequestTimeout)
+
+		defer cancel()
+	}
+
+	return m.conn.conn.RequestMsgWithContext(ctx, m.source)
 }
 
 var _ Request[AuthRelationshipRequest, AuthRelationshipResponse] = (*NATSAuthRelationshipRequest)(nil)
@@ -191,11 +277,27 @@ type NATSAuthRelationshipRequest struct {
 
 // Reply responds to an AuthRelationshipRequest with an AuthRelationshipResponse.
 func (r *NATSAuthRelationshipRequest) Reply(ctx context.Context, message AuthRelationshipResponse) (Message[AuthRelationshipResponse], error) {
-	ctx, span := r.conn.tracer.Start(ctx, "events.Reply")
+	return natsReplyAuthRelationshipResponse(ctx, r.conn, r.source, message)
+}
+
+var _ Request[AuthRelationshipBatchRequest, AuthRelationshipResponse] = (*NATSAuthRelationshipBatchRequest)(nil)
+
+// NATSAuthRelationshipBatchRequest implements Request for AuthRelationshipBatchRequest / AuthRelationshipResponse
+type NATSAuthRelationshipBatchRequest struct {
+	*NATSMessage[AuthRelationshipBatchRequest]
+}
+
+// Reply responds to an AuthRelationshipBatchRequest with an AuthRelationshipResponse.
+func (r *NATSAuthRelationshipBatchRequest) Reply(ctx context.Context, message AuthRelationshipResponse) (Message[AuthRelationshipResponse], error) {
+	return natsReplyAuthRelationshipResponse(ctx, r.conn, r.source, message)
+}
+
+func natsReplyAuthRelationshipResponse(ctx context.Context, conn *NATSConnection, source *nats.Msg, message AuthRelationshipResponse) (Message[AuthRelationshipResponse], error) {
+	ctx, span := conn.tracer.Start(ctx, "events.Reply")
 
 	defer span.End()
 
-	if r.source.Reply == "" {
+	if source.Reply == "" {
 		span.RecordError(ErrNATSMessageNoReplySubject)
 		span.SetStatus(codes.Error, ErrNATSMessageNoReplySubject.Error())
 
@@ -216,7 +318,7 @@ func (r *NATSAuthRelationshipRequest) Reply(ctx context.Context, message AuthRel
 
 	message.TraceContext = mapCarrier
 
-	respMsg, err := newNATSMessage(r.conn, r.source.Reply, message)
+	respMsg, err := newNATSMessage(conn, source.Reply, message)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -224,7 +326,7 @@ func (r *NATSAuthRelationshipRequest) Reply(ctx context.Context, message AuthRel
 		return nil, err
 	}
 
-	if err := r.source.RespondMsg(respMsg.source); err != nil {
+	if err := source.RespondMsg(respMsg.source); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 
@@ -9,6 +9,7 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 
+	"go.infratographer.com/x/actorx"
 	"go.infratographer.com/x/echojwtx"
 	"go.infratographer.com/x/gidx"
 )
@@ -60,6 +61,53 @@ func (c *NATSConnection) PublishAuthRelationshipRequest(ctx context.Context, top
 	return respMsg, nil
 }
 
+// PublishAuthRelationshipBatchRequest publishes an AuthRelationshipBatchRequest message and blocks until an AuthRelationshipResponse is provided.
+// The response contains a result for each request in the batch, see AuthRelationshipResponse.FailedResults.
+func (c *NATSConnection) PublishAuthRelationshipBatchRequest(ctx context.Context, topic string, message AuthRelationshipBatchRequest) (Message[AuthRelationshipResponse], error) {
+	ctx, span := c.tracer.Start(ctx, "events.nats.PublishAuthRelationshipBatchRequest", trace.WithAttributes(
+		attribute.String("events.subject_type", topic),
+		attribute.Int("events.batch_size", len(message.Requests)),
+	))
+
+	defer span.End()
+
+	if err := message.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
+	// Propagate trace context into the message for the subscriber
+	var mapCarrier propagation.MapCarrier = make(map[string]string)
+
+	otel.GetTextMapPropagator().Inject(ctx, mapCarrier)
+
+	message.TraceContext = mapCarrier
+
+	topic = c.buildPublishSubject("auth", "relationship-batches", topic)
+
+	reqMsg, err := newNATSMessage(c, topic, message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
+	c.logger.Debugf("publishing auth relation batch request message to topic %s", topic)
+
+	respMsg, err := reqMsg.request(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
+	return respMsg, nil
+}
+
 // PublishChange publishes a ChangeMessage.
 func (c *NATSConnection) PublishChange(ctx context.Context, topic string, message ChangeMessage) (Message[ChangeMessage], error) {
 	ctx, span := c.tracer.Start(ctx, "events.nats.PublishChange", trace.WithAttributes(
@@ -85,17 +133,19 @@ func (c *NATSConnection) PublishChange(ctx context.Context, topic string, messag
 
 	message.TraceContext = mapCarrier
 
+	if err := c.limiter.wait(ctx, topic, publishSource(ctx, c.cfg.Source)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
 	topic = c.buildPublishSubject("changes", message.EventType, topic)
 
 	message.Source = c.cfg.Source
 
 	if message.ActorID == gidx.NullPrefixedID {
-		id, ok := ctx.Value(echojwtx.ActorCtxKey).(string)
-		if ok {
-			message.ActorID = gidx.PrefixedID(id)
-		} else {
-			message.ActorID = "unknown-actor"
-		}
+		message.ActorID = contextActor(ctx).ID()
 	}
 
 	span.SetAttributes(
@@ -150,6 +200,13 @@ func (c *NATSConnection) PublishEvent(ctx context.Context, topic string, message
 
 	message.TraceContext = mapCarrier
 
+	if err := c.limiter.wait(ctx, topic, publishSource(ctx, message.Source, c.cfg.Source)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
 	topic = c.buildPublishSubject("events", message.EventType, topic)
 
 	msg, err := newNATSMessage(c, topic, message)
@@ -171,3 +228,18 @@ func (c *NATSConnection) PublishEvent(ctx context.Context, topic string, message
 
 	return msg, nil
 }
+
+// contextActor returns the actor stored in the context, falling back to a subject stored with
+// echojwtx.ActorCtxKey for contexts which don't include a structured actor. If neither are found
+// an anonymous actor is returned.
+func contextActor(ctx context.Context) *actorx.Actor {
+	if actor, ok := actorx.FromContext(ctx); ok {
+		return actor
+	}
+
+	if id, ok := ctx.Value(echojwtx.ActorCtxKey).(string); ok {
+		return actorx.New(id, actorx.KindUser)
+	}
+
+	return actorx.Anonymous()
+}
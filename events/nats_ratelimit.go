@@ -0,0 +1,262 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// NATSPublishRateLimitModeBlock waits for the rate limit to allow the publish, up to the context deadline.
+	NATSPublishRateLimitModeBlock = "block"
+	// NATSPublishRateLimitModeFail returns a PublishRateLimitError immediately when the rate limit is exceeded.
+	NATSPublishRateLimitModeFail = "fail"
+)
+
+// PublishRateLimitError is returned when a publish is rejected by the publish rate limit.
+// It matches ErrPublishRateLimited with errors.Is.
+type PublishRateLimitError struct {
+	// Topic is the topic the message was published to.
+	Topic string
+	// Source is the publish source the rate limit was applied to.
+	Source string
+	// RetryAfter is the time until the rate limit would allow the publish.
+	RetryAfter time.Duration
+	// Err is the context error when a blocked publish was canceled before the rate limit allowed it.
+	Err error
+}
+
+// Error implements error.
+func (e *PublishRateLimitError) Error() string {
+	msg := fmt.Sprintf("%s: topic %s, source %s, retry after %s", ErrPublishRateLimited, e.Topic, e.Source, e.RetryAfter)
+
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+// Unwrap returns ErrPublishRateLimited and the context error if the publish was canceled.
+func (e *PublishRateLimitError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{ErrPublishRateLimited, e.Err}
+	}
+
+	return []error{ErrPublishRateLimited}
+}
+
+type publishSourceContext struct{}
+
+// NewPublishSourceContext returns a new context with the publish source stored in it.
+// Publish rate limits are applied to each topic and source separately, allowing a publisher,
+// such as a bulk import, to be limited without throttling other publishers of the same topic.
+// Without a source in the context, the message source or the connection source is used.
+func NewPublishSourceContext(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, publishSourceContext{}, source)
+}
+
+// PublishSourceFromContext returns the publish source stored in the context.
+func PublishSourceFromContext(ctx context.Context) (string, bool) {
+	source, ok := ctx.Value(publishSourceContext{}).(string)
+
+	return source, ok && source != ""
+}
+
+// publishSource returns the publish source from the context, falling back to the first non empty source provided.
+func publishSource(ctx context.Context, sources ...string) string {
+	if source, ok := PublishSourceFromContext(ctx); ok {
+		return source
+	}
+
+	for _, source := range sources {
+		if source != "" {
+			return source
+		}
+	}
+
+	return ""
+}
+
+type publishLimiterKey struct {
+	topic  string
+	source string
+}
+
+// natsPublishLimiter applies a token bucket rate limit to each publish topic and source.
+type natsPublishLimiter struct {
+	mode         string
+	limit        float64
+	burst        int
+	topicLimits  map[string]float64
+	sourceLimits map[string]float64
+
+	mu       sync.Mutex
+	limiters map[publishLimiterKey]*rate.Limiter
+
+	throttled    metric.Int64Counter
+	rejected     metric.Int64Counter
+	throttleTime metric.Float64Histogram
+}
+
+// newNATSPublishLimiter returns a limiter for the config, nil is returned if no rate limits are configured.
+func newNATSPublishLimiter(cfg NATSConfig) (*natsPublishLimiter, error) {
+	if cfg.PublishRateLimit == 0 && len(cfg.PublishTopicRateLimits) == 0 && len(cfg.PublishSourceRateLimits) == 0 {
+		return nil, nil
+	}
+
+	meter := otel.GetMeterProvider().Meter(natsTracerName)
+
+	throttled, err := meter.Int64Counter("events.nats.publish.throttled",
+		metric.WithDescription("Number of publishes delayed by the publish rate limit"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, err := meter.Int64Counter("events.nats.publish.rate_limited",
+		metric.WithDescription("Number of publishes rejected by the publish rate limit"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	throttleTime, err := meter.Float64Histogram("events.nats.publish.throttle_duration",
+		metric.WithDescription("Time publishes were delayed by the publish rate limit"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsPublishLimiter{
+		mode:         cfg.PublishRateLimitMode,
+		limit:        cfg.PublishRateLimit,
+		burst:        cfg.PublishRateBurst,
+		topicLimits:  cfg.PublishTopicRateLimits,
+		sourceLimits: cfg.PublishSourceRateLimits,
+		limiters:     make(map[publishLimiterKey]*rate.Limiter),
+		throttled:    throttled,
+		rejected:     rejected,
+		throttleTime: throttleTime,
+	}, nil
+}
+
+// limiter returns the limiter for the topic and source, nil is returned if they are not rate limited.
+// Topic rate limits take precedence over source rate limits, which take precedence over the default rate limit.
+func (l *natsPublishLimiter) limiter(topic, source string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := publishLimiterKey{topic: topic, source: source}
+
+	if limiter, ok := l.limiters[key]; ok {
+		return limiter
+	}
+
+	limit, ok := l.topicLimits[topic]
+	if !ok {
+		limit, ok = l.sourceLimits[source]
+	}
+
+	if !ok {
+		limit = l.limit
+	}
+
+	var limiter *rate.Limiter
+
+	if limit > 0 {
+		burst := l.burst
+		if burst <= 0 {
+			burst = int(math.Max(1, math.Ceil(limit)))
+		}
+
+		limiter = rate.NewLimiter(rate.Limit(limit), burst)
+	}
+
+	l.limiters[key] = limiter
+
+	return limiter
+}
+
+// wait blocks until the topic and source rate limit allows a publish.
+// In fail mode, or when the wait would exceed the context deadline or the context is canceled while waiting,
+// a PublishRateLimitError is returned instead. A nil limiter never limits.
+func (l *natsPublishLimiter) wait(ctx context.Context, topic, source string) error {
+	if l == nil {
+		return nil
+	}
+
+	limiter := l.limiter(topic, source)
+	if limiter == nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return l.reject(ctx, topic, source, rate.InfDuration, nil)
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+
+	if l.mode == NATSPublishRateLimitModeFail {
+		reservation.CancelAt(now)
+
+		return l.reject(ctx, topic, source, delay, nil)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < delay {
+		reservation.CancelAt(now)
+
+		return l.reject(ctx, topic, source, delay, nil)
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("events.topic", topic),
+		attribute.String("events.source", source),
+	)
+
+	l.throttled.Add(ctx, 1, attrs)
+
+	timer := time.NewTimer(delay)
+
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		l.throttleTime.Record(ctx, time.Since(now).Seconds(), attrs)
+
+		return nil
+	case <-ctx.Done():
+		reservation.Cancel()
+
+		return l.reject(ctx, topic, source, delay-time.Since(now), ctx.Err())
+	}
+}
+
+func (l *natsPublishLimiter) reject(ctx context.Context, topic, source string, retryAfter time.Duration, err error) error {
+	l.rejected.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("events.topic", topic),
+		attribute.String("events.source", source),
+		attribute.String("events.rate_limit_mode", l.mode),
+	))
+
+	return &PublishRateLimitError{
+		Topic:      topic,
+		Source:     source,
+		RetryAfter: max(retryAfter, 0),
+		Err:        err,
+	}
+}
@@ -53,7 +53,7 @@ func (c *NATSConnection) coreSubscribe(ctx context.Context, subject string) (<-c
 	return msgCh, nil
 }
 
-func (c *NATSConnection) jsSubscribe(ctx context.Context, subject string) (<-chan *nats.Msg, error) {
+func (c *NATSConnection) jsSubscribe(ctx context.Context, subject string) (*natsSubscription, <-chan *nats.Msg, error) {
 	durableName := c.durableName(subject)
 
 	logger := c.logger.With(
@@ -64,14 +64,18 @@ func (c *NATSConnection) jsSubscribe(ctx context.Context, subject string) (<-cha
 
 	sub, err := c.jetstream.PullSubscribe(subject, durableName, c.cfg.subscribeOptions...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	tracker := c.addSubscription(ctx, logger)
+
 	msgCh := make(chan *nats.Msg, c.cfg.SubscriberFetchBatchSize)
 
 	go func() {
+		defer c.removeSubscription(tracker)
+
 		for {
-			if err := c.fetchMessages(ctx, sub, msgCh); err != nil {
+			if err := c.fetchMessages(tracker, sub, msgCh); err != nil && tracker.ctx.Err() == nil {
 				if errors.Is(err, context.DeadlineExceeded) {
 					continue
 				}
@@ -79,13 +83,13 @@ func (c *NATSConnection) jsSubscribe(ctx context.Context, subject string) (<-cha
 				logger.Errorw("error fetching messages", "error", err)
 
 				select {
-				case <-ctx.Done():
+				case <-tracker.stopped():
 				case <-time.After(c.cfg.SubscriberFetchBackoff):
 				}
 			}
 
 			select {
-			case <-ctx.Done():
+			case <-tracker.stopped():
 				close(msgCh)
 
 				if err := sub.Unsubscribe(); err != nil {
@@ -98,11 +102,11 @@ func (c *NATSConnection) jsSubscribe(ctx context.Context, subject string) (<-cha
 		}
 	}()
 
-	return msgCh, nil
+	return tracker, msgCh, nil
 }
 
-func (c *NATSConnection) fetchMessages(ctx context.Context, sub *nats.Subscription, msgCh chan<- *nats.Msg) error {
-	ctx, cancel := context.WithTimeout(ctx, c.cfg.SubscriberFetchTimeout)
+func (c *NATSConnection) fetchMessages(tracker *natsSubscription, sub *nats.Subscription, msgCh chan<- *nats.Msg) error {
+	ctx, cancel := context.WithTimeout(tracker.ctx, c.cfg.SubscriberFetchTimeout)
 
 	defer cancel()
 
@@ -112,10 +116,20 @@ func (c *NATSConnection) fetchMessages(ctx context.Context, sub *nats.Subscripti
 	}
 
 	for msg := range batch.Messages() {
+		tracker.track(msg)
+
 		select {
 		case msgCh <- msg:
-		case <-ctx.Done():
-			return ctx.Err()
+		case <-tracker.stopped():
+			tracker.nak(msg)
+
+			// Release the remainder of the batch so it may be redelivered immediately.
+			for msg := range batch.Messages() {
+				tracker.track(msg)
+				tracker.nak(msg)
+			}
+
+			return tracker.ctx.Err()
 		}
 	}
 
@@ -152,33 +166,52 @@ func (c *NATSConnection) SubscribeAuthRelationshipRequests(ctx context.Context,
 
 	c.logger.Debugf("subscribing to auth relation request message on topic %s", topic)
 
-	return natsSubscriptionAuthRelationshipRequestChan(ctx, c, c.cfg.SubscriberFetchBatchSize, natsCh), nil
+	return natsSubscriptionRequestChan(ctx, c, c.cfg.SubscriberFetchBatchSize, natsCh, func(msg *NATSMessage[AuthRelationshipRequest]) Request[AuthRelationshipRequest, AuthRelationshipResponse] {
+		return &NATSAuthRelationshipRequest{NATSMessage: msg}
+	}), nil
+}
+
+// SubscribeAuthRelationshipBatchRequests creates a new subscription parsing incoming messages as AuthRelationshipBatchRequest messages and returning a new Message channel.
+// Batch requests are published on a separate subject from single requests so existing subscribers do not receive them.
+func (c *NATSConnection) SubscribeAuthRelationshipBatchRequests(ctx context.Context, topic string) (<-chan Request[AuthRelationshipBatchRequest, AuthRelationshipResponse], error) {
+	topic = c.buildSubscribeSubject("auth", "relationship-batches", topic)
+
+	natsCh, err := c.coreSubscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Debugf("subscribing to auth relation batch request message on topic %s", topic)
+
+	return natsSubscriptionRequestChan(ctx, c, c.cfg.SubscriberFetchBatchSize, natsCh, func(msg *NATSMessage[AuthRelationshipBatchRequest]) Request[AuthRelationshipBatchRequest, AuthRelationshipResponse] {
+		return &NATSAuthRelationshipBatchRequest{NATSMessage: msg}
+	}), nil
 }
 
 // SubscribeChanges creates a new pull subscription parsing incoming messages as ChangeMessage messages and returning a new Message channel.
 func (c *NATSConnection) SubscribeChanges(ctx context.Context, topic string) (<-chan Message[ChangeMessage], error) {
 	topic = c.buildSubscribeSubject("changes", topic)
 
-	natsCh, err := c.jsSubscribe(ctx, topic)
+	tracker, natsCh, err := c.jsSubscribe(ctx, topic)
 	if err != nil {
 		return nil, err
 	}
 
 	c.logger.Debugf("subscribing to changes message on topic %s", topic)
 
-	return natsSubscriptionMessageChan[ChangeMessage](ctx, c, c.cfg.SubscriberFetchBatchSize, natsCh), nil
+	return natsSubscriptionMessageChan[ChangeMessage](c, tracker, c.cfg.SubscriberFetchBatchSize, natsCh), nil
 }
 
 // SubscribeEvents creates a new pull subscription parsing incoming messages as EventMessage messages and returning a new Message channel.
 func (c *NATSConnection) SubscribeEvents(ctx context.Context, topic string) (<-chan Message[EventMessage], error) {
 	topic = c.buildSubscribeSubject("events", topic)
 
-	natsCh, err := c.jsSubscribe(ctx, topic)
+	tracker, natsCh, err := c.jsSubscribe(ctx, topic)
 	if err != nil {
 		return nil, err
 	}
 
 	c.logger.Debugf("subscribing to events message on topic %s", topic)
 
-	return natsSubscriptionMessageChan[EventMessage](ctx, c, c.cfg.SubscriberFetchBatchSize, natsCh), nil
+	return natsSubscriptionMessageChan[EventMessage](c, tracker, c.cfg.SubscriberFetchBatchSize, natsCh), nil
 }
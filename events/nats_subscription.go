@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// natsSubscription tracks a jetstream subscription and the messages it has fetched
+// which have not yet been acked, nacked or terminated.
+type natsSubscription struct {
+	logger *zap.SugaredLogger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	inFlight map[*nats.Msg]bool
+	released chan struct{}
+}
+
+func newNATSSubscription(ctx context.Context, logger *zap.SugaredLogger) *natsSubscription {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &natsSubscription{
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		inFlight: make(map[*nats.Msg]bool),
+		released: make(chan struct{}, 1),
+	}
+}
+
+// stopped returns a channel which is closed once the subscription should no longer fetch messages.
+func (s *natsSubscription) stopped() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// stop signals the subscription to stop fetching new messages.
+func (s *natsSubscription) stop() {
+	s.cancel()
+}
+
+// track marks the message as in-flight.
+func (s *natsSubscription) track(msg *nats.Msg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight[msg] = false
+}
+
+// claim marks the in-flight message as being settled and reports whether the caller may ack, nak or term it.
+// A message which was already claimed or released, for example one nacked during shutdown, returns false.
+func (s *natsSubscription) claim(msg *nats.Msg) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claimed, ok := s.inFlight[msg]
+	if !ok || claimed {
+		return false
+	}
+
+	s.inFlight[msg] = true
+
+	return true
+}
+
+// release removes the message from the in-flight messages.
+func (s *natsSubscription) release(msg *nats.Msg) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.inFlight, msg)
+	s.mu.Unlock()
+
+	select {
+	case s.released <- struct{}{}:
+	default:
+	}
+}
+
+// nak nacks the message without a delay so it may be redelivered immediately and releases it.
+// Messages which have already been claimed are skipped.
+func (s *natsSubscription) nak(msg *nats.Msg) {
+	if !s.claim(msg) {
+		return
+	}
+
+	defer s.release(msg)
+
+	if err := msg.Nak(); err != nil {
+		s.logger.Warnw("error nacking unprocessed message", "nats.subject", msg.Subject, "error", err)
+	}
+}
+
+// wait blocks until all in-flight messages have been released or the context is done.
+func (s *natsSubscription) wait(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		remaining := len(s.inFlight)
+		s.mu.Unlock()
+
+		if remaining == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.released:
+		}
+	}
+}
+
+// nakInFlight nacks all messages which are still in-flight and have not been claimed.
+func (s *natsSubscription) nakInFlight() {
+	s.mu.Lock()
+
+	msgs := make([]*nats.Msg, 0, len(s.inFlight))
+
+	for msg, claimed := range s.inFlight {
+		if !claimed {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	s.mu.Unlock()
+
+	for _, msg := range msgs {
+		s.nak(msg)
+	}
+}
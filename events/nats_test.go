@@ -13,12 +13,17 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go.infratographer.com/x/actorx"
+	"go.infratographer.com/x/echojwtx"
 	"go.infratographer.com/x/events"
 	"go.infratographer.com/x/gidx"
 	"go.infratographer.com/x/testing/eventtools"
 )
 
-var errTimeout = errors.New("timeout waiting for event")
+var (
+	errTimeout       = errors.New("timeout waiting for event")
+	errHandlerFailed = errors.New("handler failed")
+)
 
 func TestNATSPublishAndSubscribe(t *testing.T) {
 	ctx := context.Background()
@@ -70,6 +75,16 @@ func TestNATSPublishAndSubscribe(t *testing.T) {
 			require.NoError(t, err)
 			require.NotEqual(t, change3, msg.Message())
 
+			change4 := testCreateChange()
+			change4.ActorID = ""
+
+			actorID := gidx.MustNewID("idntusr")
+			actorCtx := actorx.NewContext(ctx, &actorx.Actor{Subject: actorID, Kind: actorx.KindUser})
+
+			msg, err = conn.PublishChange(actorCtx, "test", change4)
+			require.NoError(t, err)
+			require.Equal(t, actorID, msg.Message().ActorID)
+
 			messages, err := conn.SubscribeChanges(ctx, ">")
 			require.NoError(t, err)
 
@@ -88,12 +103,244 @@ func TestNATSPublishAndSubscribe(t *testing.T) {
 			require.NoError(t, err)
 			require.NoError(t, receivedMsg.Error())
 			assert.NotEqualValues(t, change3, receivedMsg.Message())
-			assert.Equal(t, "unknown-actor", receivedMsg.Message().ActorID.String())
+			assert.Equal(t, actorx.AnonymousSubject, receivedMsg.Message().ActorID)
+			assert.NoError(t, receivedMsg.Ack())
+
+			receivedMsg, err = getSingleMessage(messages, time.Second*1)
+			require.NoError(t, err)
+			require.NoError(t, receivedMsg.Error())
+			assert.Equal(t, actorID, receivedMsg.Message().ActorID)
 			assert.NoError(t, receivedMsg.Ack())
 		})
 	}
 }
 
+func TestNATSPublishAnonymousStrict(t *testing.T) {
+	ctx := context.Background()
+
+	previous := gidx.CurrentIDPartValidationMode()
+
+	gidx.SetIDPartValidationMode(gidx.IDPartValidationStrict)
+
+	t.Cleanup(func() {
+		gidx.SetIDPartValidationMode(previous)
+	})
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	conn, err := events.NewNATSConnection(nats.Config.NATS)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	messages, err := conn.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	change := testCreateChange()
+	change.ActorID = ""
+
+	_, err = conn.PublishChange(ctx, "test", change)
+	require.NoError(t, err)
+
+	receivedMsg, err := getSingleMessage(messages, time.Second*1)
+	require.NoError(t, err)
+	require.NoError(t, receivedMsg.Error())
+	assert.Equal(t, actorx.AnonymousSubject, receivedMsg.Message().ActorID)
+	assert.NoError(t, receivedMsg.Ack())
+}
+
+func TestNATSPublishNonPrefixedIDSubject(t *testing.T) {
+	ctx := context.Background()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	conn, err := events.NewNATSConnection(nats.Config.NATS)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	messages, err := conn.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	contexts := []context.Context{
+		actorx.NewContext(ctx, actorx.FromClaims(map[string]any{"sub": "auth0|123"}, "")),
+		context.WithValue(ctx, echojwtx.ActorCtxKey, "spiffe://example.org/ns/default/sa/api"),
+	}
+
+	for _, actorCtx := range contexts {
+		change := testCreateChange()
+		change.ActorID = ""
+
+		_, err = conn.PublishChange(actorCtx, "test", change)
+		require.NoError(t, err)
+
+		receivedMsg, err := getSingleMessage(messages, time.Second*1)
+		require.NoError(t, err)
+		require.NoError(t, receivedMsg.Error())
+		assert.Equal(t, actorx.AnonymousSubject, receivedMsg.Message().ActorID)
+		assert.NoError(t, receivedMsg.Ack())
+	}
+}
+
+func TestNATSShutdownNaksUnprocessed(t *testing.T) {
+	ctx := context.Background()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	natsCfg := nats.Config.NATS
+	natsCfg.QueueGroup = "testing-shutdown"
+	natsCfg.SubscriberFetchBatchSize = 5
+	natsCfg.ShutdownTimeout = 2 * time.Second
+
+	// Create the durable consumer ahead of time so it is not removed when the first connection unsubscribes.
+	subject := eventtools.Prefix + ".changes.>"
+
+	_, err = nats.JetStream.AddConsumer("events-tests", &nc.ConsumerConfig{
+		Durable:       events.NATSConsumerDurableName(natsCfg.QueueGroup, subject),
+		FilterSubject: subject,
+		AckPolicy:     nc.AckExplicitPolicy,
+	})
+	require.NoError(t, err)
+
+	conn, err := events.NewNATSConnection(natsCfg)
+	require.NoError(t, err)
+
+	changes := make(map[gidx.PrefixedID]events.ChangeMessage)
+
+	for range 5 {
+		change := testCreateChange()
+
+		_, err := conn.PublishChange(ctx, "test", change)
+		require.NoError(t, err)
+
+		changes[change.SubjectID] = change
+	}
+
+	messages, err := conn.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	inFlight, err := getSingleMessage(messages, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, inFlight.Error())
+
+	delete(changes, inFlight.Message().SubjectID)
+
+	shutdownDone := make(chan struct{})
+
+	go func() {
+		defer close(shutdownDone)
+
+		conn.Shutdown(ctx) //nolint:errcheck // within test
+	}()
+
+	// Shutdown must wait for the in-flight message to be processed.
+	select {
+	case <-shutdownDone:
+		t.Fatal("shutdown completed before in-flight message was processed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, inFlight.Ack())
+
+	select {
+	case <-shutdownDone:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown did not complete after in-flight message was processed")
+	}
+
+	conn2, err := events.NewNATSConnection(natsCfg)
+	require.NoError(t, err)
+
+	defer conn2.Shutdown(ctx) //nolint:errcheck // within test
+
+	messages, err = conn2.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	// Unprocessed messages are nacked and should be redelivered well before the ack wait expires.
+	for range len(changes) {
+		receivedMsg, err := getSingleMessage(messages, time.Second*2)
+		require.NoError(t, err)
+		require.NoError(t, receivedMsg.Error())
+
+		expected, ok := changes[receivedMsg.Message().SubjectID]
+		require.True(t, ok, "unexpected message received")
+		assert.EqualValues(t, expected.FieldChanges, receivedMsg.Message().FieldChanges)
+		assert.NoError(t, receivedMsg.Ack())
+	}
+}
+
+func TestNATSShutdownTimeoutNaksInFlight(t *testing.T) {
+	ctx := context.Background()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	natsCfg := nats.Config.NATS
+	natsCfg.QueueGroup = "testing-shutdown-timeout"
+	natsCfg.SubscriberFetchBatchSize = 1
+	natsCfg.ShutdownTimeout = 200 * time.Millisecond
+
+	subject := eventtools.Prefix + ".changes.>"
+
+	_, err = nats.JetStream.AddConsumer("events-tests", &nc.ConsumerConfig{
+		Durable:       events.NATSConsumerDurableName(natsCfg.QueueGroup, subject),
+		FilterSubject: subject,
+		AckPolicy:     nc.AckExplicitPolicy,
+	})
+	require.NoError(t, err)
+
+	conn, err := events.NewNATSConnection(natsCfg)
+	require.NoError(t, err)
+
+	change := testCreateChange()
+
+	_, err = conn.PublishChange(ctx, "test", change)
+	require.NoError(t, err)
+
+	messages, err := conn.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	inFlight, err := getSingleMessage(messages, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, inFlight.Error())
+
+	// The in-flight message is not processed before the shutdown timeout, so it is nacked during shutdown
+	// and the whole shutdown is bound by the single timeout.
+	start := time.Now()
+
+	assert.ErrorIs(t, conn.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*natsCfg.ShutdownTimeout)
+
+	// The message was already nacked so the late ack is skipped.
+	assert.ErrorIs(t, inFlight.Ack(), events.ErrMessageAlreadySettled)
+
+	conn2, err := events.NewNATSConnection(natsCfg)
+	require.NoError(t, err)
+
+	defer conn2.Shutdown(ctx) //nolint:errcheck // within test
+
+	messages, err = conn2.SubscribeChanges(ctx, ">")
+	require.NoError(t, err)
+
+	receivedMsg, err := getSingleMessage(messages, time.Second*2)
+	require.NoError(t, err)
+	require.NoError(t, receivedMsg.Error())
+
+	assert.Equal(t, change.SubjectID, receivedMsg.Message().SubjectID)
+	assert.NoError(t, receivedMsg.Ack())
+}
+
 func TestNATSRequestReply(t *testing.T) {
 	ctx := context.Background()
 	nats, err := eventtools.NewNatsServer()
@@ -195,6 +442,208 @@ func TestNATSRequestReply(t *testing.T) {
 
 	close(respGot)
 }
+
+func TestNATSBatchRequestReply(t *testing.T) {
+	ctx := context.Background()
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	config := nats.Config.NATS
+	config.RequestNoRespondersRetries = 20
+	config.RequestNoRespondersBackoff = 100 * time.Millisecond
+
+	conn, err := events.NewNATSConnection(config)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	_, err = conn.PublishAuthRelationshipBatchRequest(ctx, "test", events.AuthRelationshipBatchRequest{})
+	require.ErrorIs(t, err, events.ErrMissingAuthRelationshipBatchRequests)
+
+	batch := events.AuthRelationshipBatchRequest{
+		Requests: []events.AuthRelationshipRequest{
+			{
+				Action:   events.WriteAuthRelationshipAction,
+				ObjectID: gidx.PrefixedID("prntobj-abc123"),
+				Relations: []events.AuthRelationshipRelation{
+					{Relation: "owner", SubjectID: gidx.PrefixedID("chldobj-abc123")},
+				},
+			},
+			{
+				Action:   events.WriteAuthRelationshipAction,
+				ObjectID: gidx.PrefixedID("prntobj-def456"),
+			},
+			{
+				Action:   events.DeleteAuthRelationshipAction,
+				ObjectID: gidx.PrefixedID("prntobj-ghi789"),
+				Relations: []events.AuthRelationshipRelation{
+					{Relation: "owner", SubjectID: gidx.PrefixedID("chldobj-ghi789")},
+				},
+			},
+		},
+	}
+
+	handled := make(chan gidx.PrefixedID, len(batch.Requests))
+
+	go func() {
+		ctx, cancel := context.WithCancel(ctx)
+
+		defer cancel()
+
+		// Subscribe after the request is published to ensure no responder errors are retried.
+		time.Sleep(300 * time.Millisecond)
+
+		msgs, err := conn.SubscribeAuthRelationshipBatchRequests(ctx, "test")
+		assert.NoError(t, err)
+
+		select {
+		case reqMsg := <-msgs:
+			_, err := events.ReplyAuthRelationshipBatch(ctx, reqMsg, func(_ context.Context, req events.AuthRelationshipRequest) error {
+				handled <- req.ObjectID
+
+				if req.Action == events.DeleteAuthRelationshipAction {
+					return errHandlerFailed
+				}
+
+				return nil
+			})
+			assert.NoError(t, err)
+		case <-time.After(time.Second * 5):
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+
+	defer cancel()
+
+	resp, err := conn.PublishAuthRelationshipBatchRequest(ctx, "test", batch)
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+
+	response := resp.Message()
+
+	assert.Empty(t, response.Errors)
+	require.Len(t, response.Results, 3)
+
+	assert.Equal(t, gidx.PrefixedID("prntobj-abc123"), response.Results[0].ObjectID)
+	assert.Empty(t, response.Results[0].Errors)
+
+	assert.Equal(t, gidx.PrefixedID("prntobj-def456"), response.Results[1].ObjectID)
+	require.Len(t, response.Results[1].Errors, 1)
+	assert.Equal(t, events.ErrMissingAuthRelationshipRequestRelation.Error(), response.Results[1].Errors[0].Error())
+
+	assert.Equal(t, gidx.PrefixedID("prntobj-ghi789"), response.Results[2].ObjectID)
+	require.Len(t, response.Results[2].Errors, 1)
+	assert.Equal(t, errHandlerFailed.Error(), response.Results[2].Errors[0].Error())
+
+	assert.Len(t, response.FailedResults(), 2)
+
+	close(handled)
+
+	var handledIDs []gidx.PrefixedID
+
+	for id := range handled {
+		handledIDs = append(handledIDs, id)
+	}
+
+	assert.Equal(t, []gidx.PrefixedID{"prntobj-abc123", "prntobj-ghi789"}, handledIDs)
+}
+
+type singleAuthRelationshipPublisher struct{}
+
+func (singleAuthRelationshipPublisher) PublishAuthRelationshipRequest(context.Context, string, events.AuthRelationshipRequest) (events.Message[events.AuthRelationshipResponse], error) {
+	return nil, nil
+}
+
+func TestAuthRelationshipBatchPublish(t *testing.T) {
+	ctx := context.Background()
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	conn, err := events.NewNATSConnection(nats.Config.NATS)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	_, ok := events.AuthRelationshipBatchFromContext(ctx)
+	assert.False(t, ok)
+
+	batchCtx, batch := events.NewAuthRelationshipBatchContext(ctx)
+
+	fromCtx, ok := events.AuthRelationshipBatchFromContext(batchCtx)
+	require.True(t, ok)
+	assert.Same(t, batch, fromCtx)
+
+	// An empty batch does not publish anything.
+	require.NoError(t, batch.Publish(ctx, singleAuthRelationshipPublisher{}))
+
+	for _, id := range []gidx.PrefixedID{"prntobj-abc123", "prntobj-def456"} {
+		batch.Add("test", events.AuthRelationshipRequest{
+			Action:   events.WriteAuthRelationshipAction,
+			ObjectID: id,
+			Relations: []events.AuthRelationshipRelation{
+				{Relation: "owner", SubjectID: gidx.PrefixedID("chldobj-abc123")},
+			},
+		})
+	}
+
+	batch.Add("test", events.AuthRelationshipRequest{
+		Action:   events.DeleteAuthRelationshipAction,
+		ObjectID: gidx.PrefixedID("prntobj-ghi789"),
+		Relations: []events.AuthRelationshipRelation{
+			{Relation: "owner", SubjectID: gidx.PrefixedID("chldobj-ghi789")},
+		},
+	})
+
+	assert.Equal(t, 3, batch.Len())
+
+	msgs, err := conn.SubscribeAuthRelationshipBatchRequests(ctx, "test")
+	require.NoError(t, err)
+
+	received := make(chan int, 1)
+
+	go func() {
+		select {
+		case reqMsg := <-msgs:
+			received <- len(reqMsg.Message().Requests)
+
+			_, err := events.ReplyAuthRelationshipBatch(ctx, reqMsg, func(_ context.Context, req events.AuthRelationshipRequest) error {
+				if req.Action == events.DeleteAuthRelationshipAction {
+					return errHandlerFailed
+				}
+
+				return nil
+			})
+			assert.NoError(t, err)
+		case <-time.After(time.Second * 5):
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+
+	defer cancel()
+
+	err = batch.Publish(ctx, conn)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "prntobj-ghi789")
+	assert.ErrorContains(t, err, errHandlerFailed.Error())
+
+	// All the requests for the topic are sent with a single batch request.
+	assert.Equal(t, 3, <-received)
+	assert.Equal(t, 0, batch.Len())
+
+	batch.Add("test", events.AuthRelationshipRequest{
+		Action:   events.WriteAuthRelationshipAction,
+		ObjectID: gidx.PrefixedID("prntobj-abc123"),
+	})
+
+	assert.ErrorIs(t, batch.Publish(ctx, singleAuthRelationshipPublisher{}), events.ErrAuthRelationshipBatchUnsupported)
+}
+
 func TestNATSRequestReplyMarshalling(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -363,3 +812,124 @@ func testChange(eventType string) events.ChangeMessage {
 func testCreateChange() events.ChangeMessage {
 	return testChange("create")
 }
+
+func TestNATSPublishRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	change := events.ChangeMessage{
+		SubjectID: gidx.PrefixedID("testing-abc123"),
+		EventType: string(events.CreateChangeType),
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		config := nats.Config.NATS
+		config.PublishRateLimit = 1
+		config.PublishRateLimitMode = events.NATSPublishRateLimitModeFail
+		config.PublishTopicRateLimits = map[string]float64{"unlimited": 0}
+		config.PublishSourceRateLimits = map[string]float64{"unlimited-source": 0}
+
+		conn, err := events.NewNATSConnection(config)
+		require.NoError(t, err)
+
+		defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+		_, err = conn.PublishChange(ctx, "limited", change)
+		require.NoError(t, err)
+
+		_, err = conn.PublishChange(ctx, "limited", change)
+		require.ErrorIs(t, err, events.ErrPublishRateLimited)
+
+		var rateErr *events.PublishRateLimitError
+
+		require.ErrorAs(t, err, &rateErr)
+		assert.Equal(t, "limited", rateErr.Topic)
+		assert.Equal(t, config.Source, rateErr.Source)
+		assert.Greater(t, rateErr.RetryAfter, time.Duration(0))
+
+		// Sources are limited independently.
+		importCtx := events.NewPublishSourceContext(ctx, "importer")
+
+		_, err = conn.PublishChange(importCtx, "limited", change)
+		require.NoError(t, err)
+
+		_, err = conn.PublishChange(importCtx, "limited", change)
+		require.ErrorAs(t, err, &rateErr)
+		assert.Equal(t, "importer", rateErr.Source)
+
+		unlimitedCtx := events.NewPublishSourceContext(ctx, "unlimited-source")
+
+		for range 5 {
+			_, err = conn.PublishChange(unlimitedCtx, "limited", change)
+			require.NoError(t, err)
+		}
+
+		// Topics are limited independently.
+		_, err = conn.PublishChange(ctx, "other", change)
+		require.NoError(t, err)
+
+		for range 5 {
+			_, err = conn.PublishChange(ctx, "unlimited", change)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		config := nats.Config.NATS
+		config.PublishRateLimit = 10
+		config.PublishRateBurst = 1
+
+		conn, err := events.NewNATSConnection(config)
+		require.NoError(t, err)
+
+		defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+		start := time.Now()
+
+		for range 3 {
+			_, err = conn.PublishChange(ctx, "blocking", change)
+			require.NoError(t, err)
+		}
+
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+		deadlineCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+
+		defer cancel()
+
+		_, err = conn.PublishChange(deadlineCtx, "blocking", change)
+		require.ErrorIs(t, err, events.ErrPublishRateLimited)
+
+		// Canceling a blocked publish returns a PublishRateLimitError wrapping the context error.
+		cancelCtx, cancelBlocked := context.WithCancel(ctx)
+
+		time.AfterFunc(10*time.Millisecond, cancelBlocked)
+
+		_, err = conn.PublishChange(cancelCtx, "blocking", change)
+		require.ErrorIs(t, err, events.ErrPublishRateLimited)
+		require.ErrorIs(t, err, context.Canceled)
+
+		var rateErr *events.PublishRateLimitError
+
+		require.ErrorAs(t, err, &rateErr)
+		assert.Equal(t, "blocking", rateErr.Topic)
+	})
+}
+
+func TestNATSConfigValidatePublishRateLimit(t *testing.T) {
+	config := events.NATSConfig{PublishRateLimitMode: "drop"}
+	require.ErrorIs(t, config.Validate(), events.ErrNATSInvalidPublishRateLimit)
+
+	config = events.NATSConfig{PublishTopicRateLimits: map[string]float64{"topic": -1}}
+	require.ErrorIs(t, config.Validate(), events.ErrNATSInvalidPublishRateLimit)
+
+	config = events.NATSConfig{PublishSourceRateLimits: map[string]float64{"source": -1}}
+	require.ErrorIs(t, config.Validate(), events.ErrNATSInvalidPublishRateLimit)
+
+	config = events.NATSConfig{PublishRateLimit: 5, PublishRateLimitMode: events.NATSPublishRateLimitModeFail}
+	require.NoError(t, config.Validate())
+}
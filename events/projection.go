@@ -0,0 +1,257 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultProjectionRebuildIdleTimeout is the default time a rebuild waits for a new message before
+// considering the projection caught up.
+var DefaultProjectionRebuildIdleTimeout = 2 * NATSDefaultSubscriberFetchTimeout
+
+// sequencedMessage is implemented by messages which know their position in the stream.
+type sequencedMessage interface {
+	// StreamSequence returns the stream sequence number of the message.
+	StreamSequence() uint64
+	// Pending returns the number of messages pending for the consumer after this message.
+	Pending() uint64
+}
+
+// Projection maintains a read-model of a subject type from the ChangeMessages published for it.
+type Projection struct {
+	logger             *zap.SugaredLogger
+	name               string
+	topic              string
+	subscriber         Subscriber
+	store              ProjectionStore
+	rebuildIdleTimeout time.Duration
+}
+
+// ProjectionOption configures a Projection.
+type ProjectionOption func(p *Projection)
+
+// WithProjectionLogger sets the logger for the projection.
+func WithProjectionLogger(logger *zap.SugaredLogger) ProjectionOption {
+	return func(p *Projection) {
+		p.logger = logger
+	}
+}
+
+// WithProjectionRebuildIdleTimeout sets the time a rebuild waits for a new message before
+// considering the projection caught up.
+func WithProjectionRebuildIdleTimeout(timeout time.Duration) ProjectionOption {
+	return func(p *Projection) {
+		p.rebuildIdleTimeout = timeout
+	}
+}
+
+// NewProjection creates a new Projection named name which applies changes received on topic to the store.
+// The name identifies the projection checkpoint in the store.
+func NewProjection(name, topic string, subscriber Subscriber, store ProjectionStore, options ...ProjectionOption) *Projection {
+	p := &Projection{
+		logger:             zap.NewNop().Sugar(),
+		name:               name,
+		topic:              topic,
+		subscriber:         subscriber,
+		store:              store,
+		rebuildIdleTimeout: DefaultProjectionRebuildIdleTimeout,
+	}
+
+	for _, opt := range options {
+		opt(p)
+	}
+
+	return p
+}
+
+// projectionClusterSeparator separates the projection name from the cluster name in cluster checkpoint names.
+const projectionClusterSeparator = "@"
+
+// Checkpoint returns the highest stream sequence applied to the projection.
+// The checkpoint may be used with the start-sequence SubscriberDeliveryPolicy to resume a projection.
+// When the subscriber is a MultiConnection, this is the checkpoint of the DefaultClusterName cluster, see ClusterCheckpoint.
+func (p *Projection) Checkpoint(ctx context.Context) (uint64, error) {
+	return p.store.LoadCheckpoint(ctx, p.name)
+}
+
+// ClusterCheckpoint returns the highest stream sequence applied to the projection from the named MultiConnection cluster.
+// Each cluster has its own stream, so the stream sequences of each cluster are checkpointed separately.
+func (p *Projection) ClusterCheckpoint(ctx context.Context, cluster string) (uint64, error) {
+	return p.store.LoadCheckpoint(ctx, p.checkpointName(cluster))
+}
+
+func (p *Projection) checkpointName(cluster string) string {
+	if cluster == "" || cluster == DefaultClusterName {
+		return p.name
+	}
+
+	return p.name + projectionClusterSeparator + cluster
+}
+
+// Run subscribes to the projection topic and applies changes until the context is canceled
+// or the subscription is closed.
+func (p *Projection) Run(ctx context.Context) error {
+	messages, err := p.subscriber.SubscribeChanges(ctx, p.topic)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			if err := p.Apply(ctx, msg); err != nil {
+				p.logger.Errorw("error applying change to projection", "projection", p.name, "events.topic", msg.Topic(), "error", err)
+			}
+		}
+	}
+}
+
+// Rebuild resets the projection store and replays all changes from the subscriber.
+// The subscriber should be a connection configured with ProjectionRebuildConfig so all messages
+// in the stream are delivered to a new consumer.
+// Rebuild returns once the consumer has no pending messages or no messages have been received
+// for the rebuild idle timeout. When the subscriber is a MultiConnection, the consumer of every cluster
+// must have no pending messages.
+func (p *Projection) Rebuild(ctx context.Context, subscriber Subscriber) error {
+	if err := p.store.Reset(ctx, p.name); err != nil {
+		return fmt.Errorf("failed resetting projection: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	defer cancel()
+
+	messages, err := subscriber.SubscribeChanges(ctx, p.topic)
+	if err != nil {
+		return err
+	}
+
+	clusters := 1
+
+	if multi, ok := subscriber.(*MultiConnection); ok {
+		clusters = len(multi.Clusters())
+	}
+
+	caughtUp := make(map[string]struct{}, clusters)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.rebuildIdleTimeout):
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			if err := p.Apply(ctx, msg); err != nil {
+				return fmt.Errorf("failed rebuilding projection: %w", err)
+			}
+
+			if seqMsg, ok := MessageAs[sequencedMessage](msg); ok && seqMsg.Pending() == 0 {
+				cluster, _ := MessageCluster(msg)
+
+				caughtUp[cluster] = struct{}{}
+
+				if len(caughtUp) == clusters {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// Apply applies the change to the projection, saves the checkpoint and acks the message.
+// Messages which fail to decode are terminated and messages which fail to apply are nacked.
+func (p *Projection) Apply(ctx context.Context, msg Message[ChangeMessage]) error {
+	if err := msg.Error(); err != nil {
+		if tErr := msg.Term(); tErr != nil {
+			p.logger.Warnw("error terminating message", "projection", p.name, "error", tErr)
+		}
+
+		return err
+	}
+
+	change := msg.Message()
+
+	if change.Timestamp.IsZero() {
+		change.Timestamp = msg.Timestamp()
+	}
+
+	if _, err := p.ApplyChange(ctx, change); err != nil {
+		if nErr := msg.Nak(0); nErr != nil {
+			p.logger.Warnw("error nacking message", "projection", p.name, "error", nErr)
+		}
+
+		return err
+	}
+
+	if seqMsg, ok := MessageAs[sequencedMessage](msg); ok {
+		cluster, _ := MessageCluster(msg)
+
+		if err := p.store.SaveCheckpoint(ctx, p.checkpointName(cluster), seqMsg.StreamSequence()); err != nil {
+			p.logger.Warnw("error saving projection checkpoint", "projection", p.name, "error", err)
+		}
+	}
+
+	return msg.Ack()
+}
+
+// ApplyChange applies the change to the store.
+// Creates and updates merge the SubjectFields and the current values of the FieldChanges into the subject,
+// deletes remove the subject.
+// Changes older than the last change applied to the subject are skipped, false is returned if skipped.
+func (p *Projection) ApplyChange(ctx context.Context, change ChangeMessage) (bool, error) {
+	switch ChangeType(change.EventType) {
+	case CreateChangeType, UpdateChangeType:
+		fields := make(map[string]string, len(change.SubjectFields)+len(change.FieldChanges))
+
+		maps.Copy(fields, change.SubjectFields)
+
+		for _, fieldChange := range change.FieldChanges {
+			fields[fieldChange.Field] = fieldChange.CurrentValue
+		}
+
+		return p.store.Upsert(ctx, change.SubjectID, fields, change.Timestamp)
+	case DeleteChangeType:
+		return p.store.Delete(ctx, change.SubjectID, change.Timestamp)
+	default:
+		return false, fmt.Errorf("%w: %s", ErrProjectionUnsupportedEventType, change.EventType)
+	}
+}
+
+// ProjectionRebuildConfig returns a copy of the config which delivers all messages in the stream to a new
+// ephemeral consumer, suitable for a connection passed to Projection.Rebuild.
+// The QueueGroup is cleared on purpose: with a queue group the rebuild would join the durable consumer
+// shared with the running projections, receiving only the unacked messages and splitting them with the
+// other members, instead of replaying the whole stream. The config of every cluster is updated the same way.
+func ProjectionRebuildConfig(config Config) Config {
+	config.NATS.SubscriberDeliveryPolicy = "all"
+	config.NATS.QueueGroup = ""
+
+	if config.Clusters != nil {
+		clusters := make(map[string]NATSConfig, len(config.Clusters))
+
+		for name, cluster := range config.Clusters {
+			cluster.SubscriberDeliveryPolicy = "all"
+			cluster.QueueGroup = ""
+
+			clusters[name] = cluster
+		}
+
+		config.Clusters = clusters
+	}
+
+	return config
+}
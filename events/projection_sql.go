@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.infratographer.com/x/gidx"
+)
+
+var _ ProjectionStore = (*SQLProjectionStore)(nil)
+
+// SQLProjectionStore is a ProjectionStore backed by a postgres compatible database such as CockroachDB.
+// Subject fields are stored as JSONB in the subjects table, deleted subjects are kept as tombstones
+// so older changes received afterwards are not applied.
+type SQLProjectionStore struct {
+	db               *sql.DB
+	table            string
+	checkpointsTable string
+}
+
+// NewSQLProjectionStore creates a new SQLProjectionStore storing subjects in table and checkpoints in table_checkpoints.
+// The table name is used as is and must be a trusted value.
+func NewSQLProjectionStore(db *sql.DB, table string) *SQLProjectionStore {
+	return &SQLProjectionStore{
+		db:               db,
+		table:            table,
+		checkpointsTable: table + "_checkpoints",
+	}
+}
+
+// CreateTables creates the subjects and checkpoints tables if they do not already exist.
+func (s *SQLProjectionStore) CreateTables(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		subject_id TEXT PRIMARY KEY,
+		fields JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		deleted BOOLEAN NOT NULL DEFAULT false
+	)`, s.table)); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		name TEXT PRIMARY KEY,
+		sequence BIGINT NOT NULL
+	)`, s.checkpointsTable))
+
+	return err
+}
+
+// Upsert merges the fields into the subject if the timestamp is newer than the last change applied to the subject.
+func (s *SQLProjectionStore) Upsert(ctx context.Context, subjectID gidx.PrefixedID, fields map[string]string, timestamp time.Time) (bool, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (subject_id, fields, updated_at, deleted) VALUES ($1, $2, $3, false)
+		ON CONFLICT (subject_id) DO UPDATE SET
+			fields = CASE WHEN %[1]s.deleted THEN excluded.fields ELSE %[1]s.fields || excluded.fields END,
+			updated_at = excluded.updated_at,
+			deleted = false
+		WHERE %[1]s.updated_at < excluded.updated_at`, s.table),
+		subjectID.String(), string(data), timestamp,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return sqlRowsApplied(result)
+}
+
+// Delete removes the subject if the timestamp is newer than the last change applied to the subject.
+func (s *SQLProjectionStore) Delete(ctx context.Context, subjectID gidx.PrefixedID, timestamp time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (subject_id, fields, updated_at, deleted) VALUES ($1, '{}', $2, true)
+		ON CONFLICT (subject_id) DO UPDATE SET
+			fields = '{}',
+			updated_at = excluded.updated_at,
+			deleted = true
+		WHERE %[1]s.updated_at < excluded.updated_at`, s.table),
+		subjectID.String(), timestamp,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return sqlRowsApplied(result)
+}
+
+// Get returns the fields of the subject, false is returned if the subject does not exist.
+func (s *SQLProjectionStore) Get(ctx context.Context, subjectID gidx.PrefixedID) (map[string]string, bool, error) {
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT fields FROM %s WHERE subject_id = $1 AND NOT deleted", s.table),
+		subjectID.String(),
+	).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	var fields map[string]string
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false, err
+	}
+
+	return fields, true, nil
+}
+
+// LoadCheckpoint returns the highest stream sequence saved for the projection name.
+func (s *SQLProjectionStore) LoadCheckpoint(ctx context.Context, name string) (uint64, error) {
+	var sequence int64
+
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT sequence FROM %s WHERE name = $1", s.checkpointsTable),
+		name,
+	).Scan(&sequence)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return uint64(sequence), nil
+}
+
+// SaveCheckpoint saves the stream sequence for the projection name if it is higher than the current checkpoint.
+func (s *SQLProjectionStore) SaveCheckpoint(ctx context.Context, name string, sequence uint64) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (name, sequence) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET sequence = excluded.sequence
+		WHERE %[1]s.sequence < excluded.sequence`, s.checkpointsTable),
+		name, int64(sequence),
+	)
+
+	return err
+}
+
+// Reset removes all subjects and the checkpoints for the projection name, including cluster checkpoints.
+func (s *SQLProjectionStore) Reset(ctx context.Context, name string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", s.table)); err != nil {
+		return err
+	}
+
+	// left is used rather than LIKE so wildcard characters in the name are not matched.
+	if _, err := tx.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE name = $1 OR left(name, length($2)) = $2", s.checkpointsTable),
+		name, name+projectionClusterSeparator,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func sqlRowsApplied(result sql.Result) (bool, error) {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows != 0, nil
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"go.infratographer.com/x/gidx"
+)
+
+// ProjectionStore stores the subjects of a Projection.
+type ProjectionStore interface {
+	// Upsert merges the fields into the subject if the timestamp is newer than the last change applied to the subject.
+	// A subject which was previously deleted has its fields replaced. False is returned if the change was skipped.
+	Upsert(ctx context.Context, subjectID gidx.PrefixedID, fields map[string]string, timestamp time.Time) (bool, error)
+	// Delete removes the subject if the timestamp is newer than the last change applied to the subject.
+	// False is returned if the change was skipped.
+	Delete(ctx context.Context, subjectID gidx.PrefixedID, timestamp time.Time) (bool, error)
+	// Get returns the fields of the subject, false is returned if the subject does not exist.
+	Get(ctx context.Context, subjectID gidx.PrefixedID) (map[string]string, bool, error)
+	// LoadCheckpoint returns the highest stream sequence saved for the projection name.
+	LoadCheckpoint(ctx context.Context, name string) (uint64, error)
+	// SaveCheckpoint saves the stream sequence for the projection name if it is higher than the current checkpoint.
+	SaveCheckpoint(ctx context.Context, name string, sequence uint64) error
+	// Reset removes all subjects and the checkpoints for the projection name, including the
+	// checkpoints of each MultiConnection cluster saved as name@cluster.
+	Reset(ctx context.Context, name string) error
+}
+
+var _ ProjectionStore = (*MemoryProjectionStore)(nil)
+
+type memoryProjectionSubject struct {
+	fields    map[string]string
+	updatedAt time.Time
+	deleted   bool
+}
+
+// MemoryProjectionStore is an in-memory ProjectionStore.
+type MemoryProjectionStore struct {
+	mu          sync.RWMutex
+	subjects    map[gidx.PrefixedID]memoryProjectionSubject
+	checkpoints map[string]uint64
+}
+
+// NewMemoryProjectionStore creates a new empty MemoryProjectionStore.
+func NewMemoryProjectionStore() *MemoryProjectionStore {
+	return &MemoryProjectionStore{
+		subjects:    make(map[gidx.PrefixedID]memoryProjectionSubject),
+		checkpoints: make(map[string]uint64),
+	}
+}
+
+// Upsert merges the fields into the subject if the timestamp is newer than the last change applied to the subject.
+func (s *MemoryProjectionStore) Upsert(_ context.Context, subjectID gidx.PrefixedID, fields map[string]string, timestamp time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject, ok := s.subjects[subjectID]
+	if ok && !subject.updatedAt.Before(timestamp) {
+		return false, nil
+	}
+
+	if !ok || subject.deleted {
+		subject.fields = make(map[string]string, len(fields))
+	} else {
+		subject.fields = maps.Clone(subject.fields)
+	}
+
+	maps.Copy(subject.fields, fields)
+
+	subject.updatedAt = timestamp
+	subject.deleted = false
+
+	s.subjects[subjectID] = subject
+
+	return true, nil
+}
+
+// Delete removes the subject if the timestamp is newer than the last change applied to the subject.
+func (s *MemoryProjectionStore) Delete(_ context.Context, subjectID gidx.PrefixedID, timestamp time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject, ok := s.subjects[subjectID]
+	if ok && !subject.updatedAt.Before(timestamp) {
+		return false, nil
+	}
+
+	s.subjects[subjectID] = memoryProjectionSubject{
+		updatedAt: timestamp,
+		deleted:   true,
+	}
+
+	return true, nil
+}
+
+// Get returns the fields of the subject, false is returned if the subject does not exist.
+func (s *MemoryProjectionStore) Get(_ context.Context, subjectID gidx.PrefixedID) (map[string]string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subject, ok := s.subjects[subjectID]
+	if !ok || subject.deleted {
+		return nil, false, nil
+	}
+
+	return maps.Clone(subject.fields), true, nil
+}
+
+// LoadCheckpoint returns the highest stream sequence saved for the projection name.
+func (s *MemoryProjectionStore) LoadCheckpoint(_ context.Context, name string) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.checkpoints[name], nil
+}
+
+// SaveCheckpoint saves the stream sequence for the projection name if it is higher than the current checkpoint.
+func (s *MemoryProjectionStore) SaveCheckpoint(_ context.Context, name string, sequence uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sequence > s.checkpoints[name] {
+		s.checkpoints[name] = sequence
+	}
+
+	return nil
+}
+
+// Reset removes all subjects and the checkpoints for the projection name, including cluster checkpoints.
+func (s *MemoryProjectionStore) Reset(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subjects = make(map[gidx.PrefixedID]memoryProjectionSubject)
+
+	delete(s.checkpoints, name)
+
+	for checkpoint := range s.checkpoints {
+		if strings.HasPrefix(checkpoint, name+projectionClusterSeparator) {
+			delete(s.checkpoints, checkpoint)
+		}
+	}
+
+	return nil
+}
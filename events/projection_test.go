@@ -0,0 +1,289 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+func TestProjectionApplyChangeOrdering(t *testing.T) {
+	ctx := context.Background()
+
+	store := events.NewMemoryProjectionStore()
+	projection := events.NewProjection("test", "*.test", nil, store)
+
+	subjectID := gidx.MustNewID("testing")
+	now := time.Now()
+
+	applied, err := projection.ApplyChange(ctx, events.ChangeMessage{
+		SubjectID:     subjectID,
+		EventType:     "create",
+		Timestamp:     now,
+		SubjectFields: map[string]string{"name": "one", "port": "80"},
+	})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	applied, err = projection.ApplyChange(ctx, events.ChangeMessage{
+		SubjectID:    subjectID,
+		EventType:    "update",
+		Timestamp:    now.Add(2 * time.Second),
+		FieldChanges: []events.FieldChange{{Field: "name", PreviousValue: "one", CurrentValue: "three"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	// An older update received late must not overwrite a newer one.
+	applied, err = projection.ApplyChange(ctx, events.ChangeMessage{
+		SubjectID:    subjectID,
+		EventType:    "update",
+		Timestamp:    now.Add(time.Second),
+		FieldChanges: []events.FieldChange{{Field: "name", PreviousValue: "one", CurrentValue: "two"}},
+	})
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	fields, ok, err := store.Get(ctx, subjectID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "three", "port": "80"}, fields)
+
+	applied, err = projection.ApplyChange(ctx, events.ChangeMessage{
+		SubjectID: subjectID,
+		EventType: "delete",
+		Timestamp: now.Add(4 * time.Second),
+	})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	// An update older than the delete must not resurrect the subject.
+	applied, err = projection.ApplyChange(ctx, events.ChangeMessage{
+		SubjectID:    subjectID,
+		EventType:    "update",
+		Timestamp:    now.Add(3 * time.Second),
+		FieldChanges: []events.FieldChange{{Field: "name", CurrentValue: "four"}},
+	})
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	_, ok, err = store.Get(ctx, subjectID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	applied, err = projection.ApplyChange(ctx, events.ChangeMessage{
+		SubjectID:     subjectID,
+		EventType:     "create",
+		Timestamp:     now.Add(5 * time.Second),
+		SubjectFields: map[string]string{"name": "five"},
+	})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	fields, ok, err = store.Get(ctx, subjectID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "five"}, fields, "recreated subject should not keep deleted fields")
+
+	_, err = projection.ApplyChange(ctx, events.ChangeMessage{
+		SubjectID: subjectID,
+		EventType: "other",
+		Timestamp: now.Add(6 * time.Second),
+	})
+	require.ErrorIs(t, err, events.ErrProjectionUnsupportedEventType)
+}
+
+func TestProjectionRunAndRebuild(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	conn, err := events.NewNATSConnection(nats.Config.NATS)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	subjectID := gidx.MustNewID("testing")
+	now := time.Now()
+
+	_, err = conn.PublishChange(ctx, "test", events.ChangeMessage{
+		SubjectID:     subjectID,
+		EventType:     "create",
+		Timestamp:     now,
+		SubjectFields: map[string]string{"name": "one"},
+	})
+	require.NoError(t, err)
+
+	_, err = conn.PublishChange(ctx, "test", events.ChangeMessage{
+		SubjectID:    subjectID,
+		EventType:    "update",
+		Timestamp:    now.Add(time.Second),
+		FieldChanges: []events.FieldChange{{Field: "name", PreviousValue: "one", CurrentValue: "two"}},
+	})
+	require.NoError(t, err)
+
+	store := events.NewMemoryProjectionStore()
+	projection := events.NewProjection("test", "*.test", conn, store)
+
+	go projection.Run(ctx) //nolint:errcheck // within test
+
+	require.Eventually(t, func() bool {
+		checkpoint, err := projection.Checkpoint(ctx)
+
+		return err == nil && checkpoint == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	fields, ok, err := store.Get(ctx, subjectID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "two"}, fields)
+
+	rebuildStore := events.NewMemoryProjectionStore()
+	rebuildConn, err := events.NewConnection(events.ProjectionRebuildConfig(nats.Config))
+	require.NoError(t, err)
+
+	defer rebuildConn.Shutdown(ctx) //nolint:errcheck // within test
+
+	rebuild := events.NewProjection("test", "*.test", conn, rebuildStore, events.WithProjectionRebuildIdleTimeout(time.Second))
+
+	// Add a subject which does not exist in the stream to ensure rebuild starts from an empty store.
+	staleID := gidx.MustNewID("testing")
+
+	_, err = rebuildStore.Upsert(ctx, staleID, map[string]string{"name": "stale"}, now)
+	require.NoError(t, err)
+
+	require.NoError(t, rebuild.Rebuild(ctx, rebuildConn))
+
+	fields, ok, err = rebuildStore.Get(ctx, subjectID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "two"}, fields)
+
+	_, ok, err = rebuildStore.Get(ctx, staleID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	checkpoint, err := rebuild.Checkpoint(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), checkpoint)
+}
+
+func TestProjectionMultiConnection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	local, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer local.Close()
+
+	central, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer central.Close()
+
+	config := local.Config
+	config.Clusters = map[string]events.NATSConfig{
+		"central": central.Config.NATS,
+	}
+
+	conn, err := events.NewMultiConnection(config)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	subjectID := gidx.MustNewID("testing")
+	now := time.Now()
+
+	_, err = conn.PublishChange(ctx, "test", events.ChangeMessage{
+		SubjectID:     subjectID,
+		EventType:     "create",
+		Timestamp:     now,
+		SubjectFields: map[string]string{"name": "one"},
+	})
+	require.NoError(t, err)
+
+	// Only publish the update to the central cluster so the cluster streams have different sequences.
+	centralConn, ok := conn.Cluster("central")
+	require.True(t, ok)
+
+	_, err = centralConn.PublishChange(ctx, "test", events.ChangeMessage{
+		SubjectID:    subjectID,
+		EventType:    "update",
+		Timestamp:    now.Add(time.Second),
+		FieldChanges: []events.FieldChange{{Field: "name", PreviousValue: "one", CurrentValue: "two"}},
+	})
+	require.NoError(t, err)
+
+	store := events.NewMemoryProjectionStore()
+	projection := events.NewProjection("test", "*.test", conn, store)
+
+	go projection.Run(ctx) //nolint:errcheck // within test
+
+	require.Eventually(t, func() bool {
+		checkpoint, err := projection.Checkpoint(ctx)
+		if err != nil || checkpoint != 1 {
+			return false
+		}
+
+		checkpoint, err = projection.ClusterCheckpoint(ctx, "central")
+
+		return err == nil && checkpoint == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	fields, ok, err := store.Get(ctx, subjectID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "two"}, fields)
+
+	rebuildConn, err := events.NewMultiConnection(events.ProjectionRebuildConfig(config))
+	require.NoError(t, err)
+
+	defer rebuildConn.Shutdown(ctx) //nolint:errcheck // within test
+
+	rebuildStore := events.NewMemoryProjectionStore()
+
+	// Stale checkpoints for every cluster must be reset so the rebuild starts from the beginning of each stream,
+	// checkpoints of other projections are kept.
+	require.NoError(t, rebuildStore.SaveCheckpoint(ctx, "test", 100))
+	require.NoError(t, rebuildStore.SaveCheckpoint(ctx, "test@central", 100))
+	require.NoError(t, rebuildStore.SaveCheckpoint(ctx, "other@central", 100))
+
+	// The idle timeout is longer than the test waits, so the rebuild must return once every cluster is caught up.
+	rebuild := events.NewProjection("test", "*.test", conn, rebuildStore, events.WithProjectionRebuildIdleTimeout(time.Minute))
+
+	rebuildCtx, rebuildCancel := context.WithTimeout(ctx, 5*time.Second)
+
+	defer rebuildCancel()
+
+	require.NoError(t, rebuild.Rebuild(rebuildCtx, rebuildConn))
+
+	fields, ok, err = rebuildStore.Get(ctx, subjectID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "two"}, fields)
+
+	checkpoint, err := rebuild.Checkpoint(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), checkpoint)
+
+	checkpoint, err = rebuild.ClusterCheckpoint(ctx, "central")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), checkpoint)
+
+	checkpoint, err = rebuildStore.LoadCheckpoint(ctx, "other@central")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), checkpoint)
+}
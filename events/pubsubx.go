@@ -0,0 +1,255 @@
+package events
+
+import (
+	"context"
+	"maps"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/pubsubx"
+)
+
+// ContextWithLegacyTrace returns a context with a remote span context built from the deprecated
+// TraceID and SpanID message fields. If either id is invalid the context is returned unchanged.
+func ContextWithLegacyTrace(ctx context.Context, traceID, spanID string) context.Context {
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return ctx
+	}
+
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return ctx
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
+
+// legacyTraceContext builds an OpenTelemetry propagation map from the deprecated TraceID and SpanID fields.
+func legacyTraceContext(traceID, spanID string) map[string]string {
+	var mapCarrier propagation.MapCarrier = make(map[string]string)
+
+	otel.GetTextMapPropagator().Inject(ContextWithLegacyTrace(context.Background(), traceID, spanID), mapCarrier)
+
+	return mapCarrier
+}
+
+// legacyTraceIDs returns the trace and span ids from the OpenTelemetry propagation map,
+// falling back to the provided ids if the map holds no valid span context.
+func legacyTraceIDs(traceContext map[string]string, traceID, spanID string) (string, string) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(traceContext))
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String(), sc.SpanID().String()
+	}
+
+	return traceID, spanID
+}
+
+// ChangeMessageFromPubsubx converts a legacy pubsubx ChangeMessage into a ChangeMessage.
+// The TraceContext is reconstructed from the TraceID and SpanID.
+func ChangeMessageFromPubsubx(m pubsubx.ChangeMessage) ChangeMessage {
+	fieldChanges := make([]FieldChange, len(m.FieldChanges))
+
+	for i, fc := range m.FieldChanges {
+		fieldChanges[i] = FieldChange(fc)
+	}
+
+	return ChangeMessage{
+		SubjectID:            m.SubjectID,
+		EventType:            m.EventType,
+		AdditionalSubjectIDs: m.AdditionalSubjectIDs,
+		ActorID:              m.ActorID,
+		Source:               m.Source,
+		Timestamp:            m.Timestamp,
+		TraceContext:         legacyTraceContext(m.TraceID, m.SpanID),
+		TraceID:              m.TraceID,
+		SpanID:               m.SpanID,
+		SubjectFields:        maps.Clone(m.SubjectFields),
+		FieldChanges:         fieldChanges,
+		AdditionalData:       maps.Clone(m.AdditionalData),
+	}
+}
+
+// ToPubsubx converts the ChangeMessage into a legacy pubsubx ChangeMessage.
+// The TraceID and SpanID are taken from the TraceContext when it holds a valid span context.
+func (m ChangeMessage) ToPubsubx() pubsubx.ChangeMessage {
+	fieldChanges := make([]pubsubx.FieldChange, len(m.FieldChanges))
+
+	for i, fc := range m.FieldChanges {
+		fieldChanges[i] = pubsubx.FieldChange(fc)
+	}
+
+	traceID, spanID := legacyTraceIDs(m.TraceContext, m.TraceID, m.SpanID)
+
+	return pubsubx.ChangeMessage{
+		SubjectID:            m.SubjectID,
+		EventType:            m.EventType,
+		AdditionalSubjectIDs: m.AdditionalSubjectIDs,
+		ActorID:              m.ActorID,
+		Source:               m.Source,
+		Timestamp:            m.Timestamp,
+		TraceID:              traceID,
+		SpanID:               spanID,
+		SubjectFields:        maps.Clone(m.SubjectFields),
+		FieldChanges:         fieldChanges,
+		AdditionalData:       maps.Clone(m.AdditionalData),
+	}
+}
+
+// EventMessageFromPubsubx converts a legacy pubsubx EventMessage into an EventMessage.
+// The TraceContext is reconstructed from the TraceID and SpanID.
+func EventMessageFromPubsubx(m pubsubx.EventMessage) EventMessage {
+	return EventMessage{
+		SubjectID:            m.SubjectID,
+		EventType:            m.EventType,
+		AdditionalSubjectIDs: m.AdditionalSubjectIDs,
+		Source:               m.Source,
+		Timestamp:            m.Timestamp,
+		TraceContext:         legacyTraceContext(m.TraceID, m.SpanID),
+		TraceID:              m.TraceID,
+		SpanID:               m.SpanID,
+		Data:                 maps.Clone(m.Data),
+	}
+}
+
+// ToPubsubx converts the EventMessage into a legacy pubsubx EventMessage.
+// The TraceID and SpanID are taken from the TraceContext when it holds a valid span context.
+func (m EventMessage) ToPubsubx() pubsubx.EventMessage {
+	traceID, spanID := legacyTraceIDs(m.TraceContext, m.TraceID, m.SpanID)
+
+	return pubsubx.EventMessage{
+		SubjectID:            m.SubjectID,
+		EventType:            m.EventType,
+		AdditionalSubjectIDs: m.AdditionalSubjectIDs,
+		Source:               m.Source,
+		Timestamp:            m.Timestamp,
+		TraceID:              traceID,
+		SpanID:               spanID,
+		Data:                 maps.Clone(m.Data),
+	}
+}
+
+// PubsubxBridge consumes messages published in the legacy pubsubx format and republishes them as events messages.
+//
+// The pubsubx and events message formats share the same json encoding, apart from the events TraceContext,
+// so legacy messages may be received with any Subscriber. The trace context of each republished message is
+// reconstructed from the legacy TraceID and SpanID when the message has no TraceContext.
+type PubsubxBridge struct {
+	logger     *zap.SugaredLogger
+	subscriber Subscriber
+	publisher  Publisher
+}
+
+// PubsubxBridgeOption configures a PubsubxBridge.
+type PubsubxBridgeOption func(b *PubsubxBridge)
+
+// WithPubsubxBridgeLogger sets the logger for the bridge.
+func WithPubsubxBridgeLogger(logger *zap.SugaredLogger) PubsubxBridgeOption {
+	return func(b *PubsubxBridge) {
+		b.logger = logger
+	}
+}
+
+// NewPubsubxBridge creates a new bridge receiving legacy messages from the subscriber and republishing them with the publisher.
+// The subscriber and publisher should use different subject prefixes so republished messages are not received again.
+func NewPubsubxBridge(subscriber Subscriber, publisher Publisher, options ...PubsubxBridgeOption) *PubsubxBridge {
+	b := &PubsubxBridge{
+		logger:     zap.NewNop().Sugar(),
+		subscriber: subscriber,
+		publisher:  publisher,
+	}
+
+	for _, opt := range options {
+		opt(b)
+	}
+
+	return b
+}
+
+// RunChanges republishes legacy change messages received on legacyTopic to topic until the context is canceled
+// or the subscription is closed.
+func (b *PubsubxBridge) RunChanges(ctx context.Context, legacyTopic, topic string) error {
+	messages, err := b.subscriber.SubscribeChanges(ctx, legacyTopic)
+	if err != nil {
+		return err
+	}
+
+	return runPubsubxBridge(ctx, b.logger, messages, func(msg ChangeMessage) error {
+		_, err := b.publisher.PublishChange(legacyMessageContext(ctx, msg.TraceContext, msg.TraceID, msg.SpanID), topic, msg)
+
+		return err
+	})
+}
+
+// RunEvents republishes legacy event messages received on legacyTopic to topic until the context is canceled
+// or the subscription is closed.
+func (b *PubsubxBridge) RunEvents(ctx context.Context, legacyTopic, topic string) error {
+	messages, err := b.subscriber.SubscribeEvents(ctx, legacyTopic)
+	if err != nil {
+		return err
+	}
+
+	return runPubsubxBridge(ctx, b.logger, messages, func(msg EventMessage) error {
+		_, err := b.publisher.PublishEvent(legacyMessageContext(ctx, msg.TraceContext, msg.TraceID, msg.SpanID), topic, msg)
+
+		return err
+	})
+}
+
+// legacyMessageContext returns the context to publish a bridged message with.
+// Messages which already carry a TraceContext keep it, otherwise it is built from the legacy ids.
+func legacyMessageContext(ctx context.Context, traceContext map[string]string, traceID, spanID string) context.Context {
+	if len(traceContext) != 0 {
+		return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(traceContext))
+	}
+
+	return ContextWithLegacyTrace(ctx, traceID, spanID)
+}
+
+func runPubsubxBridge[T any](ctx context.Context, logger *zap.SugaredLogger, messages <-chan Message[T], publish func(T) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			logger := logger.With("events.topic", msg.Topic(), "events.message_id", msg.ID())
+
+			if err := msg.Error(); err != nil {
+				logger.Errorw("terminating undecodable legacy message", "error", err)
+
+				if err := msg.Term(); err != nil {
+					logger.Warnw("error terminating message", "error", err)
+				}
+
+				continue
+			}
+
+			if err := publish(msg.Message()); err != nil {
+				logger.Errorw("error republishing legacy message", "error", err)
+
+				if err := msg.Nak(0); err != nil {
+					logger.Warnw("error nacking message", "error", err)
+				}
+
+				continue
+			}
+
+			if err := msg.Ack(); err != nil {
+				logger.Warnw("error acking message", "error", err)
+			}
+		}
+	}
+}
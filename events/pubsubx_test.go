@@ -0,0 +1,153 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.infratographer.com/x/pubsubx"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+const (
+	testLegacyTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testLegacySpanID  = "00f067aa0ba902b7"
+)
+
+func withTraceContextPropagator(t *testing.T) {
+	t.Helper()
+
+	previous := otel.GetTextMapPropagator()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTextMapPropagator(previous)
+	})
+}
+
+func TestPubsubxChangeMessageConversion(t *testing.T) {
+	withTraceContextPropagator(t)
+
+	legacy := pubsubx.ChangeMessage{
+		SubjectID:            gidx.MustNewID("testing"),
+		EventType:            "update",
+		AdditionalSubjectIDs: []gidx.PrefixedID{gidx.MustNewID("testtnt")},
+		ActorID:              gidx.MustNewID("testusr"),
+		Source:               "legacy",
+		Timestamp:            time.Now().UTC(),
+		TraceID:              testLegacyTraceID,
+		SpanID:               testLegacySpanID,
+		SubjectFields:        map[string]string{"name": "new"},
+		FieldChanges:         []pubsubx.FieldChange{{Field: "name", PreviousValue: "old", CurrentValue: "new"}},
+		AdditionalData:       map[string]interface{}{"key": "value"},
+	}
+
+	change := events.ChangeMessageFromPubsubx(legacy)
+
+	assert.Equal(t, legacy.SubjectID, change.SubjectID)
+	assert.Equal(t, []events.FieldChange{{Field: "name", PreviousValue: "old", CurrentValue: "new"}}, change.FieldChanges)
+	assert.Equal(t, "00-"+testLegacyTraceID+"-"+testLegacySpanID+"-01", change.TraceContext["traceparent"])
+
+	sc := trace.SpanContextFromContext(change.GetTraceContext(context.Background()))
+
+	assert.Equal(t, testLegacyTraceID, sc.TraceID().String())
+	assert.Equal(t, testLegacySpanID, sc.SpanID().String())
+
+	assert.Equal(t, legacy, change.ToPubsubx())
+
+	// TraceContext takes precedence over the deprecated fields.
+	change.TraceID = ""
+	change.SpanID = ""
+
+	assert.Equal(t, legacy, change.ToPubsubx())
+}
+
+func TestPubsubxEventMessageConversion(t *testing.T) {
+	withTraceContextPropagator(t)
+
+	legacy := pubsubx.EventMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		EventType: "ping",
+		Source:    "legacy",
+		Timestamp: time.Now().UTC(),
+		TraceID:   "invalid",
+		SpanID:    "invalid",
+		Data:      map[string]interface{}{"key": "value"},
+	}
+
+	event := events.EventMessageFromPubsubx(legacy)
+
+	assert.Empty(t, event.TraceContext)
+	assert.Equal(t, legacy, event.ToPubsubx())
+}
+
+type recordingPublisher struct {
+	changes chan context.Context
+}
+
+func (p *recordingPublisher) PublishChange(ctx context.Context, _ string, _ events.ChangeMessage) (events.Message[events.ChangeMessage], error) {
+	p.changes <- ctx
+
+	return nil, nil
+}
+
+func (p *recordingPublisher) PublishEvent(_ context.Context, _ string, _ events.EventMessage) (events.Message[events.EventMessage], error) {
+	return nil, nil
+}
+
+func TestPubsubxBridge(t *testing.T) {
+	withTraceContextPropagator(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	conn, err := events.NewNATSConnection(nats.Config.NATS)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	legacy := pubsubx.ChangeMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		EventType: "create",
+		TraceID:   testLegacyTraceID,
+		SpanID:    testLegacySpanID,
+	}
+
+	data, err := json.Marshal(legacy)
+	require.NoError(t, err)
+
+	_, err = nats.JetStream.Publish(eventtools.Prefix+".changes.create.legacy", data)
+	require.NoError(t, err)
+
+	publisher := &recordingPublisher{changes: make(chan context.Context, 1)}
+
+	bridge := events.NewPubsubxBridge(conn, publisher)
+
+	go bridge.RunChanges(ctx, "*.legacy", "test") //nolint:errcheck // within test
+
+	select {
+	case pubCtx := <-publisher.changes:
+		sc := trace.SpanContextFromContext(pubCtx)
+
+		assert.Equal(t, testLegacyTraceID, sc.TraceID().String())
+		assert.Equal(t, testLegacySpanID, sc.SpanID().String())
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for bridged message")
+	}
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	// DefaultMaxAttempts is the default number of delivery attempts made to an endpoint for a single message.
+	DefaultMaxAttempts = 5
+	// DefaultInitialBackoff is the default delay before the first retry.
+	DefaultInitialBackoff = time.Second
+	// DefaultMaxBackoff is the default maximum delay between retries.
+	DefaultMaxBackoff = time.Minute
+	// DefaultRequestTimeout is the default timeout for a single delivery request.
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultFailureThreshold is the default number of consecutive failed deliveries before an endpoint is disabled.
+	DefaultFailureThreshold = 10
+	// DefaultProgressInterval is the default interval a message is marked as in progress while it is delivered.
+	// It must be shorter than the AckWait of the consumer to prevent redelivery during retries.
+	DefaultProgressInterval = 10 * time.Second
+	// DefaultConcurrency is the default number of messages delivered at once.
+	DefaultConcurrency = 10
+)
+
+// Config defines the webhook delivery configuration.
+type Config struct {
+	// MaxAttempts is the number of delivery attempts made to an endpoint for a single message.
+	MaxAttempts int `mapstructure:"maxAttempts"`
+	// InitialBackoff is the delay before the first retry, each following retry doubles the delay.
+	InitialBackoff time.Duration `mapstructure:"initialBackoff"`
+	// MaxBackoff is the maximum delay between retries.
+	MaxBackoff time.Duration `mapstructure:"maxBackoff"`
+	// RequestTimeout is the timeout for a single delivery request.
+	RequestTimeout time.Duration `mapstructure:"requestTimeout"`
+	// FailureThreshold is the number of consecutive failed deliveries before an endpoint is disabled.
+	FailureThreshold int `mapstructure:"failureThreshold"`
+	// ProgressInterval is the interval a message is marked as in progress while it is being delivered.
+	ProgressInterval time.Duration `mapstructure:"progressInterval"`
+	// Concurrency is the number of messages delivered at once, so a slow or failing endpoint
+	// doesn't hold up the delivery of other messages.
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+// WithDefaults sets default values for the fields unset.
+func (c Config) WithDefaults() Config {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = DefaultInitialBackoff
+	}
+
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = DefaultMaxBackoff
+	}
+
+	if c.RequestTimeout <= 0 {
+		c.RequestTimeout = DefaultRequestTimeout
+	}
+
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = DefaultFailureThreshold
+	}
+
+	if c.ProgressInterval <= 0 {
+		c.ProgressInterval = DefaultProgressInterval
+	}
+
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultConcurrency
+	}
+
+	return c
+}
+
+// backoff returns the delay to wait after the provided attempt.
+func (c Config) backoff(attempt int) time.Duration {
+	delay := c.InitialBackoff
+
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+
+		if delay >= c.MaxBackoff {
+			return c.MaxBackoff
+		}
+	}
+
+	return delay
+}
+
+// MustViperFlags binds the webhook config to viper.
+func MustViperFlags(v *viper.Viper) {
+	v.MustBindEnv("webhooks.maxAttempts")
+	v.MustBindEnv("webhooks.initialBackoff")
+	v.MustBindEnv("webhooks.maxBackoff")
+	v.MustBindEnv("webhooks.requestTimeout")
+	v.MustBindEnv("webhooks.failureThreshold")
+	v.MustBindEnv("webhooks.progressInterval")
+	v.MustBindEnv("webhooks.concurrency")
+
+	v.SetDefault("webhooks.maxAttempts", DefaultMaxAttempts)
+	v.SetDefault("webhooks.initialBackoff", DefaultInitialBackoff)
+	v.SetDefault("webhooks.maxBackoff", DefaultMaxBackoff)
+	v.SetDefault("webhooks.requestTimeout", DefaultRequestTimeout)
+	v.SetDefault("webhooks.failureThreshold", DefaultFailureThreshold)
+	v.SetDefault("webhooks.progressInterval", DefaultProgressInterval)
+	v.SetDefault("webhooks.concurrency", DefaultConcurrency)
+}
+
+// Option configures a Dispatcher.
+type Option func(d *Dispatcher)
+
+// WithLogger sets the logger for the dispatcher.
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// WithHTTPClient sets the http client used to deliver messages.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
@@ -0,0 +1,371 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/events"
+)
+
+const tracerName = "go.infratographer.com/x/events/webhook"
+
+// Dispatcher delivers ChangeMessages received from a subscriber to webhook endpoints.
+type Dispatcher struct {
+	logger     *zap.SugaredLogger
+	tracer     trace.Tracer
+	client     *http.Client
+	subscriber events.Subscriber
+	store      Store
+	cfg        Config
+}
+
+// NewDispatcher creates a new Dispatcher delivering messages from the subscriber to the endpoints in the store.
+func NewDispatcher(subscriber events.Subscriber, store Store, config Config, options ...Option) *Dispatcher {
+	d := &Dispatcher{
+		logger:     zap.NewNop().Sugar(),
+		tracer:     otel.GetTracerProvider().Tracer(tracerName),
+		client:     http.DefaultClient,
+		subscriber: subscriber,
+		store:      store,
+		cfg:        config.WithDefaults(),
+	}
+
+	for _, opt := range options {
+		opt(d)
+	}
+
+	return d
+}
+
+// Run subscribes to changes on the provided topic and delivers them until the context is canceled
+// or the subscription is closed. Up to Config.Concurrency messages are delivered at once, Run waits
+// for deliveries in progress before returning.
+func (d *Dispatcher) Run(ctx context.Context, topic string) error {
+	messages, err := d.subscriber.SubscribeChanges(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+
+	defer wg.Wait()
+
+	slots := make(chan struct{}, d.cfg.Concurrency)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case slots <- struct{}{}:
+			}
+
+			wg.Add(1)
+
+			go func() {
+				defer func() {
+					<-slots
+
+					wg.Done()
+				}()
+
+				d.handleMessage(ctx, msg)
+			}()
+		}
+	}
+}
+
+func (d *Dispatcher) handleMessage(ctx context.Context, msg events.Message[events.ChangeMessage]) {
+	logger := d.logger.With("events.topic", msg.Topic(), "events.message_id", msg.ID())
+
+	if err := msg.Error(); err != nil {
+		logger.Errorw("terminating undecodable message", "error", err)
+
+		if err := msg.Term(); err != nil {
+			logger.Warnw("error terminating message", "error", err)
+		}
+
+		return
+	}
+
+	stopProgress := d.keepInProgress(ctx, msg)
+
+	err := d.Deliver(ctx, msg.ID(), msg.Message())
+
+	stopProgress()
+
+	if err != nil {
+		logger.Errorw("error delivering message, message will be redelivered", "error", err)
+
+		if err := msg.Nak(d.cfg.InitialBackoff); err != nil {
+			logger.Warnw("error nacking message", "error", err)
+		}
+
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		logger.Warnw("error acking message", "error", err)
+	}
+}
+
+// inProgressMessage is implemented by messages which support extending their redelivery timer.
+type inProgressMessage interface {
+	InProgress() error
+}
+
+// keepInProgress periodically marks the message as in progress so it isn't redelivered while
+// deliveries are retried. The returned function stops marking the message and must be called
+// once the message has been delivered.
+func (d *Dispatcher) keepInProgress(ctx context.Context, msg events.Message[events.ChangeMessage]) func() {
+	progress, ok := events.MessageAs[inProgressMessage](msg)
+	if !ok {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(d.cfg.ProgressInterval)
+
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := progress.InProgress(); err != nil {
+					d.logger.Warnw("error marking message in progress", "events.message_id", msg.ID(), "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Deliver sends the message to all matching endpoints, retrying failed deliveries with exponential backoff.
+// Endpoints which already received the message are skipped, so a redelivered message is only sent to the
+// endpoints which have not yet received it. Endpoints which exhaust their attempts have their failure
+// recorded and are disabled once the failure threshold is reached. ErrDeliveryFailed is returned if any
+// matching endpoint which is not disabled did not receive the message, so the message can be redelivered.
+// An error is also returned if the endpoints could not be loaded from the store.
+func (d *Dispatcher) Deliver(ctx context.Context, messageID string, msg events.ChangeMessage) error {
+	ctx = msg.GetTraceContext(ctx)
+
+	ctx, span := d.tracer.Start(ctx, "events.webhook.Deliver", trace.WithAttributes(
+		attribute.String("events.subject_id", msg.SubjectID.String()),
+		attribute.String("events.event_type", msg.EventType),
+	))
+
+	defer span.End()
+
+	endpoints, err := d.store.Endpoints(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Int32
+	)
+
+	for _, endpoint := range endpoints {
+		if !endpoint.Matches(msg) {
+			continue
+		}
+
+		delivered, err := d.store.Delivered(ctx, endpoint.ID, messageID)
+		if err != nil {
+			d.logger.Warnw("error checking previous deliveries", "webhook.endpoint_id", endpoint.ID, "events.message_id", messageID, "error", err)
+		}
+
+		if delivered {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if !d.deliverEndpoint(ctx, endpoint, messageID, msg, payload) {
+				failed.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if failed.Load() != 0 {
+		err := fmt.Errorf("%w: %d endpoints", ErrDeliveryFailed, failed.Load())
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+// deliverEndpoint delivers the message to the endpoint, returning true if the endpoint received it
+// or was disabled after repeated failures and will receive no further deliveries.
+func (d *Dispatcher) deliverEndpoint(ctx context.Context, endpoint Endpoint, messageID string, msg events.ChangeMessage, payload []byte) bool {
+	logger := d.logger.With("webhook.endpoint_id", endpoint.ID, "events.message_id", messageID)
+
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		result := d.send(ctx, endpoint, messageID, msg, payload)
+
+		result.MessageID = messageID
+		result.Attempt = attempt
+
+		if err := d.store.RecordAttempt(ctx, result); err != nil {
+			logger.Warnw("error recording delivery attempt", "error", err)
+		}
+
+		if result.Succeeded() {
+			if err := d.store.RecordSuccess(ctx, endpoint.ID); err != nil {
+				logger.Warnw("error recording delivery success", "error", err)
+			}
+
+			return true
+		}
+
+		logger.Debugw("delivery attempt failed", "webhook.attempt", attempt, "error", result.Error)
+
+		if !retryable(result.StatusCode) || attempt == d.cfg.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(d.cfg.backoff(attempt)):
+		}
+	}
+
+	failures, err := d.store.RecordFailure(ctx, endpoint.ID)
+	if err != nil {
+		logger.Warnw("error recording delivery failure", "error", err)
+
+		return false
+	}
+
+	logger.Warnw("delivery failed", "webhook.consecutive_failures", failures)
+
+	if failures >= d.cfg.FailureThreshold {
+		logger.Warnw("disabling webhook endpoint after repeated failures", "webhook.consecutive_failures", failures)
+
+		if err := d.store.DisableEndpoint(ctx, endpoint.ID); err != nil {
+			logger.Errorw("error disabling webhook endpoint", "error", err)
+
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (d *Dispatcher) send(ctx context.Context, endpoint Endpoint, messageID string, msg events.ChangeMessage, payload []byte) Attempt {
+	result := Attempt{
+		EndpointID: endpoint.ID,
+		Timestamp:  time.Now(),
+	}
+
+	defer func() {
+		result.Duration = time.Since(result.Timestamp)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.RequestTimeout)
+
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		result.Error = err.Error()
+
+		return result
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, messageID)
+	req.Header.Set(EventTypeHeader, msg.EventType)
+	req.Header.Set(SubjectIDHeader, msg.SubjectID.String())
+	req.Header.Set(TimestampHeader, strconv.FormatInt(result.Timestamp.Unix(), base10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, result.Timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+
+		return result
+	}
+
+	defer resp.Body.Close()
+
+	// Drain the body to allow the connection to be reused.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	result.StatusCode = resp.StatusCode
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		result.Error = fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, resp.StatusCode).Error()
+	}
+
+	return result
+}
+
+// retryable returns false for client error responses which will not succeed on retry.
+// Requests which received no response are always retryable.
+func retryable(statusCode int) bool {
+	switch {
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusTooManyRequests:
+		return true
+	case statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,442 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/events/webhook"
+	"go.infratographer.com/x/gidx"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+func TestDispatcherDelivers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	nats, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	defer nats.Close()
+
+	conn, err := events.NewNATSConnection(nats.Config.NATS)
+	require.NoError(t, err)
+
+	defer conn.Shutdown(ctx) //nolint:errcheck // within test
+
+	secret := []byte("super-secret")
+	received := make(chan events.ChangeMessage, 1)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := webhook.VerifyRequest(r, secret, time.Minute)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		assert.Equal(t, "create", r.Header.Get(webhook.EventTypeHeader))
+		assert.NotEmpty(t, r.Header.Get(webhook.EventIDHeader))
+
+		var msg events.ChangeMessage
+
+		assert.NoError(t, json.Unmarshal(body, &msg))
+
+		received <- msg
+	}))
+
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+
+	store.AddEndpoint(webhook.Endpoint{
+		ID:         "receiver",
+		URL:        receiver.URL,
+		Secret:     secret,
+		EventTypes: []string{"create"},
+	})
+
+	dispatcher := webhook.NewDispatcher(conn, store, webhook.Config{})
+
+	go dispatcher.Run(ctx, "*.test") //nolint:errcheck // within test
+
+	// Ensure the subscription has been started before publishing.
+	time.Sleep(100 * time.Millisecond)
+
+	change := events.ChangeMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		ActorID:   gidx.MustNewID("testusr"),
+		EventType: "create",
+	}
+
+	_, err = conn.PublishChange(ctx, "test", change)
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, change.SubjectID, msg.SubjectID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for webhook delivery")
+	}
+
+	require.Eventually(t, func() bool {
+		return len(store.Attempts("receiver")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	attempt := store.Attempts("receiver")[0]
+
+	assert.True(t, attempt.Succeeded())
+	assert.Equal(t, http.StatusOK, attempt.StatusCode)
+	assert.Equal(t, 1, attempt.Attempt)
+}
+
+func TestDispatcherRetriesAndDisables(t *testing.T) {
+	ctx := context.Background()
+
+	var requests atomic.Int32
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+
+	store.AddEndpoint(webhook.Endpoint{
+		ID:     "failing",
+		URL:    receiver.URL,
+		Secret: []byte("secret"),
+	})
+
+	store.AddEndpoint(webhook.Endpoint{
+		ID:              "unmatched",
+		URL:             receiver.URL,
+		SubjectPrefixes: []string{"othrtyp"},
+	})
+
+	dispatcher := webhook.NewDispatcher(nil, store, webhook.Config{
+		MaxAttempts:      3,
+		InitialBackoff:   time.Millisecond,
+		FailureThreshold: 2,
+	})
+
+	change := events.ChangeMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		EventType: "update",
+	}
+
+	require.ErrorIs(t, dispatcher.Deliver(ctx, "1", change), webhook.ErrDeliveryFailed)
+
+	assert.Equal(t, int32(3), requests.Load())
+	assert.Len(t, store.Attempts("failing"), 3)
+	assert.Empty(t, store.Attempts("unmatched"))
+
+	endpoint, err := store.Endpoint("failing")
+	require.NoError(t, err)
+
+	assert.False(t, endpoint.Disabled)
+	assert.Equal(t, 1, endpoint.ConsecutiveFailures)
+
+	// the endpoint is disabled after the failed delivery, so the message will not be redelivered to it
+	require.NoError(t, dispatcher.Deliver(ctx, "2", change))
+
+	endpoint, err = store.Endpoint("failing")
+	require.NoError(t, err)
+
+	assert.True(t, endpoint.Disabled)
+	assert.Equal(t, 2, endpoint.ConsecutiveFailures)
+
+	// no endpoints match once the failing endpoint is disabled
+	require.NoError(t, dispatcher.Deliver(ctx, "3", change))
+
+	assert.Equal(t, int32(6), requests.Load(), "disabled endpoint should receive no deliveries")
+}
+
+func TestDispatcherRedeliversToFailedEndpoints(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		succeeded atomic.Int32
+		failing   atomic.Int32
+		recovered atomic.Bool
+	)
+
+	success := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		succeeded.Add(1)
+	}))
+
+	defer success.Close()
+
+	failure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		failing.Add(1)
+
+		if !recovered.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	defer failure.Close()
+
+	store := webhook.NewMemoryStore()
+
+	store.AddEndpoint(webhook.Endpoint{ID: "success", URL: success.URL})
+	store.AddEndpoint(webhook.Endpoint{ID: "failure", URL: failure.URL})
+
+	dispatcher := webhook.NewDispatcher(nil, store, webhook.Config{
+		MaxAttempts:    1,
+		InitialBackoff: time.Millisecond,
+	})
+
+	change := events.ChangeMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		EventType: "update",
+	}
+
+	// a message delivered to only some endpoints must be redelivered
+	require.ErrorIs(t, dispatcher.Deliver(ctx, "1", change), webhook.ErrDeliveryFailed)
+
+	assert.Equal(t, int32(1), succeeded.Load())
+	assert.Equal(t, int32(1), failing.Load())
+
+	recovered.Store(true)
+
+	// the redelivered message is only sent to the endpoint which has not received it
+	require.NoError(t, dispatcher.Deliver(ctx, "1", change))
+
+	assert.Equal(t, int32(1), succeeded.Load())
+	assert.Equal(t, int32(2), failing.Load())
+}
+
+func TestDispatcherDoesNotRetryClientErrors(t *testing.T) {
+	var requests atomic.Int32
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+
+	store.AddEndpoint(webhook.Endpoint{
+		ID:  "rejecting",
+		URL: receiver.URL,
+	})
+
+	dispatcher := webhook.NewDispatcher(nil, store, webhook.Config{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+
+	change := events.ChangeMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		EventType: "delete",
+	}
+
+	require.ErrorIs(t, dispatcher.Deliver(context.Background(), "1", change), webhook.ErrDeliveryFailed)
+
+	assert.Equal(t, int32(1), requests.Load())
+
+	attempts := store.Attempts("rejecting")
+
+	require.Len(t, attempts, 1)
+	assert.False(t, attempts[0].Succeeded())
+	assert.Equal(t, http.StatusBadRequest, attempts[0].StatusCode)
+}
+
+type testSubscriber struct {
+	messages chan events.Message[events.ChangeMessage]
+}
+
+func (s *testSubscriber) SubscribeChanges(_ context.Context, _ string) (<-chan events.Message[events.ChangeMessage], error) {
+	return s.messages, nil
+}
+
+func (s *testSubscriber) SubscribeEvents(_ context.Context, _ string) (<-chan events.Message[events.EventMessage], error) {
+	return nil, nil
+}
+
+type testMessage struct {
+	id         string
+	message    events.ChangeMessage
+	inProgress atomic.Int32
+	result     chan string
+}
+
+func (m *testMessage) Connection() events.Connection { return nil }
+func (m *testMessage) ID() string                    { return m.id }
+func (m *testMessage) Topic() string                 { return "changes.test" }
+func (m *testMessage) Message() events.ChangeMessage { return m.message }
+func (m *testMessage) Error() error                  { return nil }
+func (m *testMessage) InProgress() error             { m.inProgress.Add(1); return nil }
+func (m *testMessage) Ack() error                    { m.result <- "ack"; return nil }
+func (m *testMessage) Nak(time.Duration) error       { m.result <- "nak"; return nil }
+func (m *testMessage) Term() error                   { m.result <- "term"; return nil }
+func (m *testMessage) Timestamp() time.Time          { return time.Time{} }
+func (m *testMessage) Deliveries() uint64            { return 1 }
+func (m *testMessage) Source() any                   { return nil }
+
+func TestDispatcherRunAcksAndNaks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	var (
+		requests atomic.Int32
+		eventIDs = make(chan string, 10)
+	)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eventIDs <- r.Header.Get(webhook.EventIDHeader)
+
+		// the first message succeeds on the third attempt, every later request fails
+		if n := requests.Add(1); n != 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+
+	store.AddEndpoint(webhook.Endpoint{
+		ID:  "receiver",
+		URL: receiver.URL,
+	})
+
+	subscriber := &testSubscriber{messages: make(chan events.Message[events.ChangeMessage])}
+
+	dispatcher := webhook.NewDispatcher(subscriber, store, webhook.Config{
+		MaxAttempts:      3,
+		InitialBackoff:   50 * time.Millisecond,
+		ProgressInterval: 10 * time.Millisecond,
+	})
+
+	go dispatcher.Run(ctx, "*.test") //nolint:errcheck // within test
+
+	change := events.ChangeMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		EventType: "create",
+	}
+
+	delivered := &testMessage{id: "delivered", message: change, result: make(chan string, 1)}
+
+	subscriber.messages <- delivered
+
+	assert.Equal(t, "ack", <-delivered.result)
+	assert.Positive(t, delivered.inProgress.Load(), "expected message to be marked in progress during retries")
+
+	for range 3 {
+		assert.Equal(t, "delivered", <-eventIDs)
+	}
+
+	failed := &testMessage{id: "failed", message: change, result: make(chan string, 1)}
+
+	subscriber.messages <- failed
+
+	assert.Equal(t, "nak", <-failed.result, "expected undelivered message to be redelivered")
+}
+
+func TestDispatcherRunConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	release := make(chan struct{})
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(webhook.EventIDHeader) == "slow" {
+			<-release
+		}
+	}))
+
+	defer receiver.Close()
+	defer close(release)
+
+	store := webhook.NewMemoryStore()
+
+	store.AddEndpoint(webhook.Endpoint{
+		ID:  "receiver",
+		URL: receiver.URL,
+	})
+
+	subscriber := &testSubscriber{messages: make(chan events.Message[events.ChangeMessage])}
+
+	dispatcher := webhook.NewDispatcher(subscriber, store, webhook.Config{})
+
+	go dispatcher.Run(ctx, "*.test") //nolint:errcheck // within test
+
+	change := events.ChangeMessage{
+		SubjectID: gidx.MustNewID("testing"),
+		EventType: "create",
+	}
+
+	slow := &testMessage{id: "slow", message: change, result: make(chan string, 1)}
+	fast := &testMessage{id: "fast", message: change, result: make(chan string, 1)}
+
+	subscriber.messages <- slow
+	subscriber.messages <- fast
+
+	select {
+	case result := <-fast.result:
+		assert.Equal(t, "ack", result)
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow delivery blocked the delivery of other messages")
+	}
+
+	assert.Empty(t, slow.result)
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("secret")
+	payload := []byte(`{"eventType":"create"}`)
+	now := time.Now()
+
+	signature := webhook.Sign(secret, now, payload)
+
+	testCases := []struct {
+		name      string
+		secret    []byte
+		signature string
+		signedAt  time.Time
+		payload   []byte
+		expectErr error
+	}{
+		{"valid", secret, signature, now, payload, nil},
+		{"wrong secret", []byte("other"), signature, now, payload, webhook.ErrInvalidSignature},
+		{"modified payload", secret, signature, now, []byte(`{}`), webhook.ErrInvalidSignature},
+		{"missing signature", secret, "", now, payload, webhook.ErrMissingSignature},
+		{"expired", secret, webhook.Sign(secret, now.Add(-time.Hour), payload), now.Add(-time.Hour), payload, webhook.ErrSignatureExpired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			timestamp := strconv.FormatInt(tc.signedAt.Unix(), 10)
+
+			err := webhook.VerifySignature(tc.secret, tc.signature, timestamp, tc.payload, time.Minute)
+
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
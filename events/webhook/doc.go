@@ -0,0 +1,34 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook delivers events ChangeMessages to HTTP endpoints.
+//
+// A Dispatcher subscribes to changes using an events.Subscriber and posts each
+// message to every enabled Endpoint from a Store which matches the message.
+// Payloads are signed with the endpoint's secret, see Sign and VerifyRequest.
+// Each delivery includes the message id in the EventIDHeader so receivers can
+// deduplicate redelivered messages. Messages which were not delivered to every
+// matching endpoint are nacked and redelivered, endpoints which already received
+// the message are skipped on redelivery. Messages are acked once every matching
+// endpoint has received the message or been disabled.
+//
+//	store := webhook.NewMemoryStore()
+//	store.AddEndpoint(webhook.Endpoint{ID: "customer-a", URL: "https://example.com/hook", Secret: []byte("secret")})
+//
+//	dispatcher := webhook.NewDispatcher(conn, store, webhook.Config{})
+//
+//	if err := dispatcher.Run(ctx, "*.loadbalancer"); err != nil {
+//		// handle error
+//	}
+package webhook
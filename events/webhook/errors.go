@@ -0,0 +1,23 @@
+package webhook
+
+import "errors"
+
+var (
+	// ErrEndpointNotFound is returned when the requested endpoint does not exist in the store.
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+
+	// ErrDeliveryFailed is returned when a message could not be delivered to one or more of its matching endpoints.
+	ErrDeliveryFailed = errors.New("webhook delivery failed")
+
+	// ErrUnexpectedStatusCode is returned when an endpoint responds with a non 2xx status code.
+	ErrUnexpectedStatusCode = errors.New("unexpected webhook response status code")
+
+	// ErrMissingSignature is returned when a request does not include the signature or timestamp headers.
+	ErrMissingSignature = errors.New("webhook signature missing")
+
+	// ErrInvalidSignature is returned when a request signature does not match the payload.
+	ErrInvalidSignature = errors.New("webhook signature invalid")
+
+	// ErrSignatureExpired is returned when a request timestamp is outside of the allowed tolerance.
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside of tolerance")
+)
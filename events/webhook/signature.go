@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader is the header containing the payload signature.
+	SignatureHeader = "X-Infratographer-Signature"
+	// TimestampHeader is the header containing the unix timestamp the payload was signed at.
+	TimestampHeader = "X-Infratographer-Timestamp"
+	// EventTypeHeader is the header containing the event type of the delivered message.
+	EventTypeHeader = "X-Infratographer-Event-Type"
+	// SubjectIDHeader is the header containing the subject id of the delivered message.
+	SubjectIDHeader = "X-Infratographer-Subject-ID"
+	// EventIDHeader is the header containing the id of the delivered message. The id is the same
+	// for every delivery attempt of a message, allowing receivers to deduplicate deliveries.
+	EventIDHeader = "X-Infratographer-Event-ID"
+
+	signatureVersion = "v1="
+
+	base10    = 10
+	bitSize64 = 64
+)
+
+// Sign returns the signature for the payload signed at the provided time.
+// The signature is the hex encoded HMAC-SHA256 of the unix timestamp, a period and the payload.
+func Sign(secret []byte, timestamp time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), base10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature ensures the signature matches the payload and the timestamp is within the tolerance of now.
+// A tolerance of zero disables the timestamp check.
+func VerifySignature(secret []byte, signature, timestamp string, payload []byte, tolerance time.Duration) error {
+	if signature == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, base10, bitSize64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp: %w", ErrInvalidSignature, err)
+	}
+
+	signedAt := time.Unix(unix, 0)
+
+	if tolerance > 0 {
+		if diff := time.Since(signedAt).Abs(); diff > tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, signedAt, payload))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// VerifyRequest reads the request body and verifies the signature headers.
+// The body is returned and the request body is replaced so it may be read again.
+func VerifyRequest(r *http.Request, secret []byte, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := VerifySignature(secret, r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body, tolerance); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.infratographer.com/x/events"
+)
+
+// Endpoint defines an HTTP endpoint messages are delivered to.
+type Endpoint struct {
+	// ID uniquely identifies the endpoint.
+	ID string
+	// URL is the address messages are posted to.
+	URL string
+	// Secret is the key used to sign payloads delivered to the endpoint.
+	Secret []byte
+	// EventTypes limits delivery to messages with one of the event types. If empty all event types are delivered.
+	EventTypes []string
+	// SubjectPrefixes limits delivery to messages whose SubjectID has one of the prefixes. If empty all subjects are delivered.
+	SubjectPrefixes []string
+	// Disabled endpoints receive no deliveries.
+	Disabled bool
+	// ConsecutiveFailures is the number of deliveries which have failed since the last successful delivery.
+	ConsecutiveFailures int
+}
+
+// Matches returns true if the message should be delivered to the endpoint.
+func (e Endpoint) Matches(msg events.ChangeMessage) bool {
+	if e.Disabled {
+		return false
+	}
+
+	if len(e.EventTypes) != 0 && !slices.Contains(e.EventTypes, msg.EventType) {
+		return false
+	}
+
+	if len(e.SubjectPrefixes) != 0 && !slices.Contains(e.SubjectPrefixes, msg.SubjectID.Prefix()) {
+		return false
+	}
+
+	return true
+}
+
+// Attempt records the result of a single delivery attempt.
+type Attempt struct {
+	// EndpointID is the endpoint the delivery was made to.
+	EndpointID string
+	// MessageID is the id of the message delivered.
+	MessageID string
+	// Attempt is the attempt number for the message, starting at 1.
+	Attempt int
+	// StatusCode is the response status code, zero if no response was received.
+	StatusCode int
+	// Error is the error encountered, empty if the delivery succeeded.
+	Error string
+	// Timestamp is when the attempt was made.
+	Timestamp time.Time
+	// Duration is how long the attempt took.
+	Duration time.Duration
+}
+
+// Succeeded returns true if the attempt was successful.
+func (a Attempt) Succeeded() bool {
+	return a.Error == ""
+}
+
+// Store provides the endpoints to deliver to and records the delivery results.
+type Store interface {
+	// Endpoints returns all endpoints which are not disabled.
+	Endpoints(ctx context.Context) ([]Endpoint, error)
+	// RecordAttempt stores the result of a delivery attempt.
+	RecordAttempt(ctx context.Context, attempt Attempt) error
+	// Delivered returns true if a successful attempt has been recorded for the message and endpoint.
+	Delivered(ctx context.Context, endpointID, messageID string) (bool, error)
+	// RecordSuccess resets the consecutive failure count of the endpoint.
+	RecordSuccess(ctx context.Context, endpointID string) error
+	// RecordFailure increments the consecutive failure count of the endpoint returning the new count.
+	RecordFailure(ctx context.Context, endpointID string) (int, error)
+	// DisableEndpoint stops all future deliveries to the endpoint.
+	DisableEndpoint(ctx context.Context, endpointID string) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory Store.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	endpoints map[string]Endpoint
+	attempts  map[string][]Attempt
+}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		endpoints: make(map[string]Endpoint),
+		attempts:  make(map[string][]Attempt),
+	}
+}
+
+// AddEndpoint adds or replaces an endpoint.
+func (s *MemoryStore) AddEndpoint(endpoint Endpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.endpoints[endpoint.ID] = endpoint
+}
+
+// Endpoint returns the endpoint with the provided id.
+func (s *MemoryStore) Endpoint(endpointID string) (Endpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	endpoint, ok := s.endpoints[endpointID]
+	if !ok {
+		return Endpoint{}, ErrEndpointNotFound
+	}
+
+	return endpoint, nil
+}
+
+// Attempts returns all delivery attempts recorded for the endpoint.
+func (s *MemoryStore) Attempts(endpointID string) []Attempt {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.attempts[endpointID])
+}
+
+// Endpoints returns all endpoints which are not disabled.
+func (s *MemoryStore) Endpoints(_ context.Context) ([]Endpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	endpoints := make([]Endpoint, 0, len(s.endpoints))
+
+	for _, endpoint := range s.endpoints {
+		if !endpoint.Disabled {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// RecordAttempt stores the result of a delivery attempt.
+func (s *MemoryStore) RecordAttempt(_ context.Context, attempt Attempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts[attempt.EndpointID] = append(s.attempts[attempt.EndpointID], attempt)
+
+	return nil
+}
+
+// Delivered returns true if a successful attempt has been recorded for the message and endpoint.
+func (s *MemoryStore) Delivered(_ context.Context, endpointID, messageID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, attempt := range s.attempts[endpointID] {
+		if attempt.MessageID == messageID && attempt.Succeeded() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RecordSuccess resets the consecutive failure count of the endpoint.
+func (s *MemoryStore) RecordSuccess(_ context.Context, endpointID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, ok := s.endpoints[endpointID]
+	if !ok {
+		return ErrEndpointNotFound
+	}
+
+	endpoint.ConsecutiveFailures = 0
+
+	s.endpoints[endpointID] = endpoint
+
+	return nil
+}
+
+// RecordFailure increments the consecutive failure count of the endpoint returning the new count.
+func (s *MemoryStore) RecordFailure(_ context.Context, endpointID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, ok := s.endpoints[endpointID]
+	if !ok {
+		return 0, ErrEndpointNotFound
+	}
+
+	endpoint.ConsecutiveFailures++
+
+	s.endpoints[endpointID] = endpoint
+
+	return endpoint.ConsecutiveFailures, nil
+}
+
+// DisableEndpoint stops all future deliveries to the endpoint.
+func (s *MemoryStore) DisableEndpoint(_ context.Context, endpointID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, ok := s.endpoints[endpointID]
+	if !ok {
+		return ErrEndpointNotFound
+	}
+
+	endpoint.Disabled = true
+
+	s.endpoints[endpointID] = endpoint
+
+	return nil
+}
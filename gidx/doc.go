@@ -27,4 +27,16 @@
 // prefix inst and have an object type of instance. The 3 character code for
 // instance might be anc, resulting in a prefix of instanc. An instance ID might
 // then look like instanc-myrandomidvalue.
+//
+// # Validation
+//
+// Parse, Scan, UnmarshalGQL and UnmarshalJSON all validate IDs the same way: the
+// value must be in the prefix-id format with a valid prefix. By default the ID part
+// is validated leniently so IDs which predate generated IDs, such as unknown-actor,
+// continue to be read from existing streams and databases. Scan previously accepted
+// any value, values which are not in the prefix-id format are now rejected.
+//
+// Once stored IDs have been migrated, services may require generated ID parts with:
+//
+//	gidx.SetIDPartValidationMode(gidx.IDPartValidationStrict)
 package gidx
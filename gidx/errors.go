@@ -18,18 +18,42 @@ import (
 	"errors"
 )
 
-// ErrUnsupportedType is returned when a value is provided of an unsupported type
-var ErrUnsupportedType = errors.New("unsupported type")
+var (
+	// ErrUnsupportedType is returned when a value is provided of an unsupported type
+	ErrUnsupportedType = errors.New("unsupported type")
+
+	// ErrPrefixCollision is returned when registering a prefix or type name which is already registered
+	ErrPrefixCollision = errors.New("prefix collision")
+
+	// ErrMissingTypeName is returned when registering a prefix without a type name
+	ErrMissingTypeName = errors.New("prefix type name required")
+
+	// ErrPrefixMismatch is wrapped by ErrInvalidID when a TypedID is given an ID with a different prefix
+	ErrPrefixMismatch = errors.New("prefix mismatch")
+
+	// ErrUnknownPrefix is wrapped by ErrInvalidID when a prefix is not registered and registered prefixes are required
+	ErrUnknownPrefix = errors.New("unknown prefix")
+)
 
 // ErrInvalidID is returned when a provided ID value is invalid
 type ErrInvalidID struct {
 	msg string
+	err error
 }
 
 func (e *ErrInvalidID) Error() string {
 	return "invalid id: " + e.msg
 }
 
+// Unwrap returns the underlying error, if any.
+func (e *ErrInvalidID) Unwrap() error {
+	return e.err
+}
+
 func newErrInvalidID(s string) error {
 	return &ErrInvalidID{msg: s}
 }
+
+func newErrInvalidIDWrap(err error, s string) error {
+	return &ErrInvalidID{msg: s, err: err}
+}
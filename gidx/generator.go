@@ -0,0 +1,241 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sortableAlphabet is the nanoid alphabet in ascii order so encoded values sort the same as the values.
+	sortableAlphabet = "-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+	// sortableBits is the number of bits encoded by each character of the sortable alphabet.
+	sortableBits = 6
+	sortableMask = 1<<sortableBits - 1
+
+	// TimestampPartLength is the number of characters of a time-sortable ID part holding the timestamp.
+	// The timestamp is the 42 bit unix millisecond time, which lasts until 2109. A 48 bit timestamp
+	// would start with the zero digit, '-', until then, so every ID would start with '-'.
+	TimestampPartLength = 7
+	// randomPartLength is the number of random characters following the timestamp in a time-sortable ID part.
+	randomPartLength = IDPartLength - TimestampPartLength
+)
+
+// IDGenerator generates the ID part of new PrefixedIDs.
+type IDGenerator interface {
+	// NewIDPart returns a new IDPartLength character ID part using the nanoid alphabet.
+	NewIDPart() (string, error)
+}
+
+// IDGeneratorFunc adapts a function to an IDGenerator.
+type IDGeneratorFunc func() (string, error)
+
+// NewIDPart calls f.
+func (f IDGeneratorFunc) NewIDPart() (string, error) {
+	return f()
+}
+
+// RandomGenerator generates random nanoid ID parts. This is the default generator.
+var RandomGenerator IDGenerator = IDGeneratorFunc(newIDValue)
+
+// TimeSortableGenerator generates ID parts which sort by the time they were generated.
+//
+// The first TimestampPartLength characters encode the unix millisecond timestamp and the remaining
+// characters are random. Characters are taken from the nanoid alphabet ordered by ascii value so IDs
+// sort by time when compared as strings. IDs generated in the same millisecond are not ordered
+// unless Monotonic is set.
+type TimeSortableGenerator struct {
+	// Monotonic increments the random part of the previous ID instead of generating a new random
+	// part when IDs are generated in the same millisecond, so IDs from the generator always increase.
+	Monotonic bool
+
+	// now returns the current time, used for testing.
+	now func() time.Time
+
+	mu         sync.Mutex
+	lastMillis uint64
+	lastRandom [randomPartLength]byte
+}
+
+// NewTimeSortableGenerator creates a new TimeSortableGenerator.
+func NewTimeSortableGenerator(monotonic bool) *TimeSortableGenerator {
+	return &TimeSortableGenerator{
+		Monotonic: monotonic,
+	}
+}
+
+// NewIDPart returns a new time-sortable ID part.
+func (g *TimeSortableGenerator) NewIDPart() (string, error) {
+	now := time.Now
+	if g.now != nil {
+		now = g.now
+	}
+
+	millis := uint64(now().UnixMilli()) //nolint:gosec // unix time is positive
+
+	var random [randomPartLength]byte
+
+	if !g.Monotonic {
+		if err := randomSortableDigits(random[:]); err != nil {
+			return "", err
+		}
+
+		return encodeTimeSortable(millis, random), nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if millis <= g.lastMillis {
+		// Use the previous timestamp, also when the clock moved backwards, and increment the random part.
+		// If the random part overflows the timestamp is moved forward a millisecond.
+		millis = g.lastMillis
+		random = g.lastRandom
+
+		if !incrementSortableDigits(random[:]) {
+			millis++
+		}
+	} else if err := randomSortableDigits(random[:]); err != nil {
+		return "", err
+	}
+
+	g.lastMillis = millis
+	g.lastRandom = random
+
+	return encodeTimeSortable(millis, random), nil
+}
+
+func encodeTimeSortable(millis uint64, random [randomPartLength]byte) string {
+	var b [IDPartLength]byte
+
+	for i := TimestampPartLength - 1; i >= 0; i-- {
+		b[i] = sortableAlphabet[millis&sortableMask]
+		millis >>= sortableBits
+	}
+
+	for i, digit := range random {
+		b[TimestampPartLength+i] = sortableAlphabet[digit]
+	}
+
+	return string(b[:])
+}
+
+// randomSortableDigits fills digits with random values within the sortable alphabet.
+func randomSortableDigits(digits []byte) error {
+	if _, err := rand.Read(digits); err != nil {
+		return err
+	}
+
+	for i := range digits {
+		digits[i] &= sortableMask
+	}
+
+	return nil
+}
+
+// incrementSortableDigits increments the digits by one, false is returned if the digits overflowed.
+func incrementSortableDigits(digits []byte) bool {
+	for i := len(digits) - 1; i >= 0; i-- {
+		if digits[i] < sortableMask {
+			digits[i]++
+
+			return true
+		}
+
+		digits[i] = 0
+	}
+
+	return false
+}
+
+// Timestamp returns the time embedded in an ID generated by a TimeSortableGenerator.
+// An error is returned if the ID part is not a valid time-sortable ID part. IDs from
+// other generators may still return a time, which will not be meaningful.
+func (p PrefixedID) Timestamp() (time.Time, error) {
+	id := p.ID()
+
+	if len(id) != IDPartLength {
+		return time.Time{}, newErrInvalidID(fmt.Sprintf("expected id part length is %d, '%s' is %d", IDPartLength, id, len(id)))
+	}
+
+	var millis uint64
+
+	for i := range TimestampPartLength {
+		digit := strings.IndexByte(sortableAlphabet, id[i])
+		if digit < 0 {
+			return time.Time{}, newErrInvalidID(fmt.Sprintf("id part '%s' contains invalid character %q", id, id[i]))
+		}
+
+		millis = millis<<sortableBits | uint64(digit)
+	}
+
+	return time.UnixMilli(int64(millis)).UTC(), nil //nolint:gosec // 42 bit timestamp does not overflow
+}
+
+var generators = struct {
+	sync.RWMutex
+
+	fallback IDGenerator
+	prefixes map[string]IDGenerator
+}{
+	fallback: RandomGenerator,
+	prefixes: make(map[string]IDGenerator),
+}
+
+// SetDefaultGenerator sets the generator used by NewID for prefixes without a prefix generator.
+// A nil generator restores the RandomGenerator.
+func SetDefaultGenerator(g IDGenerator) {
+	generators.Lock()
+	defer generators.Unlock()
+
+	if g == nil {
+		g = RandomGenerator
+	}
+
+	generators.fallback = g
+}
+
+// SetPrefixGenerator sets the generator used by NewID for the prefix.
+// A nil generator removes the prefix generator so the default generator is used.
+func SetPrefixGenerator(prefix string, g IDGenerator) {
+	prefix = strings.ToLower(prefix)
+
+	generators.Lock()
+	defer generators.Unlock()
+
+	if g == nil {
+		delete(generators.prefixes, prefix)
+
+		return
+	}
+
+	generators.prefixes[prefix] = g
+}
+
+// generatorFor returns the generator used for the prefix.
+func generatorFor(prefix string) IDGenerator {
+	generators.RLock()
+	defer generators.RUnlock()
+
+	if g, ok := generators.prefixes[prefix]; ok {
+		return g
+	}
+
+	return generators.fallback
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx_test
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/gidx"
+)
+
+var idPartRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{21}$`)
+
+func TestTimeSortableGenerator(t *testing.T) {
+	gen := gidx.NewTimeSortableGenerator(false)
+
+	start := time.Now().Truncate(time.Millisecond)
+
+	var ids []gidx.PrefixedID
+
+	for range 5 {
+		part, err := gen.NewIDPart()
+		require.NoError(t, err)
+		require.Regexp(t, idPartRegexp, part)
+
+		ids = append(ids, gidx.PrefixedID("testing-"+part))
+
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	assert.True(t, sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] }))
+
+	ts, err := ids[0].Timestamp()
+	require.NoError(t, err)
+	assert.WithinDuration(t, start, ts, time.Second)
+	assert.False(t, ts.Before(start))
+}
+
+func TestTimeSortableGeneratorMonotonic(t *testing.T) {
+	gen := gidx.NewTimeSortableGenerator(true)
+
+	last := ""
+
+	for range 10000 {
+		part, err := gen.NewIDPart()
+		require.NoError(t, err)
+		require.Regexp(t, idPartRegexp, part)
+		require.Greater(t, part, last)
+
+		last = part
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	_, err := gidx.PrefixedID("testing-short").Timestamp()
+	assert.ErrorContains(t, err, "invalid id: expected id part length is 21")
+
+	_, err = gidx.PrefixedID("testing-!!!!!!!!!!!!!!!!!!!!!").Timestamp()
+	assert.ErrorContains(t, err, "contains invalid character")
+
+	// the smallest and largest characters of the alphabet
+	ts, err := gidx.PrefixedID("testing-" + "-------" + "abcdefghijklmn").Timestamp()
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(0).UTC(), ts)
+
+	ts, err = gidx.PrefixedID("testing-" + "------0" + "abcdefghijklmn").Timestamp()
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1).UTC(), ts)
+
+	ts, err = gidx.PrefixedID("testing-" + "zzzzzzz" + "abcdefghijklmn").Timestamp()
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1<<42-1).UTC(), ts)
+	assert.Equal(t, 2109, ts.Year())
+}
+
+func TestTimeSortableGeneratorFirstCharacter(t *testing.T) {
+	for _, gen := range []*gidx.TimeSortableGenerator{
+		gidx.NewTimeSortableGenerator(false),
+		gidx.NewTimeSortableGenerator(true),
+	} {
+		part, err := gen.NewIDPart()
+		require.NoError(t, err)
+
+		id := gidx.PrefixedID("testing-" + part)
+
+		// the character following the prefix separator must not be the zero digit of the timestamp
+		assert.NotEqual(t, byte('-'), id.ID()[0], "unexpected leading '-' in %s", id)
+	}
+}
+
+func TestPrefixGenerator(t *testing.T) {
+	fixed := gidx.IDGeneratorFunc(func() (string, error) {
+		return "fixedfixedfixedfixed1", nil
+	})
+
+	gidx.SetPrefixGenerator("FIXEDPR", fixed)
+
+	t.Cleanup(func() {
+		gidx.SetPrefixGenerator("fixedpr", nil)
+		gidx.SetDefaultGenerator(nil)
+	})
+
+	assert.Equal(t, gidx.PrefixedID("fixedpr-fixedfixedfixedfixed1"), gidx.MustNewID("fixedpr"))
+	assert.NotEqual(t, gidx.PrefixedID("otherpr-fixedfixedfixedfixed1"), gidx.MustNewID("otherpr"))
+
+	gidx.SetDefaultGenerator(gidx.NewTimeSortableGenerator(true))
+
+	before := time.Now().Add(-time.Millisecond)
+
+	ts, err := gidx.MustNewID("otherpr").Timestamp()
+	require.NoError(t, err)
+	assert.WithinDuration(t, before, ts, time.Second)
+
+	gidx.SetPrefixGenerator("fixedpr", nil)
+
+	assert.NotEqual(t, gidx.PrefixedID("fixedpr-fixedfixedfixedfixed1"), gidx.MustNewID("fixedpr"))
+}
@@ -17,6 +17,7 @@ package gidx
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"io"
 	"regexp"
@@ -78,6 +79,11 @@ func MustNewID(prefix string) PrefixedID {
 	return id
 }
 
+// ValidatePrefix returns an error if the prefix is not a valid PrefixedID prefix.
+func ValidatePrefix(prefix string) error {
+	return validPrefix(prefix)
+}
+
 func validPrefix(s string) error {
 	if len(s) <= PrefixPartMinLength {
 		return newErrInvalidID(fmt.Sprintf("expected prefix length is at least %d, '%s' is %d", PrefixPartMinLength, s, len(s)))
@@ -91,14 +97,15 @@ func validPrefix(s string) error {
 }
 
 // NewID will return a new PrefixedID with the given prefix and a generated ID value.
-// The ID value will be a 21 character nanoID value.
+// The ID value will be a 21 character nanoID value, generated by the generator set for the
+// prefix with SetPrefixGenerator, or the default generator set with SetDefaultGenerator.
 func NewID(prefix string) (PrefixedID, error) {
 	prefix = strings.ToLower(prefix)
 	if err := validPrefix(prefix); err != nil {
 		return "", err
 	}
 
-	id, err := newIDValue()
+	id, err := generatorFor(prefix).NewIDPart()
 	if err != nil {
 		return "", err
 	}
@@ -117,6 +124,24 @@ func newIDValue() (string, error) {
 	return id(), nil
 }
 
+func validIDPart(s string) error {
+	if len(s) != IDPartLength {
+		return newErrInvalidID(fmt.Sprintf("expected id part length is %d, '%s' is %d", IDPartLength, s, len(s)))
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isIDPartChar(s[i]) {
+			return newErrInvalidID(fmt.Sprintf("expected id part must only contain nanoid characters [A-Za-z0-9_-], '%s' does not", s))
+		}
+	}
+
+	return nil
+}
+
+func isIDPartChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
 func parts(str string) (string, string) {
 	if cnt := strings.Count(str, "-"); cnt == 0 {
 		return "", ""
@@ -128,8 +153,25 @@ func parts(str string) (string, string) {
 }
 
 // Parse reads in a string and returns a PrefixedID if the string is a properly
-// formatted PrefixedID value
+// formatted PrefixedID value. The prefix is validated against the default registry
+// using the mode set by SetPrefixValidationMode.
 func Parse(str string) (PrefixedID, error) {
+	return ParseWithOptions(str)
+}
+
+// ParseWithOptions reads in a string and returns a PrefixedID if the string is a properly
+// formatted PrefixedID value, validated with the provided options.
+func ParseWithOptions(str string, options ...ParseOption) (PrefixedID, error) {
+	opts := parseOptions{
+		registry:         defaultRegistry,
+		prefixValidation: CurrentPrefixValidationMode(),
+		idPartValidation: CurrentIDPartValidationMode(),
+	}
+
+	for _, opt := range options {
+		opt(&opts)
+	}
+
 	if str == "" {
 		return PrefixedID(""), nil
 	}
@@ -149,6 +191,16 @@ func Parse(str string) (PrefixedID, error) {
 		return "", newErrInvalidID("uuids are not valid prefix-ids")
 	}
 
+	if opts.idPartValidation == IDPartValidationStrict {
+		if err := validIDPart(id); err != nil {
+			return "", err
+		}
+	}
+
+	if opts.prefixValidation == PrefixValidationRegistered && !opts.registry.Registered(prefix) {
+		return "", newErrInvalidIDWrap(ErrUnknownPrefix, fmt.Sprintf("prefix '%s' is not registered", prefix))
+	}
+
 	return PrefixedID(str), nil
 }
 
@@ -168,25 +220,29 @@ func (p PrefixedID) Value() (driver.Value, error) {
 }
 
 // Scan implements sql.Scanner so PrefixedIDs can be read from databases
-// transparently. The value returned is not checked to ensure it's a
-// properly formatted PrefixedID.
+// transparently. The value is validated the same way as Parse, so it must be a properly
+// formatted PrefixedID and the ID part is validated using the mode set by SetIDPartValidationMode.
 func (p *PrefixedID) Scan(v any) error {
-	if v == nil {
-		*p = PrefixedID("")
-		return nil
-	}
+	var str string
 
 	switch src := v.(type) {
+	case nil:
 	case string:
-		*p = PrefixedID(src)
+		str = src
 	case []byte:
-		*p = PrefixedID(string(src))
+		str = string(src)
 	case PrefixedID:
-		*p = src
+		str = string(src)
 	default:
 		return ErrUnsupportedType
 	}
 
+	if _, err := Parse(str); err != nil {
+		return err
+	}
+
+	*p = PrefixedID(str)
+
 	return nil
 }
 
@@ -199,12 +255,32 @@ func (p PrefixedID) MarshalGQL(w io.Writer) {
 
 // UnmarshalGQL provides GraphQL unmarshaling so that PrefixedIDs can be parsed
 // in GraphQL requests transparently. Only input types that map to a string are supported.
+// The value is validated in the same way as Scan.
 func (p *PrefixedID) UnmarshalGQL(v interface{}) error {
 	return p.Scan(v)
 }
 
+// MarshalJSON implements json.Marshaler. The value is not checked, PrefixedIDs are
+// validated when they are read in by Parse, Scan and UnmarshalJSON.
+func (p PrefixedID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(p))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The value is validated in the same way as Scan.
+func (p *PrefixedID) UnmarshalJSON(b []byte) error {
+	var s string
+
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	return p.Scan(s)
+}
+
 // Verify interfaces are satisfied
 var (
-	_ driver.Valuer = PrefixedID("")
-	_ sql.Scanner   = (*PrefixedID)(nil)
+	_ driver.Valuer    = PrefixedID("")
+	_ sql.Scanner      = (*PrefixedID)(nil)
+	_ json.Marshaler   = PrefixedID("")
+	_ json.Unmarshaler = (*PrefixedID)(nil)
 )
@@ -16,7 +16,9 @@ package gidx_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -69,6 +71,15 @@ func TestPrefix(t *testing.T) {
 	assert.Equal(t, "testpre", id.Prefix())
 }
 
+func TestValidatePrefix(t *testing.T) {
+	var invalidErr *gidx.ErrInvalidID
+
+	assert.NoError(t, gidx.ValidatePrefix("testpre"))
+	assert.ErrorAs(t, gidx.ValidatePrefix("a"), &invalidErr)
+	assert.ErrorAs(t, gidx.ValidatePrefix("ALLCAPS"), &invalidErr)
+	assert.ErrorAs(t, gidx.ValidatePrefix("👹bad"), &invalidErr)
+}
+
 func TestID(t *testing.T) {
 	id, err := gidx.Parse("testpre-suffix")
 	assert.NoError(t, err)
@@ -135,9 +146,13 @@ func TestParsers(t *testing.T) {
 			t.Run(tt.name, func(t *testing.T) {
 				id := gidx.PrefixedID("")
 				err := id.Scan(tt.id)
-				// scan should never return an error, if it's in the database treat it like it's valid
-				assert.NoError(t, err)
-				assert.Equal(t, tt.id, string(id))
+				// scan validates the same way as parse, legacy id parts are accepted in the default lenient mode
+				if tt.errorMsg == "" {
+					assert.NoError(t, err)
+					assert.Equal(t, tt.id, string(id))
+				} else {
+					assert.ErrorContains(t, err, tt.errorMsg)
+				}
 			})
 		}
 	})
@@ -151,3 +166,135 @@ func TestMarshalGQL(t *testing.T) {
 	id.MarshalGQL(&b)
 	assert.Equal(t, fmt.Sprintf(`"%s"`, string(id)), b.String())
 }
+
+func setIDPartValidationMode(t *testing.T, mode gidx.IDPartValidationMode) {
+	t.Helper()
+
+	previous := gidx.CurrentIDPartValidationMode()
+
+	gidx.SetIDPartValidationMode(mode)
+
+	t.Cleanup(func() {
+		gidx.SetIDPartValidationMode(previous)
+	})
+}
+
+func TestStrictIDPartValidation(t *testing.T) {
+	setIDPartValidationMode(t, gidx.IDPartValidationStrict)
+
+	cases := []struct {
+		name     string
+		id       string
+		errorMsg string
+	}{
+		{name: "valid id: null id should be valid", id: ""},
+		{name: "valid id", id: string(gidx.MustNewID("testing"))},
+		{name: "valid id with separators in id part", id: "testing-fm21VlAHH-Gf6utn1Js_c"},
+		{name: "invalid id; id part too short", id: "testing-suffix", errorMsg: "invalid id: expected id part length is 21"},
+		{name: "invalid id; id part too long", id: "testing-fm21VlAHHrGf6utn1JsKcX", errorMsg: "invalid id: expected id part length is 21"},
+		{name: "invalid id; id part with a uuid", id: "testing-" + uuid.New().String(), errorMsg: "invalid id: expected id part length is 21"},
+		{name: "invalid id; id part invalid characters", id: "testing-fm21VlAHHrGf6u#n1JsKc", errorMsg: "invalid id: expected id part must only contain nanoid characters"},
+		{name: "invalid id; unicode id part", id: "testing-fm21VlAHHrGf6u👹1Js", errorMsg: "invalid id: expected id part must only contain nanoid characters"},
+		{name: "invalid id; no separator", id: "somestringthatisalltogether", errorMsg: "invalid id: expected id format is prefix-id"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, parseErr := gidx.Parse(tt.id)
+
+			var scanned gidx.PrefixedID
+
+			scanErr := scanned.Scan(tt.id)
+
+			var gql gidx.PrefixedID
+
+			gqlErr := gql.UnmarshalGQL(tt.id)
+
+			var decoded gidx.PrefixedID
+
+			jsonErr := json.Unmarshal([]byte(strconv.Quote(tt.id)), &decoded)
+
+			_, marshalErr := json.Marshal(gidx.PrefixedID(tt.id))
+
+			// marshaling never validates
+			assert.NoError(t, marshalErr)
+
+			_, lenientErr := gidx.ParseWithOptions(tt.id, gidx.WithIDPartValidation(gidx.IDPartValidationLenient))
+
+			if tt.errorMsg == "" {
+				assert.NoError(t, parseErr)
+				assert.NoError(t, scanErr)
+				assert.NoError(t, gqlErr)
+				assert.NoError(t, jsonErr)
+				assert.Equal(t, tt.id, decoded.String())
+			} else {
+				assert.ErrorContains(t, parseErr, tt.errorMsg)
+				assert.ErrorContains(t, scanErr, tt.errorMsg)
+				assert.ErrorContains(t, gqlErr, tt.errorMsg)
+				assert.ErrorContains(t, jsonErr, tt.errorMsg)
+			}
+
+			// lenient parsing only rejects invalid formats
+			if tt.name == "invalid id; no separator" {
+				assert.Error(t, lenientErr)
+			} else {
+				assert.NoError(t, lenientErr)
+			}
+		})
+	}
+}
+
+func TestLenientJSON(t *testing.T) {
+	setIDPartValidationMode(t, gidx.IDPartValidationLenient)
+
+	var id gidx.PrefixedID
+
+	require.NoError(t, json.Unmarshal([]byte(`"unknown-actor"`), &id))
+	assert.Equal(t, gidx.PrefixedID("unknown-actor"), id)
+
+	b, err := json.Marshal(gidx.PrefixedID("legacy"))
+	require.NoError(t, err)
+	assert.Equal(t, `"legacy"`, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`1`), &id))
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add("")
+	f.Add("testing-fm21VlAHHrGf6utn1JsKc")
+	f.Add("testing-suffix")
+	f.Add("-strings")
+	f.Add("a-fm21VlAHHrGf6utn1JsKc")
+	f.Add(uuid.New().String())
+	f.Add("testing-fm21VlAHH-Gf6utn1Js_c")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		lenient, lenientErr := gidx.ParseWithOptions(s, gidx.WithIDPartValidation(gidx.IDPartValidationLenient))
+		strict, strictErr := gidx.ParseWithOptions(s, gidx.WithIDPartValidation(gidx.IDPartValidationStrict))
+
+		if strictErr == nil {
+			// strict ids are always valid lenient ids
+			require.NoError(t, lenientErr)
+			require.Equal(t, lenient, strict)
+
+			if s == "" {
+				return
+			}
+
+			require.Len(t, strict.ID(), gidx.IDPartLength)
+			require.Equal(t, s, strict.Prefix()+"-"+strict.ID())
+
+			b, err := json.Marshal(strict)
+			require.NoError(t, err)
+
+			var decoded gidx.PrefixedID
+
+			require.NoError(t, json.Unmarshal(b, &decoded))
+			require.Equal(t, strict, decoded)
+		}
+
+		if lenientErr == nil {
+			require.Equal(t, gidx.PrefixedID(s), lenient)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// pgxTypeMaps holds pgtype Maps with the gidx types registered, a Map is not safe for concurrent use.
+var pgxTypeMaps = sync.Pool{
+	New: func() any {
+		m := pgtype.NewMap()
+
+		RegisterPgxTypes(m)
+
+		return m
+	},
+}
+
+// PrefixedIDs is a list of PrefixedIDs which can be read from and written to postgres text[] columns
+// through database/sql. With pgx, []PrefixedID may also be used directly, see RegisterPgxTypes.
+type PrefixedIDs []PrefixedID
+
+// Value implements sql.Valuer so PrefixedIDs are written as a postgres array. Each PrefixedID is
+// validated in the same way as PrefixedID.Value. A nil list is written as NULL.
+func (p PrefixedIDs) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	m := pgxTypeMaps.Get().(*pgtype.Map)
+	defer pgxTypeMaps.Put(m)
+
+	buf, err := m.Encode(pgtype.TextArrayOID, pgtype.TextFormatCode, p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(buf), nil
+}
+
+// Scan implements sql.Scanner so PrefixedIDs can be read from postgres arrays.
+// Each PrefixedID is validated in the same way as PrefixedID.Scan, NULL elements are scanned as NullPrefixedID.
+func (p *PrefixedIDs) Scan(v any) error {
+	var src []byte
+
+	switch s := v.(type) {
+	case nil:
+		*p = nil
+
+		return nil
+	case string:
+		src = []byte(s)
+	case []byte:
+		src = s
+	default:
+		return ErrUnsupportedType
+	}
+
+	m := pgxTypeMaps.Get().(*pgtype.Map)
+	defer pgxTypeMaps.Put(m)
+
+	return m.Scan(pgtype.TextArrayOID, pgtype.TextFormatCode, src, p)
+}
+
+// Verify interfaces are satisfied
+var (
+	_ driver.Valuer = PrefixedIDs(nil)
+	_ sql.Scanner   = (*PrefixedIDs)(nil)
+)
@@ -0,0 +1,92 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Namespace derives deterministic ID parts from names, so the same name always results in the same ID.
+// This allows imports and sync jobs to be re-run without storing a mapping of external keys to IDs.
+//
+// ID parts are the HMAC-SHA256 of the namespace and name, keyed with the namespace key, encoded into
+// IDPartLength characters of the nanoid alphabet. The key should be kept secret if IDs must not be
+// predictable from the names.
+type Namespace struct {
+	key       []byte
+	namespace string
+}
+
+// NewNamespace creates a new Namespace. The same key and namespace must be used to derive the same IDs.
+func NewNamespace(key []byte, namespace string) Namespace {
+	return Namespace{
+		key:       append([]byte(nil), key...),
+		namespace: namespace,
+	}
+}
+
+// IDPart returns the ID part derived from the name.
+func (n Namespace) IDPart(name string) string {
+	mac := hmac.New(sha256.New, n.key)
+
+	// The namespace is length prefixed so namespace and name pairs can not collide by moving characters between them.
+	mac.Write(binary.AppendUvarint(nil, uint64(len(n.namespace)))) //nolint:errcheck // hash writes never fail
+	mac.Write([]byte(n.namespace))                                 //nolint:errcheck // hash writes never fail
+	mac.Write([]byte(name))                                        //nolint:errcheck // hash writes never fail
+
+	sum := mac.Sum(nil)
+
+	var b [IDPartLength]byte
+
+	// Take 6 bits of the digest for each character, 21 characters use 126 of the 256 bits.
+	for i := range b {
+		bit := i * sortableBits
+		word := uint16(sum[bit/8])<<8 | uint16(sum[bit/8+1])
+
+		b[i] = sortableAlphabet[(word>>(16-sortableBits-bit%8))&sortableMask]
+	}
+
+	return string(b[:])
+}
+
+// NewID returns the PrefixedID with the given prefix and the ID part derived from the name.
+func (n Namespace) NewID(prefix, name string) (PrefixedID, error) {
+	prefix = strings.ToLower(prefix)
+	if err := validPrefix(prefix); err != nil {
+		return "", err
+	}
+
+	return PrefixedID(fmt.Sprintf("%s-%s", prefix, n.IDPart(name))), nil
+}
+
+// MustNewID wraps NewID and panics in the event of an error
+func (n Namespace) MustNewID(prefix, name string) PrefixedID {
+	id, err := n.NewID(prefix, name)
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+// NewNamespacedID returns the PrefixedID with the given prefix and the ID part derived from the
+// namespace and name using key. See Namespace for details.
+func NewNamespacedID(prefix string, key []byte, namespace, name string) (PrefixedID, error) {
+	return NewNamespace(key, namespace).NewID(prefix, name)
+}
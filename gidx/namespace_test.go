@@ -0,0 +1,59 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/gidx"
+)
+
+func TestNamespace(t *testing.T) {
+	key := []byte("test-key")
+
+	ns := gidx.NewNamespace(key, "billing-import")
+
+	id, err := ns.NewID("TESTING", "customer/1234")
+	require.NoError(t, err)
+	assert.Equal(t, "testing", id.Prefix())
+	assert.Regexp(t, idPartRegexp, id.ID())
+
+	// ids must be stable across runs
+	assert.Equal(t, gidx.PrefixedID("testing-WibDyvTVb2uIueUmtIEia"), id)
+
+	again, err := gidx.NewNamespacedID("testing", key, "billing-import", "customer/1234")
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	_, err = gidx.ParseWithOptions(id.String(), gidx.WithIDPartValidation(gidx.IDPartValidationStrict))
+	require.NoError(t, err)
+
+	assert.NotEqual(t, id.ID(), ns.IDPart("customer/1235"))
+	assert.NotEqual(t, id.ID(), gidx.NewNamespace(key, "other-import").IDPart("customer/1234"))
+	assert.NotEqual(t, id.ID(), gidx.NewNamespace([]byte("other-key"), "billing-import").IDPart("customer/1234"))
+
+	// moving characters between the namespace and name results in a different id
+	assert.NotEqual(t, gidx.NewNamespace(key, "ab").IDPart("c"), gidx.NewNamespace(key, "a").IDPart("bc"))
+
+	_, err = ns.NewID("a", "customer/1234")
+	assert.ErrorContains(t, err, "invalid id: expected prefix length is at least 2")
+
+	assert.Panics(t, func() {
+		ns.MustNewID("a", "customer/1234")
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx
+
+import "sync/atomic"
+
+// PrefixValidationMode controls how prefixes are validated against a Registry when parsing.
+type PrefixValidationMode int32
+
+const (
+	// PrefixValidationFormat only validates the format of the prefix. This is the default mode.
+	PrefixValidationFormat PrefixValidationMode = iota
+	// PrefixValidationRegistered rejects prefixes which are not registered.
+	PrefixValidationRegistered
+)
+
+var defaultPrefixValidationMode atomic.Int32
+
+// SetPrefixValidationMode sets the prefix validation mode used by Parse.
+func SetPrefixValidationMode(mode PrefixValidationMode) {
+	defaultPrefixValidationMode.Store(int32(mode))
+}
+
+// CurrentPrefixValidationMode returns the prefix validation mode used by Parse.
+func CurrentPrefixValidationMode() PrefixValidationMode {
+	return PrefixValidationMode(defaultPrefixValidationMode.Load())
+}
+
+// IDPartValidationMode controls how the ID part of a PrefixedID is validated when parsing.
+type IDPartValidationMode int32
+
+const (
+	// IDPartValidationLenient accepts any non-empty ID part, for legacy data which predates strict validation.
+	// This is the default mode so existing IDs, such as the unknown-actor placeholder, continue to be accepted.
+	IDPartValidationLenient IDPartValidationMode = iota
+	// IDPartValidationStrict requires the ID part to be IDPartLength characters of the nanoid alphabet.
+	// Enable it with SetIDPartValidationMode once stored IDs have been migrated.
+	IDPartValidationStrict
+)
+
+var defaultIDPartValidationMode atomic.Int32
+
+// SetIDPartValidationMode sets the ID part validation mode used by Parse, Scan, UnmarshalGQL and UnmarshalJSON.
+func SetIDPartValidationMode(mode IDPartValidationMode) {
+	defaultIDPartValidationMode.Store(int32(mode))
+}
+
+// CurrentIDPartValidationMode returns the ID part validation mode used by Parse, Scan, UnmarshalGQL and UnmarshalJSON.
+func CurrentIDPartValidationMode() IDPartValidationMode {
+	return IDPartValidationMode(defaultIDPartValidationMode.Load())
+}
+
+type parseOptions struct {
+	registry         *Registry
+	prefixValidation PrefixValidationMode
+	idPartValidation IDPartValidationMode
+}
+
+// ParseOption configures ParseWithOptions.
+type ParseOption func(o *parseOptions)
+
+// WithRegistry sets the registry prefixes are validated against, defaults to DefaultRegistry.
+func WithRegistry(registry *Registry) ParseOption {
+	return func(o *parseOptions) {
+		o.registry = registry
+	}
+}
+
+// WithIDPartValidation sets the ID part validation mode, defaults to the mode set by SetIDPartValidationMode.
+func WithIDPartValidation(mode IDPartValidationMode) ParseOption {
+	return func(o *parseOptions) {
+		o.idPartValidation = mode
+	}
+}
+
+// WithPrefixValidation sets the prefix validation mode, defaults to the mode set by SetPrefixValidationMode.
+func WithPrefixValidation(mode PrefixValidationMode) ParseOption {
+	return func(o *parseOptions) {
+		o.prefixValidation = mode
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RegisterPgxTypes registers the PrefixedIDCodec for the text and varchar types, and their arrays, with the pgx
+// type map. PrefixedID, []PrefixedID and PrefixedIDs are also registered as text and text[] values so they are
+// encoded natively when the parameter type is unknown.
+func RegisterPgxTypes(m *pgtype.Map) {
+	for _, t := range []struct {
+		name     string
+		oid      uint32
+		arrayOID uint32
+	}{
+		{"text", pgtype.TextOID, pgtype.TextArrayOID},
+		{"varchar", pgtype.VarcharOID, pgtype.VarcharArrayOID},
+	} {
+		elementType := &pgtype.Type{Name: t.name, OID: t.oid, Codec: PrefixedIDCodec{}}
+
+		m.RegisterType(elementType)
+		m.RegisterType(&pgtype.Type{Name: "_" + t.name, OID: t.arrayOID, Codec: &pgtype.ArrayCodec{ElementType: elementType}})
+	}
+
+	m.RegisterDefaultPgType(PrefixedID(""), "text")
+	m.RegisterDefaultPgType([]PrefixedID(nil), "_text")
+	m.RegisterDefaultPgType(PrefixedIDs(nil), "_text")
+}
+
+// PgxAfterConnect registers the gidx pgx types on a new connection.
+// It may be used as the pgxpool.Config AfterConnect func.
+func PgxAfterConnect(_ context.Context, conn *pgx.Conn) error {
+	RegisterPgxTypes(conn.TypeMap())
+
+	return nil
+}
+
+// PrefixedIDCodec is a pgtype.Codec for text values which encodes and scans PrefixedIDs in both the text and
+// binary formats. Values are validated in the same way as PrefixedID Value and Scan. All other values are
+// handled by pgtype.TextCodec.
+type PrefixedIDCodec struct {
+	pgtype.TextCodec
+}
+
+// PlanEncode implements pgtype.Codec.
+func (c PrefixedIDCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value any) pgtype.EncodePlan {
+	if _, ok := value.(PrefixedID); ok && c.FormatSupported(format) {
+		return encodePlanPrefixedID{}
+	}
+
+	return c.TextCodec.PlanEncode(m, oid, format, value)
+}
+
+// PlanScan implements pgtype.Codec.
+func (c PrefixedIDCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target any) pgtype.ScanPlan {
+	if _, ok := target.(*PrefixedID); ok && c.FormatSupported(format) {
+		return scanPlanPrefixedID{}
+	}
+
+	return c.TextCodec.PlanScan(m, oid, format, target)
+}
+
+// DecodeDatabaseSQLValue implements pgtype.Codec.
+func (c PrefixedIDCodec) DecodeDatabaseSQLValue(m *pgtype.Map, oid uint32, format int16, src []byte) (driver.Value, error) {
+	return c.TextCodec.DecodeDatabaseSQLValue(m, oid, format, src)
+}
+
+// DecodeValue implements pgtype.Codec.
+func (c PrefixedIDCodec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (any, error) {
+	return c.TextCodec.DecodeValue(m, oid, format, src)
+}
+
+// encodePlanPrefixedID encodes a PrefixedID, the text and binary formats of text values are the same.
+type encodePlanPrefixedID struct{}
+
+func (encodePlanPrefixedID) Encode(value any, buf []byte) ([]byte, error) {
+	id := value.(PrefixedID)
+
+	if _, err := Parse(string(id)); err != nil {
+		return nil, err
+	}
+
+	return append(buf, id...), nil
+}
+
+// scanPlanPrefixedID scans a PrefixedID, the text and binary formats of text values are the same.
+type scanPlanPrefixedID struct{}
+
+func (scanPlanPrefixedID) Scan(src []byte, dst any) error {
+	id := dst.(*PrefixedID)
+
+	if src == nil {
+		*id = NullPrefixedID
+
+		return nil
+	}
+
+	return id.Scan(string(src))
+}
+
+// Dimensions implements pgtype.ArrayGetter so pgx encodes PrefixedIDs as an array in both text and binary formats.
+func (p PrefixedIDs) Dimensions() []pgtype.ArrayDimension {
+	if p == nil {
+		return nil
+	}
+
+	return []pgtype.ArrayDimension{{Length: int32(len(p)), LowerBound: 1}} //nolint:gosec // slice length fits in int32
+}
+
+// Index implements pgtype.ArrayGetter.
+func (p PrefixedIDs) Index(i int) any {
+	return p[i]
+}
+
+// IndexType implements pgtype.ArrayGetter.
+func (p PrefixedIDs) IndexType() any {
+	return NullPrefixedID
+}
+
+// SetDimensions implements pgtype.ArraySetter so pgx scans arrays into PrefixedIDs in both text and binary formats.
+func (p *PrefixedIDs) SetDimensions(dimensions []pgtype.ArrayDimension) error {
+	if dimensions == nil {
+		*p = nil
+
+		return nil
+	}
+
+	if len(dimensions) > 1 {
+		return fmt.Errorf("%w: multi-dimensional arrays are not supported", ErrUnsupportedType)
+	}
+
+	length := 0
+
+	if len(dimensions) == 1 {
+		length = int(dimensions[0].Length)
+	}
+
+	*p = make(PrefixedIDs, length)
+
+	return nil
+}
+
+// ScanIndex implements pgtype.ArraySetter.
+func (p PrefixedIDs) ScanIndex(i int) any {
+	return &p[i]
+}
+
+// ScanIndexType implements pgtype.ArraySetter.
+func (p PrefixedIDs) ScanIndexType() any {
+	return new(PrefixedID)
+}
+
+// Verify interfaces are satisfied
+var (
+	_ pgtype.Codec       = PrefixedIDCodec{}
+	_ pgtype.ArrayGetter = PrefixedIDs(nil)
+	_ pgtype.ArraySetter = (*PrefixedIDs)(nil)
+)
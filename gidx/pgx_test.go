@@ -0,0 +1,172 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx_test
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/gidx"
+)
+
+func TestPgxTypes(t *testing.T) {
+	m := pgtype.NewMap()
+	gidx.RegisterPgxTypes(m)
+
+	id1 := gidx.MustNewID("testing")
+	id2 := gidx.MustNewID("testing")
+
+	t.Run("default pg types", func(t *testing.T) {
+		for _, v := range []any{id1, []gidx.PrefixedID{id1}, gidx.PrefixedIDs{id1}} {
+			_, ok := m.TypeForValue(v)
+			assert.True(t, ok, "%T", v)
+		}
+	})
+
+	t.Run("prefixed id", func(t *testing.T) {
+		for _, format := range []int16{pgtype.TextFormatCode, pgtype.BinaryFormatCode} {
+			buf, err := m.Encode(pgtype.TextOID, format, id1, nil)
+			require.NoError(t, err)
+
+			var scanned gidx.PrefixedID
+
+			require.NoError(t, m.Scan(pgtype.TextOID, format, buf, &scanned))
+			assert.Equal(t, id1, scanned)
+		}
+
+		_, err := m.Encode(pgtype.TextOID, pgtype.TextFormatCode, gidx.PrefixedID("invalid"), nil)
+		assert.ErrorContains(t, err, "invalid id")
+	})
+
+	t.Run("codec", func(t *testing.T) {
+		for _, oid := range []uint32{pgtype.TextOID, pgtype.VarcharOID, pgtype.TextArrayOID, pgtype.VarcharArrayOID} {
+			pgType, ok := m.TypeForOID(oid)
+			require.True(t, ok)
+
+			if arrayCodec, ok := pgType.Codec.(*pgtype.ArrayCodec); ok {
+				assert.IsType(t, gidx.PrefixedIDCodec{}, arrayCodec.ElementType.Codec, pgType.Name)
+			} else {
+				assert.IsType(t, gidx.PrefixedIDCodec{}, pgType.Codec, pgType.Name)
+			}
+		}
+
+		// Other text values are handled by the text codec.
+		buf, err := m.Encode(pgtype.TextOID, pgtype.BinaryFormatCode, "some text", nil)
+		require.NoError(t, err)
+
+		var scanned string
+
+		require.NoError(t, m.Scan(pgtype.TextOID, pgtype.BinaryFormatCode, buf, &scanned))
+		assert.Equal(t, "some text", scanned)
+
+		var id gidx.PrefixedID
+
+		require.NoError(t, m.Scan(pgtype.TextOID, pgtype.BinaryFormatCode, nil, &id))
+		assert.Equal(t, gidx.NullPrefixedID, id)
+
+		assert.ErrorContains(t, m.Scan(pgtype.VarcharOID, pgtype.BinaryFormatCode, []byte("invalid"), &id), "invalid id")
+	})
+
+	t.Run("prefixed id arrays", func(t *testing.T) {
+		for _, oid := range []uint32{pgtype.TextArrayOID, pgtype.VarcharArrayOID} {
+			for _, format := range []int16{pgtype.TextFormatCode, pgtype.BinaryFormatCode} {
+				buf, err := m.Encode(oid, format, []gidx.PrefixedID{id1, id2}, nil)
+				require.NoError(t, err)
+
+				var scanned []gidx.PrefixedID
+
+				require.NoError(t, m.Scan(oid, format, buf, &scanned))
+				assert.Equal(t, []gidx.PrefixedID{id1, id2}, scanned)
+
+				var scannedIDs gidx.PrefixedIDs
+
+				require.NoError(t, m.Scan(oid, format, buf, &scannedIDs))
+				assert.Equal(t, gidx.PrefixedIDs{id1, id2}, scannedIDs)
+			}
+		}
+
+		_, err := m.Encode(pgtype.TextArrayOID, pgtype.BinaryFormatCode, []gidx.PrefixedID{id1, "invalid"}, nil)
+		assert.ErrorContains(t, err, "invalid id")
+	})
+}
+
+func TestPrefixedIDs(t *testing.T) {
+	id1 := gidx.MustNewID("testing")
+	id2 := gidx.MustNewID("testing")
+
+	t.Run("value", func(t *testing.T) {
+		v, err := gidx.PrefixedIDs{id1, id2}.Value()
+		require.NoError(t, err)
+		assert.Equal(t, "{"+id1.String()+","+id2.String()+"}", v)
+
+		v, err = gidx.PrefixedIDs{}.Value()
+		require.NoError(t, err)
+		assert.Equal(t, "{}", v)
+
+		v, err = gidx.PrefixedIDs(nil).Value()
+		require.NoError(t, err)
+		assert.Nil(t, v)
+
+		_, err = gidx.PrefixedIDs{"invalid"}.Value()
+		assert.ErrorContains(t, err, "invalid id")
+	})
+
+	cases := []struct {
+		name     string
+		src      any
+		want     gidx.PrefixedIDs
+		errorMsg string
+	}{
+		{name: "null", src: nil, want: nil},
+		{name: "empty", src: "{}", want: gidx.PrefixedIDs{}},
+		{name: "unquoted", src: "{" + id1.String() + "," + id2.String() + "}", want: gidx.PrefixedIDs{id1, id2}},
+		{name: "quoted bytes", src: []byte(`{"` + id1.String() + `","` + id2.String() + `"}`), want: gidx.PrefixedIDs{id1, id2}},
+		{name: "escaped", src: `{"testing-a\"b\\c"}`, want: gidx.PrefixedIDs{`testing-a"b\c`}},
+		{name: "null element", src: "{" + id1.String() + ",NULL}", want: gidx.PrefixedIDs{id1, gidx.NullPrefixedID}},
+		{name: "not an array", src: id1.String(), errorMsg: "invalid array"},
+		{name: "multi-dimensional", src: "{{a},{b}}", errorMsg: "multi-dimensional arrays are not supported"},
+		{name: "unterminated quote", src: `{"testing-abc}`, errorMsg: "invalid array"},
+		{name: "unsupported type", src: 1, errorMsg: gidx.ErrUnsupportedType.Error()},
+	}
+
+	for _, tt := range cases {
+		t.Run("scan "+tt.name, func(t *testing.T) {
+			setIDPartValidationMode(t, gidx.IDPartValidationLenient)
+
+			var ids gidx.PrefixedIDs
+
+			err := ids.Scan(tt.src)
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+
+	t.Run("scan strict", func(t *testing.T) {
+		setIDPartValidationMode(t, gidx.IDPartValidationStrict)
+
+		var ids gidx.PrefixedIDs
+
+		assert.ErrorContains(t, ids.Scan("{testing-short}"), "expected id part length")
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PrefixInfo describes the resource type a prefix represents.
+type PrefixInfo struct {
+	// Prefix is the ID prefix, such as loadbal.
+	Prefix string
+	// TypeName is the name of the resource type, such as LoadBalancer.
+	TypeName string
+	// Service is the name of the service which owns the resource type.
+	Service string
+	// Description is a short description of the resource type.
+	Description string
+}
+
+// Registry maps prefixes to the resource types they represent.
+// Prefixes and type names must be unique within a registry.
+type Registry struct {
+	mu       sync.RWMutex
+	prefixes map[string]PrefixInfo
+	types    map[string]PrefixInfo
+}
+
+// NewRegistry creates a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		prefixes: make(map[string]PrefixInfo),
+		types:    make(map[string]PrefixInfo),
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry used by the package level prefix functions and Parse.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds the prefix to the registry.
+// ErrPrefixCollision is returned if the prefix or type name is already registered with different details,
+// registering identical details more than once is allowed.
+func (r *Registry) Register(info PrefixInfo) error {
+	info.Prefix = strings.ToLower(info.Prefix)
+
+	if err := validPrefix(info.Prefix); err != nil {
+		return err
+	}
+
+	if info.TypeName == "" {
+		return fmt.Errorf("%w: prefix %s", ErrMissingTypeName, info.Prefix)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.prefixes[info.Prefix]; ok {
+		if existing == info {
+			return nil
+		}
+
+		return fmt.Errorf("%w: prefix %s is registered to %s (%s), cannot register %s (%s)",
+			ErrPrefixCollision, info.Prefix, existing.TypeName, existing.Service, info.TypeName, info.Service)
+	}
+
+	if existing, ok := r.types[info.TypeName]; ok {
+		return fmt.Errorf("%w: type %s is registered with prefix %s, cannot register prefix %s",
+			ErrPrefixCollision, info.TypeName, existing.Prefix, info.Prefix)
+	}
+
+	r.prefixes[info.Prefix] = info
+	r.types[info.TypeName] = info
+
+	return nil
+}
+
+// MustRegister wraps Register and panics in the event of an error.
+// It is intended to be called from init so collisions are detected at startup.
+func (r *Registry) MustRegister(info PrefixInfo) {
+	if err := r.Register(info); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the details registered for the prefix.
+func (r *Registry) Lookup(prefix string) (PrefixInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, ok := r.prefixes[prefix]
+
+	return info, ok
+}
+
+// LookupID returns the details registered for the prefix of the id.
+func (r *Registry) LookupID(id PrefixedID) (PrefixInfo, bool) {
+	return r.Lookup(id.Prefix())
+}
+
+// LookupType returns the details registered for the type name.
+func (r *Registry) LookupType(typeName string) (PrefixInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, ok := r.types[typeName]
+
+	return info, ok
+}
+
+// Registered reports whether the prefix is registered.
+func (r *Registry) Registered(prefix string) bool {
+	_, ok := r.Lookup(prefix)
+
+	return ok
+}
+
+// Prefixes returns the details of all registered prefixes sorted by prefix.
+func (r *Registry) Prefixes() []PrefixInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]PrefixInfo, 0, len(r.prefixes))
+
+	for _, info := range r.prefixes {
+		infos = append(infos, info)
+	}
+
+	slices.SortFunc(infos, func(a, b PrefixInfo) int {
+		return strings.Compare(a.Prefix, b.Prefix)
+	})
+
+	return infos
+}
+
+// RegisterPrefix adds the prefix to the default registry.
+func RegisterPrefix(info PrefixInfo) error {
+	return defaultRegistry.Register(info)
+}
+
+// MustRegisterPrefix adds the prefix to the default registry and panics in the event of an error.
+// It is intended to be called from init so collisions are detected at startup.
+func MustRegisterPrefix(info PrefixInfo) {
+	defaultRegistry.MustRegister(info)
+}
+
+// LookupPrefix returns the details registered for the prefix in the default registry.
+func LookupPrefix(prefix string) (PrefixInfo, bool) {
+	return defaultRegistry.Lookup(prefix)
+}
+
+// LookupType returns the details registered for the type name in the default registry.
+func LookupType(typeName string) (PrefixInfo, bool) {
+	return defaultRegistry.LookupType(typeName)
+}
+
+// RegisteredPrefixes returns the details of all prefixes in the default registry sorted by prefix.
+func RegisteredPrefixes() []PrefixInfo {
+	return defaultRegistry.Prefixes()
+}
+
+// PrefixInfo returns the details registered for the prefix of the id in the default registry.
+func (p PrefixedID) PrefixInfo() (PrefixInfo, bool) {
+	return defaultRegistry.LookupID(p)
+}
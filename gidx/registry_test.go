@@ -0,0 +1,117 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/gidx"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := gidx.NewRegistry()
+
+	loadBalancer := gidx.PrefixInfo{
+		Prefix:      "loadbal",
+		TypeName:    "LoadBalancer",
+		Service:     "load-balancer-api",
+		Description: "A load balancer",
+	}
+
+	require.NoError(t, registry.Register(loadBalancer))
+
+	// registering the same details again is allowed
+	require.NoError(t, registry.Register(loadBalancer))
+
+	err := registry.Register(gidx.PrefixInfo{Prefix: "loadbal", TypeName: "LoadBalancerPool", Service: "other-api"})
+	require.ErrorIs(t, err, gidx.ErrPrefixCollision)
+	assert.ErrorContains(t, err, "prefix loadbal is registered to LoadBalancer (load-balancer-api)")
+
+	err = registry.Register(gidx.PrefixInfo{Prefix: "loadbpl", TypeName: "LoadBalancer"})
+	require.ErrorIs(t, err, gidx.ErrPrefixCollision)
+
+	err = registry.Register(gidx.PrefixInfo{Prefix: "tnntten"})
+	require.ErrorIs(t, err, gidx.ErrMissingTypeName)
+
+	err = registry.Register(gidx.PrefixInfo{Prefix: "a", TypeName: "Short"})
+	assert.ErrorContains(t, err, "invalid id: expected prefix length is at least 2")
+
+	assert.Panics(t, func() {
+		registry.MustRegister(gidx.PrefixInfo{Prefix: "loadbal", TypeName: "Other"})
+	})
+
+	registry.MustRegister(gidx.PrefixInfo{Prefix: "TNNTTEN", TypeName: "Tenant", Service: "tenant-api"})
+
+	info, ok := registry.Lookup("tnntten")
+	require.True(t, ok)
+	assert.Equal(t, "Tenant", info.TypeName)
+
+	info, ok = registry.LookupID(gidx.PrefixedID("loadbal-fm21VlAHHrGf6utn1JsKc"))
+	require.True(t, ok)
+	assert.Equal(t, loadBalancer, info)
+
+	info, ok = registry.LookupType("Tenant")
+	require.True(t, ok)
+	assert.Equal(t, "tnntten", info.Prefix)
+
+	_, ok = registry.Lookup("unknown")
+	assert.False(t, ok)
+
+	prefixes := registry.Prefixes()
+	require.Len(t, prefixes, 2)
+	assert.Equal(t, "loadbal", prefixes[0].Prefix)
+	assert.Equal(t, "tnntten", prefixes[1].Prefix)
+}
+
+func TestParsePrefixValidation(t *testing.T) {
+	registry := gidx.NewRegistry()
+	registry.MustRegister(gidx.PrefixInfo{Prefix: "testreg", TypeName: "Registered"})
+
+	_, err := gidx.ParseWithOptions("testreg-fm21VlAHHrGf6utn1JsKc", gidx.WithRegistry(registry), gidx.WithPrefixValidation(gidx.PrefixValidationRegistered))
+	require.NoError(t, err)
+
+	_, err = gidx.ParseWithOptions("unknown-fm21VlAHHrGf6utn1JsKc", gidx.WithRegistry(registry), gidx.WithPrefixValidation(gidx.PrefixValidationRegistered))
+	require.ErrorIs(t, err, gidx.ErrUnknownPrefix)
+	assert.ErrorContains(t, err, "invalid id: prefix 'unknown' is not registered")
+
+	var invalidErr *gidx.ErrInvalidID
+
+	require.ErrorAs(t, err, &invalidErr)
+
+	_, err = gidx.ParseWithOptions("unknown-fm21VlAHHrGf6utn1JsKc", gidx.WithRegistry(registry))
+	require.NoError(t, err)
+
+	// the default registry and mode are used by Parse
+	gidx.MustRegisterPrefix(gidx.PrefixInfo{Prefix: "testdef", TypeName: "TestDefault", Service: "gidx-tests"})
+
+	gidx.SetPrefixValidationMode(gidx.PrefixValidationRegistered)
+
+	t.Cleanup(func() {
+		gidx.SetPrefixValidationMode(gidx.PrefixValidationFormat)
+	})
+
+	_, err = gidx.Parse("testdef-fm21VlAHHrGf6utn1JsKc")
+	require.NoError(t, err)
+
+	_, err = gidx.Parse("unknown-fm21VlAHHrGf6utn1JsKc")
+	require.ErrorIs(t, err, gidx.ErrUnknownPrefix)
+
+	info, ok := gidx.PrefixedID("testdef-fm21VlAHHrGf6utn1JsKc").PrefixInfo()
+	require.True(t, ok)
+	assert.Equal(t, "gidx-tests", info.Service)
+}
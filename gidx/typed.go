@@ -0,0 +1,202 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// PrefixProvider provides the prefix of a TypedID. Implementations are expected to be empty
+// struct types so the zero value provides the prefix, for example:
+//
+//	type TenantPrefix struct{}
+//
+//	func (TenantPrefix) Prefix() string { return "tnntten" }
+//
+//	type TenantID = gidx.TypedID[TenantPrefix]
+type PrefixProvider interface {
+	Prefix() string
+}
+
+// TypedID is a PrefixedID which may only hold IDs with the prefix provided by P.
+// Using distinct TypedIDs ensures an ID of one resource type can not be passed where
+// another is expected. The prefix is enforced when parsing, scanning and decoding.
+//
+// TypedID implements sql.Scanner and driver.Valuer so it may be used as an ent field GoType,
+// and graphql.Marshaler and graphql.Unmarshaler so it may be used as a gqlgen scalar.
+//
+// gqlgen can not bind a generic type directly, so each instantiation needs a named alias
+// which is then bound as the model of a scalar in gqlgen.yml, for example:
+//
+//	// package example.com/app/ids
+//	type TenantID = gidx.TypedID[TenantPrefix]
+//
+//	# gqlgen.yml
+//	models:
+//	  TenantID:
+//	    model: example.com/app/ids.TenantID
+type TypedID[P PrefixProvider] PrefixedID
+
+// typedPrefix returns the prefix provided by P.
+func typedPrefix[P PrefixProvider]() string {
+	var p P
+
+	return p.Prefix()
+}
+
+// NewTypedID returns a new TypedID with a generated ID value.
+func NewTypedID[P PrefixProvider]() (TypedID[P], error) {
+	id, err := NewID(typedPrefix[P]())
+	if err != nil {
+		return "", err
+	}
+
+	return TypedID[P](id), nil
+}
+
+// MustNewTypedID wraps NewTypedID and panics in the event of an error
+func MustNewTypedID[P PrefixProvider]() TypedID[P] {
+	id, err := NewTypedID[P]()
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+// ParseTyped reads in a string and returns a TypedID if the string is a properly
+// formatted PrefixedID value with the prefix provided by P.
+func ParseTyped[P PrefixProvider](str string) (TypedID[P], error) {
+	id, err := Parse(str)
+	if err != nil {
+		return "", err
+	}
+
+	return TypedIDFrom[P](id)
+}
+
+// TypedIDFrom converts the PrefixedID to a TypedID, returning an error if the prefix
+// does not match the prefix provided by P. A null PrefixedID converts to a null TypedID.
+func TypedIDFrom[P PrefixProvider](id PrefixedID) (TypedID[P], error) {
+	if id == NullPrefixedID {
+		return "", nil
+	}
+
+	if expected := typedPrefix[P](); id.Prefix() != expected {
+		return "", newErrInvalidIDWrap(ErrPrefixMismatch, fmt.Sprintf("expected prefix '%s', '%s' has prefix '%s'", expected, id, id.Prefix()))
+	}
+
+	return TypedID[P](id), nil
+}
+
+// PrefixedID returns the TypedID as a PrefixedID.
+func (t TypedID[P]) PrefixedID() PrefixedID {
+	return PrefixedID(t)
+}
+
+// Prefix will return the Prefix value of the ID
+func (t TypedID[P]) Prefix() string {
+	return PrefixedID(t).Prefix()
+}
+
+// ID will return the latter part of the ID, removing the prefix
+func (t TypedID[P]) ID() string {
+	return PrefixedID(t).ID()
+}
+
+// String returns the TypedID as a string.
+func (t TypedID[P]) String() string {
+	return string(t)
+}
+
+// Value implements sql.Valuer so that TypedIDs can be written to databases
+// transparently. TypedIDs map to strings.
+func (t TypedID[P]) Value() (driver.Value, error) {
+	if _, err := ParseTyped[P](string(t)); err != nil {
+		return "", err
+	}
+
+	return string(t), nil
+}
+
+// Scan implements sql.Scanner so TypedIDs can be read from databases
+// transparently. The prefix of the value is checked to match the prefix provided by P.
+func (t *TypedID[P]) Scan(v any) error {
+	var id PrefixedID
+
+	if err := id.Scan(v); err != nil {
+		return err
+	}
+
+	typed, err := TypedIDFrom[P](id)
+	if err != nil {
+		return err
+	}
+
+	*t = typed
+
+	return nil
+}
+
+// MarshalGQL provides GraphQL marshaling so that TypedIDs can be returned
+// in GraphQL results transparently.
+func (t TypedID[P]) MarshalGQL(w io.Writer) {
+	PrefixedID(t).MarshalGQL(w)
+}
+
+// UnmarshalGQL provides GraphQL unmarshaling so that TypedIDs can be parsed
+// in GraphQL requests transparently. Only input types that map to a string are supported.
+func (t *TypedID[P]) UnmarshalGQL(v interface{}) error {
+	switch src := v.(type) {
+	case string:
+		typed, err := ParseTyped[P](src)
+		if err != nil {
+			return err
+		}
+
+		*t = typed
+
+		return nil
+	default:
+		return t.Scan(v)
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TypedID[P]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the value must be a properly formatted
+// PrefixedID with the prefix provided by P.
+func (t *TypedID[P]) UnmarshalJSON(b []byte) error {
+	var s string
+
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	typed, err := ParseTyped[P](s)
+	if err != nil {
+		return err
+	}
+
+	*t = typed
+
+	return nil
+}
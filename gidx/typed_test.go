@@ -0,0 +1,181 @@
+// Copyright 2023 The Infratographer Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gidx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"entgo.io/ent/schema/field"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/x/gidx"
+)
+
+type tenantPrefix struct{}
+
+func (tenantPrefix) Prefix() string { return "tnntten" }
+
+type locationPrefix struct{}
+
+func (locationPrefix) Prefix() string { return "lctnloc" }
+
+type (
+	tenantID   = gidx.TypedID[tenantPrefix]
+	locationID = gidx.TypedID[locationPrefix]
+)
+
+var (
+	_ graphql.Marshaler   = tenantID("")
+	_ graphql.Unmarshaler = (*tenantID)(nil)
+)
+
+func TestTypedID(t *testing.T) {
+	id := gidx.MustNewTypedID[tenantPrefix]()
+	assert.Equal(t, "tnntten", id.Prefix())
+	assert.Len(t, id.ID(), gidx.IDPartLength)
+
+	prefixed := id.PrefixedID()
+	assert.Equal(t, id.String(), prefixed.String())
+
+	back, err := gidx.TypedIDFrom[tenantPrefix](prefixed)
+	require.NoError(t, err)
+	assert.Equal(t, id, back)
+
+	_, err = gidx.TypedIDFrom[locationPrefix](prefixed)
+	require.ErrorIs(t, err, gidx.ErrPrefixMismatch)
+	assert.ErrorContains(t, err, "invalid id: expected prefix 'lctnloc'")
+
+	null, err := gidx.TypedIDFrom[tenantPrefix](gidx.NullPrefixedID)
+	require.NoError(t, err)
+	assert.Equal(t, tenantID(""), null)
+}
+
+func TestParseTyped(t *testing.T) {
+	cases := []struct {
+		name     string
+		id       string
+		errorMsg string
+	}{
+		{name: "valid id", id: "tnntten-fm21VlAHHrGf6utn1JsKc"},
+		{name: "null id", id: ""},
+		{name: "wrong prefix", id: "lctnloc-fm21VlAHHrGf6utn1JsKc", errorMsg: "invalid id: expected prefix 'tnntten'"},
+		{name: "invalid id", id: "tnntten", errorMsg: "invalid id: expected id format is prefix-id"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := gidx.ParseTyped[tenantPrefix](tt.id)
+
+			var scanned tenantID
+
+			scanErr := scanned.Scan(tt.id)
+
+			var gql tenantID
+
+			gqlErr := gql.UnmarshalGQL(tt.id)
+
+			var decoded tenantID
+
+			jsonErr := json.Unmarshal([]byte(`"`+tt.id+`"`), &decoded)
+
+			if tt.errorMsg == "" {
+				require.NoError(t, err)
+				require.NoError(t, gqlErr)
+				require.NoError(t, jsonErr)
+				assert.Equal(t, tt.id, parsed.String())
+				assert.Equal(t, tt.id, gql.String())
+				assert.Equal(t, tt.id, decoded.String())
+			} else {
+				assert.ErrorContains(t, err, tt.errorMsg)
+				assert.ErrorContains(t, gqlErr, tt.errorMsg)
+				assert.ErrorContains(t, jsonErr, tt.errorMsg)
+			}
+
+			if tt.errorMsg == "" {
+				assert.NoError(t, scanErr)
+			} else {
+				assert.ErrorContains(t, scanErr, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestTypedIDEncoding(t *testing.T) {
+	type resource struct {
+		TenantID   tenantID   `json:"tenant_id"`
+		LocationID locationID `json:"location_id"`
+	}
+
+	r := resource{
+		TenantID:   gidx.MustNewTypedID[tenantPrefix](),
+		LocationID: gidx.MustNewTypedID[locationPrefix](),
+	}
+
+	b, err := json.Marshal(r)
+	require.NoError(t, err)
+
+	var decoded resource
+
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, r, decoded)
+
+	// swapped ids must be rejected
+	swapped := []byte(`{"tenant_id":"` + r.LocationID.String() + `"}`)
+	require.ErrorIs(t, json.Unmarshal(swapped, &decoded), gidx.ErrPrefixMismatch)
+
+	v, err := r.TenantID.Value()
+	require.NoError(t, err)
+	assert.Equal(t, r.TenantID.String(), v)
+
+	_, err = tenantID("lctnloc-fm21VlAHHrGf6utn1JsKc").Value()
+	require.ErrorIs(t, err, gidx.ErrPrefixMismatch)
+
+	var buf bytes.Buffer
+
+	r.TenantID.MarshalGQL(&buf)
+	assert.Equal(t, `"`+r.TenantID.String()+`"`, buf.String())
+}
+
+func TestTypedIDEntField(t *testing.T) {
+	desc := field.String("tenant_id").GoType(tenantID("")).Descriptor()
+	require.NoError(t, desc.Err)
+	assert.True(t, desc.Info.ValueScanner())
+}
+
+func TestTypedIDGQL(t *testing.T) {
+	id := gidx.MustNewTypedID[tenantPrefix]()
+
+	var buf bytes.Buffer
+
+	id.MarshalGQL(&buf)
+
+	var raw string
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+
+	var decoded tenantID
+
+	require.NoError(t, decoded.UnmarshalGQL(raw))
+	assert.Equal(t, id, decoded)
+
+	loc := gidx.MustNewTypedID[locationPrefix]()
+
+	require.ErrorIs(t, decoded.UnmarshalGQL(loc.String()), gidx.ErrPrefixMismatch)
+	require.Error(t, decoded.UnmarshalGQL(1))
+}
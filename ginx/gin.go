@@ -34,6 +34,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"go.infratographer.com/x/actorx"
 	"go.infratographer.com/x/versionx"
 )
 
@@ -161,8 +162,11 @@ func (s *Server) engine() *gin.Engine {
 	r.Use(func(c *gin.Context) {
 		u := c.GetHeader("User")
 		if u != "" {
+			actor := actorx.New(u, actorx.KindUser)
+
 			c.Set("current_actor", u)
-			c.Set("actor_type", "user")
+			c.Set("actor_type", string(actor.Kind))
+			c.Request = c.Request.WithContext(actorx.NewContext(c.Request.Context(), actor))
 		}
 	})
 
@@ -218,3 +222,8 @@ func (s Server) Run() {
 		s.logger.Fatal("server forced to shutdown", zap.Error(err))
 	}
 }
+
+// Actor returns the actor for the request, or an anonymous actor if the request has no actor.
+func Actor(c *gin.Context) *actorx.Actor {
+	return actorx.FromContextOrAnonymous(c.Request.Context())
+}
@@ -29,6 +29,13 @@ func (c *MockConnection) PublishAuthRelationshipRequest(_ context.Context, topic
 	return args.Get(0).(events.Message[events.AuthRelationshipResponse]), args.Error(1)
 }
 
+// PublishAuthRelationshipBatchRequest implements events.AuthRelationshipBatchPublisher
+func (c *MockConnection) PublishAuthRelationshipBatchRequest(_ context.Context, topic string, message events.AuthRelationshipBatchRequest) (events.Message[events.AuthRelationshipResponse], error) {
+	args := c.Called(topic, message)
+
+	return args.Get(0).(events.Message[events.AuthRelationshipResponse]), args.Error(1)
+}
+
 // PublishChange implements events.Connection
 func (c *MockConnection) PublishChange(_ context.Context, topic string, message events.ChangeMessage) (events.Message[events.ChangeMessage], error) {
 	args := c.Called(topic, message)
@@ -57,6 +64,13 @@ func (c *MockConnection) SubscribeAuthRelationshipRequests(_ context.Context, to
 	return args.Get(0).(<-chan events.Request[events.AuthRelationshipRequest, events.AuthRelationshipResponse]), args.Error(1)
 }
 
+// SubscribeAuthRelationshipBatchRequests implements events.AuthRelationshipBatchSubscriber
+func (c *MockConnection) SubscribeAuthRelationshipBatchRequests(_ context.Context, topic string) (<-chan events.Request[events.AuthRelationshipBatchRequest, events.AuthRelationshipResponse], error) {
+	args := c.Called(topic)
+
+	return args.Get(0).(<-chan events.Request[events.AuthRelationshipBatchRequest, events.AuthRelationshipResponse]), args.Error(1)
+}
+
 // SubscribeChanges implements events.Connection
 func (c *MockConnection) SubscribeChanges(_ context.Context, topic string) (<-chan events.Message[events.ChangeMessage], error) {
 	args := c.Called(topic)